@@ -91,11 +91,17 @@ type ethstatsConfig struct {
 	URL string `toml:",omitempty"`
 }
 
+type concreteConfig struct {
+	// Plugins are the Go plugin files to load precompiles from.
+	Plugins []string `toml:",omitempty"`
+}
+
 type gethConfig struct {
 	Eth      ethconfig.Config
 	Node     node.Config
 	Ethstats ethstatsConfig
 	Metrics  metrics.Config
+	Concrete concreteConfig
 }
 
 func loadConfig(file string, cfg *gethConfig) error {
@@ -146,6 +152,21 @@ func loadBaseConfig(ctx *cli.Context) gethConfig {
 	return cfg
 }
 
+// loadConcreteConfig loads the Concrete settings from the config file and the
+// command line.
+func loadConcreteConfig(ctx *cli.Context) concreteConfig {
+	var cfg gethConfig
+	if file := ctx.String(configFileFlag.Name); file != "" {
+		if err := loadConfig(file, &cfg); err != nil {
+			utils.Fatalf("%v", err)
+		}
+	}
+	if ctx.IsSet(utils.ConcretePluginsFlag.Name) {
+		cfg.Concrete.Plugins = ctx.StringSlice(utils.ConcretePluginsFlag.Name)
+	}
+	return cfg.Concrete
+}
+
 // makeConfigNode loads geth configuration and creates a blank node instance.
 func makeConfigNode(ctx *cli.Context) (*node.Node, gethConfig) {
 	cfg := loadBaseConfig(ctx)
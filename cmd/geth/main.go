@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete"
+	concrete_plugin "github.com/ethereum/go-ethereum/concrete/plugin"
 	concrete_rpc "github.com/ethereum/go-ethereum/concrete/rpc"
 	"github.com/ethereum/go-ethereum/console/prompt"
 	"github.com/ethereum/go-ethereum/eth"
@@ -144,6 +145,7 @@ var (
 		utils.DeveloperGasLimitFlag,
 		utils.DeveloperPeriodFlag,
 		utils.VMEnableDebugFlag,
+		utils.ConcretePluginsFlag,
 		utils.NetworkIdFlag,
 		utils.EthStatsURLFlag,
 		utils.NoCompactionFlag,
@@ -190,6 +192,7 @@ var (
 		utils.InsecureUnlockAllowedFlag,
 		utils.RPCGlobalGasCapFlag,
 		utils.RPCGlobalEVMTimeoutFlag,
+		utils.RPCPrecompileOverridesFlag,
 		utils.RPCGlobalTxFeeCapFlag,
 		utils.AllowUnprotectedTxs,
 		utils.BatchRequestLimit,
@@ -374,8 +377,8 @@ func newConcreteGeth(concreteRegistry concrete.PrecompileRegistry, concreteApis
 		stack, backend, eth := makeFullNode(ctx)
 		defer stack.Close()
 
-		// Construct Concrete APIs
-		ccApis := make([]rpc.API, 0)
+		// Construct Concrete APIs, starting with the default concrete namespace
+		ccApis := []rpc.API{concrete_rpc.NewConcreteAPI(eth)}
 		for _, constructor := range concreteApis {
 			ccApis = append(ccApis, constructor(eth))
 		}
@@ -383,8 +386,14 @@ func newConcreteGeth(concreteRegistry concrete.PrecompileRegistry, concreteApis
 		// Register Concrete APIs
 		stack.RegisterAPIs(ccApis)
 
+		// Load Concrete precompiles from plugins
+		registry, err := withConcretePlugins(ctx, concreteRegistry)
+		if err != nil {
+			utils.Fatalf("Failed to load Concrete plugins: %v", err)
+		}
+
 		// Set Concrete precompiles
-		backend.SetConcrete(concreteRegistry)
+		backend.SetConcrete(registry)
 
 		startNode(ctx, stack, backend, false)
 		stack.Wait()
@@ -392,6 +401,24 @@ func newConcreteGeth(concreteRegistry concrete.PrecompileRegistry, concreteApis
 	}
 }
 
+// withConcretePlugins merges the precompiles of the configured plugins into the
+// given registry.
+func withConcretePlugins(ctx *cli.Context, registry concrete.PrecompileRegistry) (concrete.PrecompileRegistry, error) {
+	paths := loadConcreteConfig(ctx).Plugins
+	if len(paths) == 0 {
+		return registry, nil
+	}
+	generic, ok := registry.(*concrete.GenericPrecompileRegistry)
+	if !ok {
+		return nil, fmt.Errorf("plugins require a %T registry, have %T", generic, registry)
+	}
+	loaded, err := concrete_plugin.Load(paths...)
+	if err != nil {
+		return nil, err
+	}
+	return concrete.MergeRegistries(generic, loaded)
+}
+
 func newConcreteGethApp(registry concrete.PrecompileRegistry, apis []concrete_rpc.APIConstructor) *cli.App {
 	ccApp := flags.NewApp("the concrete-geth command line interface")
 	ccApp.Action = newConcreteGeth(registry, apis)
@@ -536,6 +536,11 @@ var (
 		Usage:    "Record information useful for VM and contract debugging",
 		Category: flags.VMCategory,
 	}
+	ConcretePluginsFlag = &cli.StringSliceFlag{
+		Name:     "concrete.plugins",
+		Usage:    "Comma separated list of Go plugin (.so) files to load Concrete precompiles from (Linux only)",
+		Category: flags.VMCategory,
+	}
 
 	// API options.
 	RPCGlobalGasCapFlag = &cli.Uint64Flag{
@@ -550,6 +555,11 @@ var (
 		Value:    ethconfig.Defaults.RPCEVMTimeout,
 		Category: flags.APICategory,
 	}
+	RPCPrecompileOverridesFlag = &cli.BoolFlag{
+		Name:     "rpc.precompileoverrides",
+		Usage:    "Allow eth_call, eth_estimateGas and debug_traceCall to override concrete precompiles with code, e.g. WASM modules (the code runs as a trusted precompile)",
+		Category: flags.APICategory,
+	}
 	RPCGlobalTxFeeCapFlag = &cli.Float64Flag{
 		Name:     "rpc.txfeecap",
 		Usage:    "Sets a cap on transaction fee (in ether) that can be sent via the RPC APIs (0 = no cap)",
@@ -1839,6 +1849,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(RPCGlobalEVMTimeoutFlag.Name) {
 		cfg.RPCEVMTimeout = ctx.Duration(RPCGlobalEVMTimeoutFlag.Name)
 	}
+	if ctx.IsSet(RPCPrecompileOverridesFlag.Name) {
+		cfg.RPCPrecompileOverrides = ctx.Bool(RPCPrecompileOverridesFlag.Name)
+	}
 	if ctx.IsSet(RPCGlobalTxFeeCapFlag.Name) {
 		cfg.RPCTxFeeCap = ctx.Float64(RPCGlobalTxFeeCapFlag.Name)
 	}
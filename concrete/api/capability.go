@@ -0,0 +1,66 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import "strings"
+
+// Capability is a set of system operations a precompile is allowed to execute.
+// System operations modify accounts other than the precompile itself and are
+// only available to precompiles explicitly granted the matching capability.
+type Capability uint64
+
+const (
+	CapabilityBalance Capability = 1 << iota // Mint and burn the balance of any account
+	CapabilityNonce                          // Set the nonce of any account
+	CapabilityStorage                        // Write the storage of any account
+	CapabilityCode                           // Set the code of any account
+
+	CapabilityNone Capability = 0
+	CapabilityAll             = CapabilityBalance | CapabilityNonce | CapabilityStorage | CapabilityCode
+)
+
+var capabilityNames = []struct {
+	capability Capability
+	name       string
+}{
+	{CapabilityBalance, "balance"},
+	{CapabilityNonce, "nonce"},
+	{CapabilityStorage, "storage"},
+	{CapabilityCode, "code"},
+}
+
+// Has returns whether all capabilities in other are in the set.
+func (c Capability) Has(other Capability) bool {
+	return c&other == other
+}
+
+// Names returns the names of the capabilities in the set.
+func (c Capability) Names() []string {
+	names := []string{}
+	for _, entry := range capabilityNames {
+		if c.Has(entry.capability) {
+			names = append(names, entry.name)
+		}
+	}
+	return names
+}
+
+func (c Capability) String() string {
+	if c == CapabilityNone {
+		return "none"
+	}
+	return strings.Join(c.Names(), "|")
+}
@@ -0,0 +1,43 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+// This file will be ignored when building with tinygo to prevent compatibility
+// issues.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCapabilities(t *testing.T) {
+	r := require.New(t)
+
+	caps := CapabilityBalance | CapabilityCode
+	r.True(caps.Has(CapabilityBalance))
+	r.True(caps.Has(CapabilityNone))
+	r.False(caps.Has(CapabilityNonce))
+	r.False(caps.Has(CapabilityBalance | CapabilityNonce))
+	r.True(CapabilityAll.Has(caps))
+
+	r.Equal([]string{"balance", "code"}, caps.Names())
+	r.Equal("balance|code", caps.String())
+	r.Equal("none", CapabilityNone.String())
+	r.Empty(CapabilityNone.Names())
+}
@@ -17,17 +17,24 @@ package api
 
 import (
 	"bytes"
+	"fmt"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete/utils"
-	"github.com/ethereum/go-ethereum/log"
 	"github.com/holiman/uint256"
 )
 
+// debugfFormat formats a message followed by its key-value context, leaving
+// the timestamp and level to the logger of the node.
 func debugfFormat(msg string, ctx ...interface{}) string {
 	var buf bytes.Buffer
-	logger := log.NewLogger(log.NewTerminalHandlerWithLevel(&buf, log.LevelDebug, true))
-	logger.Debug(msg, ctx...)
+	buf.WriteString(msg)
+	if len(ctx)%2 != 0 {
+		ctx = append(ctx, nil)
+	}
+	for i := 0; i < len(ctx); i += 2 {
+		fmt.Fprintf(&buf, " %v=%+v", ctx[i], ctx[i+1])
+	}
 	return buf.String()
 }
 
@@ -36,6 +43,7 @@ type Environment interface {
 
 	// Meta
 	EnableGasMetering(meter bool)
+	GetExecutionMode() ExecutionMode
 	Debug(msg string)
 	Debugf(msg string, ctx ...interface{})
 	TimeNow() uint64
@@ -64,6 +72,9 @@ type Environment interface {
 	GetBlockBaseFee() *uint256.Int
 	GetBlockCoinbase() common.Address
 	GetPrevRandom() common.Hash
+	GetBlobBaseFee() *uint256.Int
+	// Chain
+	GetChainID() *uint256.Int
 	// Block hash
 	GetBlockHash(block uint64) common.Hash
 	// Balance
@@ -71,6 +82,13 @@ type Environment interface {
 	// Transaction
 	GetTxGasPrice() *uint256.Int
 	GetTxOrigin() common.Address
+	GetBlobHash(index uint64) common.Hash
+	// OP-stack
+	GetL1BlockNumber() uint64
+	GetL1BlockHash() common.Hash
+	GetL1BaseFee() *uint256.Int
+	GetTxL1Fee() *uint256.Int
+	IsDepositTx() bool
 	// Call
 	GetCallData() []byte
 	GetCallDataSize() int
@@ -97,6 +115,11 @@ type Environment interface {
 	GetExternalCode(address common.Address) []byte
 	GetExternalCodeSize(address common.Address) int
 	GetExternalCodeHash(address common.Address) common.Hash
+	// Access list
+	IsAddressWarm(address common.Address) bool
+	IsSlotWarm(address common.Address, key common.Hash) bool
+	// Storage
+	GetExternalStorage(address common.Address, key common.Hash) common.Hash
 	// Call
 	CallStatic(address common.Address, data []byte, gas uint64) ([]byte, error)
 
@@ -107,22 +130,40 @@ type Environment interface {
 	// Create
 	Create(data []byte, value *uint256.Int) ([]byte, common.Address, error)
 	Create2(data []byte, endowment *uint256.Int, salt *uint256.Int) ([]byte, common.Address, error)
+
+	// System -- require capabilities
+	// Balance
+	MintBalance(address common.Address, amount *uint256.Int)
+	BurnBalance(address common.Address, amount *uint256.Int)
+	// Nonce
+	SetExternalNonce(address common.Address, nonce uint64)
+	// Storage
+	SetExternalStorage(address common.Address, key common.Hash, value common.Hash)
+	// Code
+	SetExternalCode(address common.Address, code []byte)
 }
 
 type EnvConfig struct {
 	IsStatic bool
 	// Ephemeral bool
 	IsTrusted bool
+	// Capabilities granted to the precompile, required by system operations
+	Capabilities Capability
+	// Mode is the context the precompile is executed in
+	Mode ExecutionMode
 }
 
 type Contract struct {
-	Address  common.Address
-	Origin   common.Address
-	Caller   common.Address
-	GasPrice *uint256.Int
-	Input    []byte
-	Gas      uint64
-	Value    *uint256.Int
+	Address    common.Address
+	Origin     common.Address
+	Caller     common.Address
+	GasPrice   *uint256.Int
+	BlobHashes []common.Hash
+	IsDeposit  bool                // OP-stack deposit transaction
+	L1Fee      func() *uint256.Int // OP-stack L1 data fee of the transaction, computed on demand
+	Input      []byte
+	Gas        uint64
+	Value      *uint256.Int
 }
 
 func NewContract(origin, caller, address common.Address, gasPrice *uint256.Int) *Contract {
@@ -143,7 +184,7 @@ func (c *Contract) useGas(gas uint64) bool {
 }
 
 type Env struct {
-	table    JumpTable
+	table    *JumpTable
 	_execute func(op OpCode, env *Env, args [][]byte) ([][]byte, error)
 
 	config   EnvConfig
@@ -158,8 +199,39 @@ type Env struct {
 	revertErr    error
 	nonRevertErr error
 	callGasTemp  uint64
+
+	gasTracer   GasTracer
+	debugTracer DebugTracer
+}
+
+// GasTracer receives the gas used by every operation executed by an
+// environment, net of the gas returned by calls.
+type GasTracer interface {
+	CaptureEnvGas(address common.Address, op OpCode, gas uint64)
 }
 
+// DebugTracer receives the debug messages emitted by precompiles.
+type DebugTracer interface {
+	CaptureEnvDebug(address common.Address, msg string)
+}
+
+// DebugMessage is a debug message emitted by the precompile at Address.
+type DebugMessage struct {
+	Address common.Address `json:"address"`
+	Message string         `json:"message"`
+}
+
+// DebugCollector is a DebugTracer collecting debug messages in order.
+type DebugCollector struct {
+	Messages []DebugMessage
+}
+
+func (c *DebugCollector) CaptureEnvDebug(address common.Address, msg string) {
+	c.Messages = append(c.Messages, DebugMessage{Address: address, Message: msg})
+}
+
+var _ DebugTracer = (*DebugCollector)(nil)
+
 func NewEnvironment(
 	config EnvConfig,
 	meterGas bool,
@@ -190,10 +262,23 @@ func execute(op OpCode, env *Env, args [][]byte) ([][]byte, error) {
 	if !env.config.IsTrusted && operation.trusted {
 		return nil, ErrEnvNotTrusted
 	}
+	if !env.config.Capabilities.Has(operation.capability) {
+		return nil, ErrMissingCapability
+	}
+	if operation.simulation && !env.config.Mode.IsSimulation() {
+		return nil, ErrSimulationOnly
+	}
 	if env.config.IsStatic && !operation.static {
 		return nil, ErrWriteProtection
 	}
 
+	if env.meterGas && env.gasTracer != nil {
+		gasBefore := env.contract.Gas
+		defer func() {
+			env.gasTracer.CaptureEnvGas(env.contract.Address, op, gasBefore-env.contract.Gas)
+		}()
+	}
+
 	if env.meterGas {
 		gasConst := operation.constantGas
 		if ok := env.useGas(gasConst); !ok {
@@ -222,6 +307,27 @@ func (env *Env) execute(op OpCode, args [][]byte) [][]byte {
 	return ret
 }
 
+// SetAbiVersion selects the jump table matching the ABI version the running
+// precompile was built against.
+func (env *Env) SetAbiVersion(version AbiVersion) error {
+	table, err := newEnvironmentMethodsForVersion(version)
+	if err != nil {
+		return err
+	}
+	env.table = table
+	return nil
+}
+
+// SetGasTracer sets a tracer to receive the gas used by every operation.
+func (env *Env) SetGasTracer(tracer GasTracer) {
+	env.gasTracer = tracer
+}
+
+// SetDebugTracer sets a tracer to receive the debug messages of the precompile.
+func (env *Env) SetDebugTracer(tracer DebugTracer) {
+	env.debugTracer = tracer
+}
+
 func (env *Env) Config() EnvConfig {
 	return env.config
 }
@@ -276,6 +382,11 @@ func (env *Env) Debugf(msg string, ctx ...interface{}) {
 	env.Debug(fmsg)
 }
 
+func (env *Env) GetExecutionMode() ExecutionMode {
+	output := env.execute(GetExecutionMode_OpCode, nil)
+	return ExecutionMode(output[0][0])
+}
+
 func (env *Env) TimeNow() uint64 {
 	output := env.execute(TimeNow_OpCode, nil)
 	return utils.BytesToUint64(output[0])
@@ -343,6 +454,16 @@ func (env *Env) GetPrevRandom() common.Hash {
 	return common.BytesToHash(output[0])
 }
 
+func (env *Env) GetBlobBaseFee() *uint256.Int {
+	output := env.execute(GetBlobBaseFee_OpCode, nil)
+	return new(uint256.Int).SetBytes(output[0])
+}
+
+func (env *Env) GetChainID() *uint256.Int {
+	output := env.execute(GetChainID_OpCode, nil)
+	return new(uint256.Int).SetBytes(output[0])
+}
+
 func (env *Env) GetBlockHash(number uint64) common.Hash {
 	input := [][]byte{utils.Uint64ToBytes(number)}
 	output := env.execute(GetBlockHash_OpCode, input)
@@ -364,6 +485,37 @@ func (env *Env) GetTxOrigin() common.Address {
 	return common.BytesToAddress(output[0])
 }
 
+func (env *Env) GetBlobHash(index uint64) common.Hash {
+	input := [][]byte{utils.Uint64ToBytes(index)}
+	output := env.execute(GetBlobHash_OpCode, input)
+	return common.BytesToHash(output[0])
+}
+
+func (env *Env) GetL1BlockNumber() uint64 {
+	output := env.execute(GetL1BlockNumber_OpCode, nil)
+	return utils.BytesToUint64(output[0])
+}
+
+func (env *Env) GetL1BlockHash() common.Hash {
+	output := env.execute(GetL1BlockHash_OpCode, nil)
+	return common.BytesToHash(output[0])
+}
+
+func (env *Env) GetL1BaseFee() *uint256.Int {
+	output := env.execute(GetL1BaseFee_OpCode, nil)
+	return new(uint256.Int).SetBytes(output[0])
+}
+
+func (env *Env) GetTxL1Fee() *uint256.Int {
+	output := env.execute(GetTxL1Fee_OpCode, nil)
+	return new(uint256.Int).SetBytes(output[0])
+}
+
+func (env *Env) IsDepositTx() bool {
+	output := env.execute(IsDepositTx_OpCode, nil)
+	return utils.BytesToBool(output[0])
+}
+
 func (env *Env) GetCallData() []byte {
 	output := env.execute(GetCallData_OpCode, nil)
 	return output[0]
@@ -455,6 +607,18 @@ func (env *Env) GetExternalCodeHash(address common.Address) common.Hash {
 	return common.BytesToHash(output[0])
 }
 
+func (env *Env) IsAddressWarm(address common.Address) bool {
+	input := [][]byte{address.Bytes()}
+	output := env.execute(IsAddressWarm_OpCode, input)
+	return utils.BytesToBool(output[0])
+}
+
+func (env *Env) IsSlotWarm(address common.Address, key common.Hash) bool {
+	input := [][]byte{address.Bytes(), key.Bytes()}
+	output := env.execute(IsSlotWarm_OpCode, input)
+	return utils.BytesToBool(output[0])
+}
+
 func (env *Env) Call(address common.Address, data []byte, gas uint64, value *uint256.Int) ([]byte, error) {
 	v := value.Bytes32()
 	input := [][]byte{address.Bytes(), data, utils.Uint64ToBytes(gas), v[:]}
@@ -483,4 +647,37 @@ func (env *Env) Create2(data []byte, endowment *uint256.Int, salt *uint256.Int)
 	return output[0], common.BytesToAddress(output[1]), utils.DecodeError(output[2])
 }
 
+func (env *Env) GetExternalStorage(address common.Address, key common.Hash) common.Hash {
+	input := [][]byte{address.Bytes(), key.Bytes()}
+	output := env.execute(GetExternalStorage_OpCode, input)
+	return common.BytesToHash(output[0])
+}
+
+func (env *Env) MintBalance(address common.Address, amount *uint256.Int) {
+	a := amount.Bytes32()
+	input := [][]byte{address.Bytes(), a[:]}
+	env.execute(MintBalance_OpCode, input)
+}
+
+func (env *Env) BurnBalance(address common.Address, amount *uint256.Int) {
+	a := amount.Bytes32()
+	input := [][]byte{address.Bytes(), a[:]}
+	env.execute(BurnBalance_OpCode, input)
+}
+
+func (env *Env) SetExternalNonce(address common.Address, nonce uint64) {
+	input := [][]byte{address.Bytes(), utils.Uint64ToBytes(nonce)}
+	env.execute(SetExternalNonce_OpCode, input)
+}
+
+func (env *Env) SetExternalStorage(address common.Address, key common.Hash, value common.Hash) {
+	input := [][]byte{address.Bytes(), key.Bytes(), value.Bytes()}
+	env.execute(SetExternalStorage_OpCode, input)
+}
+
+func (env *Env) SetExternalCode(address common.Address, code []byte) {
+	input := [][]byte{address.Bytes(), code}
+	env.execute(SetExternalCode_OpCode, input)
+}
+
 var _ Environment = (*Env)(nil)
@@ -22,11 +22,14 @@ package api
 
 import (
 	"bytes"
-	"io"
-	"os"
+	"encoding/binary"
+	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
@@ -40,7 +43,7 @@ func TestGas(t *testing.T) {
 		gas      = uint64(1e6)
 	)
 
-	env, _, _, _ := NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+	env, _, _, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 	env.contract.Gas = gas
 
 	// GetGasLeft() costs gas, so the cost of that operation must be subtracted
@@ -59,7 +62,7 @@ func TestBlockOps_Minimal(t *testing.T) {
 		gas      = uint64(1e6)
 	)
 
-	env, _, _, _ := NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+	env, _, _, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 	env.contract.Gas = gas
 
 	r.Equal(env.block.GetHash(0), env.GetBlockHash(0))
@@ -70,6 +73,8 @@ func TestBlockOps_Minimal(t *testing.T) {
 	r.Equal(env.block.BaseFee(), env.GetBlockBaseFee())
 	r.Equal(env.block.Coinbase(), env.GetBlockCoinbase())
 	r.Equal(env.block.Random(), env.GetPrevRandom())
+	r.Equal(env.block.BlobBaseFee(), env.GetBlobBaseFee())
+	r.Equal(env.block.ChainID(), env.GetChainID())
 }
 
 func TestCallOps_Minimal(t *testing.T) {
@@ -80,13 +85,16 @@ func TestCallOps_Minimal(t *testing.T) {
 		gas      = uint64(1e6)
 	)
 
-	env, _, _, _ := NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+	env, _, _, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 	env.contract.Input = []byte{0x01, 0x02, 0x03}
 	env.contract.Gas = gas
 	env.contract.Value = uint256.NewInt(1)
+	env.contract.BlobHashes = []common.Hash{{0x01}}
 
 	r.Equal(env.contract.GasPrice, env.GetTxGasPrice())
 	r.Equal(env.contract.Origin, env.GetTxOrigin())
+	r.Equal(env.contract.BlobHashes[0], env.GetBlobHash(0))
+	r.Equal(common.Hash{}, env.GetBlobHash(1))
 	r.Equal(env.contract.Input, env.GetCallData())
 	r.Equal(len(env.contract.Input), env.GetCallDataSize())
 	r.Equal(env.contract.Caller, env.GetCaller())
@@ -100,11 +108,11 @@ func TestTrustAndWriteProtection(t *testing.T) {
 		meterGas = false
 	)
 
-	env, _, _, _ := NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+	env, _, _, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 	env.contract.Input = []byte{0x01, 0x02, 0x03}
 	env.contract.Value = uint256.NewInt(1)
 
-	table := newEnvironmentMethods()
+	table := env.table
 	for opcode, method := range table {
 		err := func() (err error) {
 			defer func() {
@@ -135,34 +143,58 @@ func TestDebugf(t *testing.T) {
 		r        = require.New(t)
 		config   = EnvConfig{IsStatic: true, IsTrusted: true}
 		meterGas = false
+		address  = common.HexToAddress("0xc0ffee")
+		contract = NewContract(common.Address{}, common.Address{}, address, new(uint256.Int))
 	)
 
-	env, _, _, _ := NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+	env, _, _, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas), WithContract(contract))
+	collector := &DebugCollector{}
+	env.SetDebugTracer(collector)
 
-	read, write, _ := os.Pipe()
-
-	// Capture stderr
-	stderr := os.Stderr
-	os.Stderr = write
-	defer func() {
-		os.Stderr = stderr
-	}()
-
-	// Copy captured stderr to a buffer
-	done := make(chan *bytes.Buffer)
-	go func() {
-		defer read.Close()
-		var buf bytes.Buffer
-		io.Copy(&buf, read)
-		done <- &buf
-	}()
+	// Capture the node logs
+	var buf bytes.Buffer
+	root := log.Root()
+	log.SetDefault(log.NewLogger(log.NewTerminalHandlerWithLevel(&buf, log.LevelDebug, false)))
+	defer log.SetDefault(root)
 
 	env.Debugf("Message", "arg1", 1, "arg2", "val2", "arg3", struct{ A int }{A: 3})
+	env.Debug("Line\n")
+
+	r.Equal([]DebugMessage{
+		{Address: address, Message: "Message arg1=1 arg2=val2 arg3={A:3}"},
+		{Address: address, Message: "Line"},
+	}, collector.Messages)
+	r.Contains(buf.String(), "Precompile debug")
+	r.Contains(buf.String(), "address="+address.Hex())
+	r.Contains(buf.String(), `msg="Message arg1=1 arg2=val2 arg3={A:3}"`)
+}
 
-	write.Close()
-	buf := <-done
-
-	r.Equal("Message                                  \x1b[36marg1\x1b[0m=1 \x1b[36marg2\x1b[0m=val2 \x1b[36marg3\x1b[0m={A:3}\n", buf.String()[35:])
+func TestExecutionMode(t *testing.T) {
+	r := require.New(t)
+	modes := []struct {
+		mode       ExecutionMode
+		name       string
+		simulation bool
+	}{
+		{ExecutionModeConsensus, "consensus", false},
+		{ExecutionModeCall, "call", true},
+		{ExecutionModeEstimate, "estimate", true},
+		{ExecutionModeTrace, "trace", true},
+	}
+	for _, test := range modes {
+		config := EnvConfig{IsStatic: true, IsTrusted: true, Mode: test.mode}
+		env, _, _, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(false))
+
+		r.Equal(test.name, test.mode.String())
+		r.Equal(test.simulation, test.mode.IsSimulation())
+		r.Equal(test.mode, env.GetExecutionMode())
+		// Simulation-only operations hard fail in consensus modes
+		if test.simulation {
+			r.NotZero(env.TimeNow())
+		} else {
+			r.PanicsWithError(ErrSimulationOnly.Error(), func() { env.TimeNow() })
+		}
+	}
 }
 
 func TestBlockContextMethods(t *testing.T) {
@@ -173,7 +205,7 @@ func TestBlockContextMethods(t *testing.T) {
 		gas      = uint64(1e6)
 	)
 
-	env, _, _block, _ := NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+	env, _, _block, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 	block := _block.(*mockBlockContext)
 
 	t.Run("BlockHash", func(t *testing.T) {
@@ -275,6 +307,191 @@ func TestBlockContextMethods(t *testing.T) {
 		r.Equal(blockRandom, env.GetPrevRandom())
 		r.Equal(env.Gas(), gas-GasQuickStep)
 	})
+
+	t.Run("BlobBaseFee", func(t *testing.T) {
+		env.contract.Gas = gas
+
+		blobBaseFee := uint256.NewInt(1)
+		block.SetBlobBaseFee(blobBaseFee)
+
+		r.Equal(blobBaseFee, env.GetBlobBaseFee())
+		r.Equal(env.Gas(), gas-GasQuickStep)
+	})
+
+	t.Run("ChainID", func(t *testing.T) {
+		env.contract.Gas = gas
+
+		chainID := uint256.NewInt(901)
+		block.SetChainID(chainID)
+
+		r.Equal(chainID, env.GetChainID())
+		r.Equal(env.Gas(), gas-GasQuickStep)
+	})
+}
+
+func TestRollupMethods(t *testing.T) {
+	var (
+		r        = require.New(t)
+		config   = EnvConfig{IsStatic: true, IsTrusted: false}
+		meterGas = true
+		gas      = uint64(1e6)
+	)
+
+	env, _statedb, _, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
+	statedb := _statedb.(*state.StateDB)
+
+	t.Run("NotOPStack", func(t *testing.T) {
+		env.contract.Gas = gas
+
+		// Reading the first slot also loads the L1Block account
+		r.Equal(uint64(0), env.GetL1BlockNumber())
+		r.Equal(env.Gas(), gas-params.ColdAccountAccessCostEIP2929+params.WarmStorageReadCostEIP2929-params.ColdSloadCostEIP2929)
+		_, slotWarm := statedb.SlotInAccessList(types.L1BlockAddr, types.L1BlockNumberSlot)
+		r.True(slotWarm)
+		r.Equal(common.Hash{}, env.GetL1BlockHash())
+		r.Equal(uint256.NewInt(0), env.GetL1BaseFee())
+		r.Equal(uint256.NewInt(0), env.GetTxL1Fee())
+		r.False(env.IsDepositTx())
+	})
+
+	t.Run("L1Block", func(t *testing.T) {
+		env.contract.Gas = gas
+
+		var numberAndTime common.Hash
+		binary.BigEndian.PutUint64(numberAndTime[16:24], 1700000000)
+		binary.BigEndian.PutUint64(numberAndTime[24:32], 19000000)
+		statedb.SetState(types.L1BlockAddr, types.L1BlockNumberSlot, numberAndTime)
+		statedb.SetState(types.L1BlockAddr, types.L1BlockHashSlot, common.Hash{0x01})
+		statedb.SetState(types.L1BlockAddr, types.L1BaseFeeSlot, common.BigToHash(big.NewInt(30e9)))
+
+		r.Equal(uint64(19000000), env.GetL1BlockNumber())
+		r.Equal(common.Hash{0x01}, env.GetL1BlockHash())
+		r.Equal(uint256.NewInt(30e9), env.GetL1BaseFee())
+		// The slots were warmed up by the previous reads
+		r.Equal(env.Gas(), gas-3*params.WarmStorageReadCostEIP2929)
+	})
+
+	t.Run("Tx", func(t *testing.T) {
+		env.contract.Gas = gas
+		env.contract.IsDeposit = true
+		env.contract.L1Fee = func() *uint256.Int { return uint256.NewInt(1234) }
+
+		r.True(env.IsDepositTx())
+		r.Equal(uint256.NewInt(1234), env.GetTxL1Fee())
+		r.Equal(env.Gas(), gas-2*GasQuickStep)
+	})
+}
+
+func TestAccessListMethods(t *testing.T) {
+	var (
+		r        = require.New(t)
+		config   = EnvConfig{IsStatic: true, IsTrusted: false}
+		meterGas = true
+		gas      = uint64(1e6)
+		address  = common.Address{0x01}
+		slot     = common.Hash{0x02}
+	)
+
+	env, statedb, _, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
+	env.contract.Gas = gas
+
+	r.False(env.IsAddressWarm(address))
+	r.False(env.IsSlotWarm(address, slot))
+	// Checking does not warm the address or slot
+	r.False(statedb.AddressInAccessList(address))
+
+	statedb.AddSlotToAccessList(address, slot)
+	r.True(env.IsAddressWarm(address))
+	r.True(env.IsSlotWarm(address, slot))
+	r.False(env.IsSlotWarm(address, common.Hash{0x03}))
+
+	r.Equal(env.Gas(), gas-5*GasQuickStep)
+}
+
+func TestGetExternalStorage(t *testing.T) {
+	var (
+		r        = require.New(t)
+		config   = EnvConfig{IsStatic: true, IsTrusted: false}
+		meterGas = true
+		gas      = uint64(1e6)
+		address  = common.Address{0x01}
+		slot     = common.Hash{0x02}
+		value    = common.Hash{0x03}
+	)
+
+	env, statedb, _, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
+	env.contract.Gas = gas
+	statedb.SetState(address, slot, value)
+
+	// Cold account and slot
+	r.Equal(value, env.GetExternalStorage(address, slot))
+	r.Equal(gas-params.ColdAccountAccessCostEIP2929+params.WarmStorageReadCostEIP2929-params.ColdSloadCostEIP2929, env.Gas())
+	r.True(statedb.AddressInAccessList(address))
+	_, slotOk := statedb.SlotInAccessList(address, slot)
+	r.True(slotOk)
+
+	// Warm account and slot
+	gas = env.Gas()
+	r.Equal(value, env.GetExternalStorage(address, slot))
+	r.Equal(gas-params.WarmStorageReadCostEIP2929, env.Gas())
+
+	// Warm account, cold slot
+	gas = env.Gas()
+	r.Equal(common.Hash{}, env.GetExternalStorage(address, common.Hash{0x04}))
+	r.Equal(gas-params.ColdSloadCostEIP2929, env.Gas())
+}
+
+func TestSystemMethods(t *testing.T) {
+	var (
+		r        = require.New(t)
+		config   = EnvConfig{IsStatic: false, IsTrusted: true, Capabilities: CapabilityAll}
+		meterGas = true
+		gas      = uint64(1e6)
+		address  = common.Address{0x01}
+		slot     = common.Hash{0x02}
+		value    = common.Hash{0x03}
+		code     = []byte{0x60, 0x00}
+	)
+
+	env, _statedb, _, _ := NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
+	statedb := _statedb.(*state.StateDB)
+	env.contract.Gas = gas
+
+	snapshot := statedb.Snapshot()
+
+	env.MintBalance(address, uint256.NewInt(100))
+	env.BurnBalance(address, uint256.NewInt(40))
+	r.Equal(uint256.NewInt(60), statedb.GetBalance(address))
+	r.PanicsWithError(ErrInsufficientFunds.Error(), func() { env.BurnBalance(address, uint256.NewInt(61)) })
+
+	env.SetExternalNonce(address, 5)
+	r.Equal(uint64(5), statedb.GetNonce(address))
+	r.PanicsWithError(ErrNonceDecrease.Error(), func() { env.SetExternalNonce(address, 4) })
+
+	env.SetExternalStorage(address, slot, value)
+	r.Equal(value, statedb.GetState(address, slot))
+	r.Equal(value, env.GetExternalStorage(address, slot))
+
+	env.SetExternalCode(address, code)
+	r.Equal(code, statedb.GetCode(address))
+
+	r.Less(env.Gas(), gas)
+
+	// All changes are journaled and undone on revert
+	statedb.RevertToSnapshot(snapshot)
+	r.True(statedb.GetBalance(address).IsZero())
+	r.Zero(statedb.GetNonce(address))
+	r.Equal(common.Hash{}, statedb.GetState(address, slot))
+	r.Empty(statedb.GetCode(address))
+
+	// Operations require their capability to be granted
+	config.Capabilities = CapabilityBalance
+	env, _, _, _ = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
+	env.contract.Gas = gas
+	env.MintBalance(address, uint256.NewInt(1))
+	r.PanicsWithError(ErrMissingCapability.Error(), func() { env.SetExternalNonce(address, 1) })
+	r.PanicsWithError(ErrMissingCapability.Error(), func() { env.SetExternalStorage(address, slot, value) })
+	r.PanicsWithError(ErrMissingCapability.Error(), func() { env.SetExternalCode(address, code) })
 }
 
 func TestCallMethods(t *testing.T) {
@@ -286,7 +503,7 @@ func TestCallMethods(t *testing.T) {
 
 	t.Run("CallStatic", func(t *testing.T) {
 		var (
-			env, _, _, _caller = NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+			env, _, _, _caller = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 			caller             = _caller.(*mockCaller)
 			gas                = uint64(1e6)
 			callAddr           = common.Address{0x01}
@@ -314,7 +531,7 @@ func TestCallMethods(t *testing.T) {
 
 	t.Run("CallStaticInsufficientGas", func(t *testing.T) {
 		var (
-			env, _, _, _caller = NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+			env, _, _, _caller = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 			caller             = _caller.(*mockCaller)
 			gas                = uint64(3000)
 			availableGas       = gas - params.ColdAccountAccessCostEIP2929
@@ -334,7 +551,7 @@ func TestCallMethods(t *testing.T) {
 
 	t.Run("Call", func(t *testing.T) {
 		var (
-			env, _, _, _caller = NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+			env, _, _, _caller = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 			caller             = _caller.(*mockCaller)
 			gas                = uint64(1e6)
 			callAddr           = common.Address{0x01}
@@ -364,7 +581,7 @@ func TestCallMethods(t *testing.T) {
 
 	t.Run("CallInsufficientGas", func(t *testing.T) {
 		var (
-			env, _, _, _caller = NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+			env, _, _, _caller = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 			caller             = _caller.(*mockCaller)
 			gas                = uint64(3000)
 			availableGas       = gas - params.ColdAccountAccessCostEIP2929
@@ -384,7 +601,7 @@ func TestCallMethods(t *testing.T) {
 
 	t.Run("CallDelegate", func(t *testing.T) {
 		var (
-			env, _, _, _caller = NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+			env, _, _, _caller = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 			caller             = _caller.(*mockCaller)
 			gas                = uint64(1e6)
 			callAddr           = common.Address{0x01}
@@ -412,7 +629,7 @@ func TestCallMethods(t *testing.T) {
 
 	t.Run("CallDelegateInsufficientGas", func(t *testing.T) {
 		var (
-			env, _, _, _caller = NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+			env, _, _, _caller = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 			caller             = _caller.(*mockCaller)
 			gas                = uint64(3000)
 			availableGas       = gas - params.ColdAccountAccessCostEIP2929
@@ -432,7 +649,7 @@ func TestCallMethods(t *testing.T) {
 
 	t.Run("Create", func(t *testing.T) {
 		var (
-			env, _, _, _caller = NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+			env, _, _, _caller = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 			caller             = _caller.(*mockCaller)
 			gas                = uint64(1e6)
 			createInput        = []byte("input")
@@ -462,7 +679,7 @@ func TestCallMethods(t *testing.T) {
 
 	t.Run("CreateInsufficientGas", func(t *testing.T) {
 		var (
-			env, _, _, _caller = NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+			env, _, _, _caller = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 			caller             = _caller.(*mockCaller)
 			gas                = uint64(32005)
 			createInput        = []byte("input")
@@ -482,7 +699,7 @@ func TestCallMethods(t *testing.T) {
 
 	t.Run("Create2", func(t *testing.T) {
 		var (
-			env, _, _, _caller = NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+			env, _, _, _caller = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 			caller             = _caller.(*mockCaller)
 			gas                = uint64(1e6)
 			createInput        = []byte("input")
@@ -514,7 +731,7 @@ func TestCallMethods(t *testing.T) {
 
 	t.Run("Create2InsufficientGas", func(t *testing.T) {
 		var (
-			env, _, _, _caller = NewMockEnvironment(WithConfig(config), WithMeterGas(meterGas))
+			env, _, _, _caller = NewMockEnvironment(WithAbiVersion(AbiVersion2), WithConfig(config), WithMeterGas(meterGas))
 			caller             = _caller.(*mockCaller)
 			gas                = uint64(32010)
 			createInput        = []byte("input")
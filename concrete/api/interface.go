@@ -33,6 +33,8 @@ type BlockContext interface {
 	BaseFee() *uint256.Int
 	Coinbase() common.Address
 	Random() common.Hash
+	BlobBaseFee() *uint256.Int
+	ChainID() *uint256.Int
 }
 
 type Caller interface {
@@ -36,8 +36,14 @@ type StateDB interface {
 	GetCode(common.Address) []byte
 	GetCodeSize(common.Address) int
 	GetCodeHash(common.Address) common.Hash
+	SetCode(common.Address, []byte)
 	// Balance
 	GetBalance(addr common.Address) *uint256.Int
+	AddBalance(common.Address, *uint256.Int)
+	SubBalance(common.Address, *uint256.Int)
+	// Nonce
+	GetNonce(common.Address) uint64
+	SetNonce(common.Address, uint64)
 	// Logs
 	AddLog(*types.Log)
 	// Refunds
@@ -19,6 +19,8 @@ import "errors"
 
 var (
 	ErrEnvNotTrusted     = errors.New("environment not trusted")
+	ErrMissingCapability = errors.New("missing capability")
+	ErrSimulationOnly    = errors.New("operation only available in simulation")
 	ErrWriteProtection   = errors.New("write protection")
 	ErrOutOfGas          = errors.New("out of gas")
 	ErrGasUintOverflow   = errors.New("gas uint64 overflow")
@@ -26,6 +28,8 @@ var (
 	ErrInvalidOpCode     = errors.New("invalid opcode")
 	ErrInvalidInput      = errors.New("invalid input")
 	ErrExecutionReverted = errors.New("execution reverted")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrNonceDecrease     = errors.New("nonce cannot be decreased")
 )
 
 const (
@@ -47,6 +51,8 @@ type operation struct {
 	constantGas uint64
 	dynamicGas  gasFunc
 	trusted     bool
+	capability  Capability
+	simulation  bool // Only available in simulation execution modes
 	static      bool
 }
 
@@ -22,8 +22,7 @@ package api
 
 import (
 	"errors"
-	"fmt"
-	"os"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -31,11 +30,38 @@ import (
 	"github.com/ethereum/go-ethereum/concrete/crypto"
 	"github.com/ethereum/go-ethereum/concrete/utils"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/holiman/uint256"
 )
 
-func newEnvironmentMethods() JumpTable {
+var (
+	v1EnvironmentMethods = newV1EnvironmentMethods()
+	v2EnvironmentMethods = newV2EnvironmentMethods()
+)
+
+// jumpTables maps every supported ABI version to its jump table. The tables
+// are built once and shared by all environments, so they must not be modified.
+var jumpTables = map[AbiVersion]*JumpTable{
+	AbiVersion1: &v1EnvironmentMethods,
+	AbiVersion2: &v2EnvironmentMethods,
+}
+
+// newEnvironmentMethods returns the table environments start with. Precompiles
+// run against version 1 unless they opt into a later version, see SetAbiVersion.
+func newEnvironmentMethods() *JumpTable {
+	return jumpTables[AbiVersion1]
+}
+
+func newEnvironmentMethodsForVersion(version AbiVersion) (*JumpTable, error) {
+	table, ok := jumpTables[version]
+	if !ok {
+		return nil, ErrUnsupportedAbiVersion
+	}
+	return table, nil
+}
+
+func newV1EnvironmentMethods() JumpTable {
 	tbl := JumpTable{
 		EnableGasMetering_OpCode: {
 			execute: opEnableGasMetering,
@@ -256,6 +282,129 @@ func newEnvironmentMethods() JumpTable {
 	return tbl
 }
 
+// newV2EnvironmentMethods returns the jump table of ABI version 2, which adds
+// the execution mode, chain, OP-stack, access list and system operations and
+// restricts TimeNow to simulations. The version 1 table must not change, as
+// deployed guests depend on its behavior during consensus execution.
+func newV2EnvironmentMethods() JumpTable {
+	tbl := newV1EnvironmentMethods()
+	changes := JumpTable{
+		GetExecutionMode_OpCode: {
+			execute:     opGetExecutionMode,
+			constantGas: GasQuickStep,
+			static:      true,
+		},
+		TimeNow_OpCode: {
+			execute:    opTimeNow,
+			trusted:    true,
+			simulation: true,
+			static:     true,
+		},
+		GetBlobBaseFee_OpCode: {
+			execute:     opGetBlobBaseFee,
+			constantGas: GasQuickStep,
+			static:      true,
+		},
+		GetChainID_OpCode: {
+			execute:     opGetChainID,
+			constantGas: GasQuickStep,
+			static:      true,
+		},
+		GetBlobHash_OpCode: {
+			execute:     opGetBlobHash,
+			constantGas: GasFastestStep,
+			static:      true,
+		},
+		GetL1BlockNumber_OpCode: {
+			execute:    opGetL1BlockNumber,
+			dynamicGas: gasL1BlockSlot(types.L1BlockNumberSlot),
+			static:     true,
+		},
+		GetL1BlockHash_OpCode: {
+			execute:    opGetL1BlockHash,
+			dynamicGas: gasL1BlockSlot(types.L1BlockHashSlot),
+			static:     true,
+		},
+		GetL1BaseFee_OpCode: {
+			execute:    opGetL1BaseFee,
+			dynamicGas: gasL1BlockSlot(types.L1BaseFeeSlot),
+			static:     true,
+		},
+		GetTxL1Fee_OpCode: {
+			execute:     opGetTxL1Fee,
+			constantGas: GasQuickStep,
+			static:      true,
+		},
+		IsDepositTx_OpCode: {
+			execute:     opIsDepositTx,
+			constantGas: GasQuickStep,
+			static:      true,
+		},
+		IsAddressWarm_OpCode: {
+			execute:     opIsAddressWarm,
+			constantGas: GasQuickStep,
+			static:      true,
+		},
+		IsSlotWarm_OpCode: {
+			execute:     opIsSlotWarm,
+			constantGas: GasQuickStep,
+			static:      true,
+		},
+		GetExternalStorage_OpCode: {
+			execute:    opGetExternalStorage,
+			dynamicGas: gasGetExternalStorage,
+			static:     true,
+		},
+		MintBalance_OpCode: {
+			execute:     opMintBalance,
+			constantGas: params.WarmStorageReadCostEIP2929,
+			dynamicGas:  gasMintBalance,
+			trusted:     true,
+			capability:  CapabilityBalance,
+			static:      false,
+		},
+		BurnBalance_OpCode: {
+			execute:     opBurnBalance,
+			constantGas: params.WarmStorageReadCostEIP2929,
+			dynamicGas:  gasBurnBalance,
+			trusted:     true,
+			capability:  CapabilityBalance,
+			static:      false,
+		},
+		SetExternalNonce_OpCode: {
+			execute:     opSetExternalNonce,
+			constantGas: params.WarmStorageReadCostEIP2929,
+			dynamicGas:  gasSetExternalNonce,
+			trusted:     true,
+			capability:  CapabilityNonce,
+			static:      false,
+		},
+		SetExternalStorage_OpCode: {
+			execute:    opSetExternalStorage,
+			dynamicGas: gasSetExternalStorage,
+			trusted:    true,
+			capability: CapabilityStorage,
+			static:     false,
+		},
+		SetExternalCode_OpCode: {
+			execute:     opSetExternalCode,
+			constantGas: params.WarmStorageReadCostEIP2929,
+			dynamicGas:  gasSetExternalCode,
+			trusted:     true,
+			capability:  CapabilityCode,
+			static:      false,
+		},
+	}
+
+	for i, entry := range changes {
+		if entry != nil {
+			tbl[i] = entry
+		}
+	}
+
+	return tbl
+}
+
 func toWordSize(size int) uint64 {
 	return uint64((size + 31) / 32)
 }
@@ -306,11 +455,22 @@ func opDebug(env *Env, args [][]byte) ([][]byte, error) {
 	if len(args) != 1 {
 		return nil, ErrInvalidInput
 	}
-	msg := string(args[0])
-	fmt.Fprint(os.Stderr, msg)
+	msg := strings.TrimSuffix(string(args[0]), "\n")
+	if env.debugTracer != nil {
+		env.debugTracer.CaptureEnvDebug(env.contract.Address, msg)
+	}
+	// Dropped without formatting unless debug logs are enabled
+	log.Debug("Precompile debug", "address", env.contract.Address, "origin", env.contract.Origin, "block", env.block.BlockNumber(), "msg", msg)
 	return nil, nil
 }
 
+func opGetExecutionMode(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 0 {
+		return nil, ErrInvalidInput
+	}
+	return [][]byte{{byte(env.config.Mode)}}, nil
+}
+
 func opTimeNow(env *Env, args [][]byte) ([][]byte, error) {
 	if len(args) != 0 {
 		return nil, ErrInvalidInput
@@ -432,6 +592,22 @@ func opGetPrevRandom(env *Env, args [][]byte) ([][]byte, error) {
 	return [][]byte{random.Bytes()}, nil
 }
 
+func opGetBlobBaseFee(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 0 {
+		return nil, ErrInvalidInput
+	}
+	blobBaseFee := env.block.BlobBaseFee()
+	return [][]byte{blobBaseFee.Bytes()}, nil
+}
+
+func opGetChainID(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 0 {
+		return nil, ErrInvalidInput
+	}
+	chainID := env.block.ChainID()
+	return [][]byte{chainID.Bytes()}, nil
+}
+
 func opGetBlockHash(env *Env, args [][]byte) ([][]byte, error) {
 	if len(args) != 1 {
 		return nil, ErrInvalidInput
@@ -480,6 +656,86 @@ func opGetTxOrigin(env *Env, args [][]byte) ([][]byte, error) {
 	return [][]byte{origin.Bytes()}, nil
 }
 
+func opGetBlobHash(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, ErrInvalidInput
+	}
+	if len(args[0]) != 8 {
+		return nil, ErrInvalidInput
+	}
+	index := utils.BytesToUint64(args[0])
+	var hash common.Hash
+	if index < uint64(len(env.contract.BlobHashes)) {
+		hash = env.contract.BlobHashes[index]
+	}
+	return [][]byte{hash.Bytes()}, nil
+}
+
+// The L1 block attributes are read from the storage of the L1Block predeploy,
+// so they are zero on chains that are not OP-stack chains. Reading them costs
+// the same as reading the slot with GetExternalStorage.
+
+func gasL1BlockSlot(slot common.Hash) gasFunc {
+	return func(env *Env, args [][]byte) (uint64, error) {
+		if len(args) != 0 {
+			return 0, ErrInvalidInput
+		}
+		gas, err := gasAccountAccessMinusWarm(env, types.L1BlockAddr)
+		if err != nil {
+			return 0, err
+		}
+		return gas + gasStorageLoadAt(env, types.L1BlockAddr, slot), nil
+	}
+}
+
+func opGetL1BlockNumber(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 0 {
+		return nil, ErrInvalidInput
+	}
+	slot := env.statedb.GetState(types.L1BlockAddr, types.L1BlockNumberSlot)
+	return [][]byte{common.CopyBytes(slot[24:32])}, nil
+}
+
+func opGetL1BlockHash(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 0 {
+		return nil, ErrInvalidInput
+	}
+	hash := env.statedb.GetState(types.L1BlockAddr, types.L1BlockHashSlot)
+	return [][]byte{hash.Bytes()}, nil
+}
+
+func opGetL1BaseFee(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 0 {
+		return nil, ErrInvalidInput
+	}
+	baseFee := env.statedb.GetState(types.L1BlockAddr, types.L1BaseFeeSlot)
+	return [][]byte{baseFee.Bytes()}, nil
+}
+
+// opGetTxL1Fee has a flat cost as the fee is computed by the node from the
+// transaction data, and the L1 cost function reads the L1Block attributes once
+// per block outside of the access list, as it does when charging the fee.
+func opGetTxL1Fee(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 0 {
+		return nil, ErrInvalidInput
+	}
+	var fee *uint256.Int
+	if env.contract.L1Fee != nil {
+		fee = env.contract.L1Fee()
+	}
+	if fee == nil {
+		fee = new(uint256.Int)
+	}
+	return [][]byte{fee.Bytes()}, nil
+}
+
+func opIsDepositTx(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 0 {
+		return nil, ErrInvalidInput
+	}
+	return [][]byte{utils.BoolToBytes(env.contract.IsDeposit)}, nil
+}
+
 func opGetCallData(env *Env, args [][]byte) ([][]byte, error) {
 	if len(args) != 0 {
 		return nil, ErrInvalidInput
@@ -540,11 +796,17 @@ func gasStorageLoad(env *Env, args [][]byte) (uint64, error) {
 		return 0, ErrInvalidInput
 	}
 	key := common.BytesToHash(args[0])
-	if _, slotPresent := env.statedb.SlotInAccessList(env.contract.Address, key); !slotPresent {
-		env.statedb.AddSlotToAccessList(env.contract.Address, key)
-		return params.ColdSloadCostEIP2929, nil
+	return gasStorageLoadAt(env, env.contract.Address, key), nil
+}
+
+// gasStorageLoadAt returns the SLOAD gas of reading the given slot of an
+// account and adds the slot to the access list.
+func gasStorageLoadAt(env *Env, address common.Address, key common.Hash) uint64 {
+	if _, slotPresent := env.statedb.SlotInAccessList(address, key); !slotPresent {
+		env.statedb.AddSlotToAccessList(address, key)
+		return params.ColdSloadCostEIP2929
 	}
-	return params.WarmStorageReadCostEIP2929, nil
+	return params.WarmStorageReadCostEIP2929
 }
 
 func opStorageLoad(env *Env, args [][]byte) ([][]byte, error) {
@@ -572,23 +834,29 @@ func gasStorageStore(env *Env, args [][]byte) (uint64, error) {
 	if len(args[0]) != 32 || len(args[1]) != 32 {
 		return 0, ErrInvalidInput
 	}
+	key := common.BytesToHash(args[0])
+	value := common.BytesToHash(args[1])
+	return gasStorageStoreAt(env, env.contract.Address, key, value)
+}
+
+// gasStorageStoreAt returns the SSTORE gas of writing the given slot of an
+// account, adds the slot to the access list and updates the refund counter.
+func gasStorageStoreAt(env *Env, address common.Address, key common.Hash, value common.Hash) (uint64, error) {
 	if env.contract.Gas <= params.SstoreSentryGasEIP2200 {
 		return 0, errors.New("not enough gas for reentrancy sentry")
 	}
 	var (
-		key     = common.BytesToHash(args[0])
-		current = env.statedb.GetState(env.contract.Address, key)
+		current = env.statedb.GetState(address, key)
 		cost    = uint64(0)
 	)
-	if _, slotPresent := env.statedb.SlotInAccessList(env.contract.Address, key); !slotPresent {
+	if _, slotPresent := env.statedb.SlotInAccessList(address, key); !slotPresent {
 		cost = params.ColdSloadCostEIP2929
-		env.statedb.AddSlotToAccessList(env.contract.Address, key)
+		env.statedb.AddSlotToAccessList(address, key)
 	}
-	value := common.BytesToHash(args[1])
 	if current == value {
 		return cost + params.WarmStorageReadCostEIP2929, nil
 	}
-	original := env.statedb.GetCommittedState(env.contract.Address, key)
+	original := env.statedb.GetCommittedState(address, key)
 	if original == current {
 		if original == (common.Hash{}) {
 			return cost + params.SstoreSetGasEIP2200, nil
@@ -738,6 +1006,31 @@ func opGetExternalCodeHash(env *Env, args [][]byte) ([][]byte, error) {
 	return [][]byte{hash.Bytes()}, nil
 }
 
+func opIsAddressWarm(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 1 {
+		return nil, ErrInvalidInput
+	}
+	if len(args[0]) != 20 {
+		return nil, ErrInvalidInput
+	}
+	address := common.BytesToAddress(args[0])
+	warm := env.statedb.AddressInAccessList(address)
+	return [][]byte{utils.BoolToBytes(warm)}, nil
+}
+
+func opIsSlotWarm(env *Env, args [][]byte) ([][]byte, error) {
+	if len(args) != 2 {
+		return nil, ErrInvalidInput
+	}
+	if len(args[0]) != 20 || len(args[1]) != 32 {
+		return nil, ErrInvalidInput
+	}
+	address := common.BytesToAddress(args[0])
+	key := common.BytesToHash(args[1])
+	_, warm := env.statedb.SlotInAccessList(address, key)
+	return [][]byte{utils.BoolToBytes(warm)}, nil
+}
+
 func gasCallStatic(env *Env, args [][]byte) (uint64, error) {
 	if len(args) != 3 {
 		return 0, ErrInvalidInput
@@ -874,3 +1167,136 @@ func opCreate2(env *Env, args [][]byte) ([][]byte, error) {
 	env.contract.Gas += gasLeft
 	return [][]byte{ret, address.Bytes(), utils.EncodeError(err)}, nil
 }
+
+func gasGetExternalStorage(env *Env, args [][]byte) (uint64, error) {
+	if len(args) != 2 {
+		return 0, ErrInvalidInput
+	}
+	if len(args[0]) != 20 || len(args[1]) != 32 {
+		return 0, ErrInvalidInput
+	}
+	address := common.BytesToAddress(args[0])
+	key := common.BytesToHash(args[1])
+	// Reading a slot of a cold account also loads the account
+	gas, err := gasAccountAccessMinusWarm(env, address)
+	if err != nil {
+		return 0, err
+	}
+	return gas + gasStorageLoadAt(env, address, key), nil
+}
+
+func opGetExternalStorage(env *Env, args [][]byte) ([][]byte, error) {
+	address := common.BytesToAddress(args[0])
+	key := common.BytesToHash(args[1])
+	value := env.statedb.GetState(address, key)
+	return [][]byte{value.Bytes()}, nil
+}
+
+func gasMintBalance(env *Env, args [][]byte) (uint64, error) {
+	if len(args) != 2 {
+		return 0, ErrInvalidInput
+	}
+	if len(args[0]) != 20 || len(args[1]) != 32 {
+		return 0, ErrInvalidInput
+	}
+	address := common.BytesToAddress(args[0])
+	return gasAccountAccessMinusWarm(env, address)
+}
+
+func opMintBalance(env *Env, args [][]byte) ([][]byte, error) {
+	address := common.BytesToAddress(args[0])
+	amount := new(uint256.Int).SetBytes(args[1])
+	if _, overflow := new(uint256.Int).AddOverflow(env.statedb.GetBalance(address), amount); overflow {
+		return nil, ErrInvalidInput
+	}
+	env.statedb.AddBalance(address, amount)
+	return nil, nil
+}
+
+func gasBurnBalance(env *Env, args [][]byte) (uint64, error) {
+	if len(args) != 2 {
+		return 0, ErrInvalidInput
+	}
+	if len(args[0]) != 20 || len(args[1]) != 32 {
+		return 0, ErrInvalidInput
+	}
+	address := common.BytesToAddress(args[0])
+	return gasAccountAccessMinusWarm(env, address)
+}
+
+func opBurnBalance(env *Env, args [][]byte) ([][]byte, error) {
+	address := common.BytesToAddress(args[0])
+	amount := new(uint256.Int).SetBytes(args[1])
+	if env.statedb.GetBalance(address).Lt(amount) {
+		return nil, ErrInsufficientFunds
+	}
+	env.statedb.SubBalance(address, amount)
+	return nil, nil
+}
+
+func gasSetExternalNonce(env *Env, args [][]byte) (uint64, error) {
+	if len(args) != 2 {
+		return 0, ErrInvalidInput
+	}
+	if len(args[0]) != 20 || len(args[1]) != 8 {
+		return 0, ErrInvalidInput
+	}
+	address := common.BytesToAddress(args[0])
+	return gasAccountAccessMinusWarm(env, address)
+}
+
+func opSetExternalNonce(env *Env, args [][]byte) ([][]byte, error) {
+	address := common.BytesToAddress(args[0])
+	nonce := utils.BytesToUint64(args[1])
+	// Decreasing a nonce would allow replaying transactions
+	if nonce < env.statedb.GetNonce(address) {
+		return nil, ErrNonceDecrease
+	}
+	env.statedb.SetNonce(address, nonce)
+	return nil, nil
+}
+
+func gasSetExternalStorage(env *Env, args [][]byte) (uint64, error) {
+	if len(args) != 3 {
+		return 0, ErrInvalidInput
+	}
+	if len(args[0]) != 20 || len(args[1]) != 32 || len(args[2]) != 32 {
+		return 0, ErrInvalidInput
+	}
+	address := common.BytesToAddress(args[0])
+	key := common.BytesToHash(args[1])
+	value := common.BytesToHash(args[2])
+	return gasStorageStoreAt(env, address, key, value)
+}
+
+func opSetExternalStorage(env *Env, args [][]byte) ([][]byte, error) {
+	address := common.BytesToAddress(args[0])
+	key := common.BytesToHash(args[1])
+	value := common.BytesToHash(args[2])
+	env.statedb.SetState(address, key, value)
+	return nil, nil
+}
+
+func gasSetExternalCode(env *Env, args [][]byte) (uint64, error) {
+	if len(args) != 2 {
+		return 0, ErrInvalidInput
+	}
+	if len(args[0]) != 20 || len(args[1]) > params.MaxCodeSize {
+		return 0, ErrInvalidInput
+	}
+	address := common.BytesToAddress(args[0])
+	gas, err := gasAccountAccessMinusWarm(env, address)
+	if err != nil {
+		return 0, err
+	}
+	// Charged like the code deposit of a contract creation
+	return gas + uint64(len(args[1]))*params.CreateDataGas, nil
+}
+
+func opSetExternalCode(env *Env, args [][]byte) ([][]byte, error) {
+	address := common.BytesToAddress(args[0])
+	code := make([]byte, len(args[1]))
+	copy(code, args[1])
+	env.statedb.SetCode(address, code)
+	return nil, nil
+}
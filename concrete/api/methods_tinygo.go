@@ -20,6 +20,17 @@
 
 package api
 
-func newEnvironmentMethods() JumpTable {
-	return JumpTable{}
+var emptyEnvironmentMethods JumpTable
+
+var jumpTables = map[AbiVersion]*JumpTable{
+	AbiVersion1: &emptyEnvironmentMethods,
+	AbiVersion2: &emptyEnvironmentMethods,
+}
+
+func newEnvironmentMethods() *JumpTable {
+	return &emptyEnvironmentMethods
+}
+
+func newEnvironmentMethodsForVersion(version AbiVersion) (*JumpTable, error) {
+	return &emptyEnvironmentMethods, nil
 }
@@ -42,6 +42,7 @@ type mockEnvConfig struct {
 	db        StateDB
 	blockCtx  BlockContext
 	caller    Caller
+	version   AbiVersion
 }
 
 type MockEnvOption func(*mockEnvConfig)
@@ -94,10 +95,17 @@ func WithCaller(caller Caller) MockEnvOption {
 	}
 }
 
+func WithAbiVersion(version AbiVersion) MockEnvOption {
+	return func(c *mockEnvConfig) {
+		c.version = version
+	}
+}
+
 func NewMockEnvironment(opts ...MockEnvOption) (*Env, StateDB, BlockContext, Caller) {
 	mockConfig := &mockEnvConfig{
 		envConfig: EnvConfig{},
 		meterGas:  false,
+		version:   AbiVersion1,
 	}
 
 	for _, opt := range opts {
@@ -118,6 +126,9 @@ func NewMockEnvironment(opts ...MockEnvOption) (*Env, StateDB, BlockContext, Cal
 	}
 
 	env := NewEnvironment(mockConfig.envConfig, mockConfig.meterGas, mockConfig.db, mockConfig.blockCtx, mockConfig.caller, mockConfig.contract)
+	if err := env.SetAbiVersion(mockConfig.version); err != nil {
+		panic(err)
+	}
 
 	return env, mockConfig.db, mockConfig.blockCtx, mockConfig.caller
 }
@@ -130,6 +141,8 @@ type mockBlockContext struct {
 	difficulty  *uint256.Int
 	baseFee     *uint256.Int
 	random      common.Hash
+	blobBaseFee *uint256.Int
+	chainID     *uint256.Int
 	blockHashes map[uint64]common.Hash
 }
 
@@ -142,6 +155,8 @@ func NewMockBlockContext() *mockBlockContext {
 		difficulty:  uint256.NewInt(0),
 		baseFee:     uint256.NewInt(0),
 		random:      common.Hash{},
+		blobBaseFee: uint256.NewInt(0),
+		chainID:     uint256.NewInt(0),
 		blockHashes: make(map[uint64]common.Hash),
 	}
 }
@@ -174,6 +189,14 @@ func (m *mockBlockContext) SetRandom(random common.Hash) {
 	m.random = random
 }
 
+func (m *mockBlockContext) SetBlobBaseFee(blobBaseFee *uint256.Int) {
+	m.blobBaseFee = blobBaseFee
+}
+
+func (m *mockBlockContext) SetChainID(chainID *uint256.Int) {
+	m.chainID = chainID
+}
+
 func (m *mockBlockContext) SetBlockHash(blockNumber uint64, hash common.Hash) {
 	m.blockHashes[blockNumber] = hash
 }
@@ -186,6 +209,8 @@ func (m *mockBlockContext) Difficulty() *uint256.Int               { return m.di
 func (m *mockBlockContext) BaseFee() *uint256.Int                  { return m.baseFee }
 func (m *mockBlockContext) Coinbase() common.Address               { return m.coinbase }
 func (m *mockBlockContext) Random() common.Hash                    { return m.random }
+func (m *mockBlockContext) BlobBaseFee() *uint256.Int              { return m.blobBaseFee }
+func (m *mockBlockContext) ChainID() *uint256.Int                  { return m.chainID }
 
 var _ BlockContext = (*mockBlockContext)(nil)
 
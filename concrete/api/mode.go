@@ -0,0 +1,55 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+// ExecutionMode is the context a precompile is executed in. The consensus mode
+// executes transactions that are part of the chain, both when building and
+// importing blocks so they execute the same way on every node. Simulation modes
+// execute calls whose results are only returned over RPC.
+type ExecutionMode uint8
+
+const (
+	ExecutionModeConsensus ExecutionMode = iota // Chain transactions
+	ExecutionModeCall                           // Calls, e.g. eth_call
+	ExecutionModeEstimate                       // Gas estimation
+	ExecutionModeTrace                          // Traced calls, e.g. debug_traceCall
+)
+
+// IsSimulation returns whether the mode executes calls that are not part of
+// the chain, in which simulation-only operations are available.
+func (mode ExecutionMode) IsSimulation() bool {
+	switch mode {
+	case ExecutionModeCall, ExecutionModeEstimate, ExecutionModeTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func (mode ExecutionMode) String() string {
+	switch mode {
+	case ExecutionModeConsensus:
+		return "consensus"
+	case ExecutionModeCall:
+		return "call"
+	case ExecutionModeEstimate:
+		return "estimate"
+	case ExecutionModeTrace:
+		return "trace"
+	default:
+		return "unknown"
+	}
+}
@@ -30,6 +30,7 @@ const (
 	// ManyOps_OpCode OpCode = 0x04
 	// Meta-env
 	EnableGasMetering_OpCode OpCode = 0x08
+	GetExecutionMode_OpCode  OpCode = 0x09
 	// Debug
 	Debug_OpCode   OpCode = 0x0c
 	TimeNow_OpCode OpCode = 0x0d
@@ -59,6 +60,15 @@ const (
 	TransientLoad_OpCode      OpCode = 0x45
 	GetCode_OpCode            OpCode = 0x42
 	GetCodeSize_OpCode        OpCode = 0x43
+	GetChainID_OpCode         OpCode = 0x46
+	GetBlobBaseFee_OpCode     OpCode = 0x47
+	GetBlobHash_OpCode        OpCode = 0x48
+	// Internal reads -- OP-stack
+	GetL1BlockNumber_OpCode OpCode = 0x49
+	GetL1BlockHash_OpCode   OpCode = 0x4a
+	GetL1BaseFee_OpCode     OpCode = 0x4b
+	GetTxL1Fee_OpCode       OpCode = 0x4c
+	IsDepositTx_OpCode      OpCode = 0x4d
 	// Internal writes
 	StorageStore_OpCode   OpCode = 0x51
 	TransientStore_OpCode OpCode = 0x55
@@ -69,9 +79,40 @@ const (
 	GetExternalCode_OpCode     OpCode = 0x62
 	GetExternalCodeSize_OpCode OpCode = 0x63
 	GetExternalCodeHash_OpCode OpCode = 0x64
+	IsAddressWarm_OpCode       OpCode = 0x65
+	IsSlotWarm_OpCode          OpCode = 0x66
+	GetExternalStorage_OpCode  OpCode = 0x67
 	// External writes
 	Call_OpCode         OpCode = 0x70
 	CallDelegate_OpCode OpCode = 0x71
 	Create_OpCode       OpCode = 0x72
 	Create2_OpCode      OpCode = 0x73
+	// System -- trusted, require a capability
+	MintBalance_OpCode        OpCode = 0x80
+	BurnBalance_OpCode        OpCode = 0x81
+	SetExternalNonce_OpCode   OpCode = 0x82
+	SetExternalStorage_OpCode OpCode = 0x83
+	SetExternalCode_OpCode    OpCode = 0x84
 )
+
+// Class returns the group of operations the opcode belongs to.
+func (opcode OpCode) Class() string {
+	switch {
+	case opcode < Debug_OpCode:
+		return "meta"
+	case opcode < UseGas_OpCode:
+		return "debug"
+	case opcode < GetAddress_OpCode:
+		return "utils"
+	case opcode < StorageStore_OpCode:
+		return "internal reads"
+	case opcode < CallStatic_OpCode:
+		return "internal writes"
+	case opcode < Call_OpCode:
+		return "external reads"
+	case opcode < MintBalance_OpCode:
+		return "external writes"
+	default:
+		return "system"
+	}
+}
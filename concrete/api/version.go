@@ -0,0 +1,54 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import "errors"
+
+var ErrUnsupportedAbiVersion = errors.New("unsupported ABI version")
+
+// AbiVersion identifies the host/guest contract: the opcode numbering and
+// argument encoding of environment operations and the packing of memory
+// pointers. Any breaking change to either must introduce a new version.
+type AbiVersion uint64
+
+const (
+	// AbiVersion1 is the original ABI. Guests that do not export their ABI
+	// version are assumed to use it.
+	AbiVersion1 AbiVersion = 1
+	// AbiVersion2 adds the execution mode, chain, OP-stack, access list and
+	// system operations, and restricts TimeNow to simulations.
+	AbiVersion2 AbiVersion = 2
+
+	LatestAbiVersion = AbiVersion2
+)
+
+// SupportedAbiVersions returns the ABI versions the host can execute.
+func SupportedAbiVersions() []AbiVersion {
+	versions := make([]AbiVersion, 0, len(jumpTables))
+	for v := AbiVersion1; v <= LatestAbiVersion; v++ {
+		if _, ok := jumpTables[v]; ok {
+			versions = append(versions, v)
+		}
+	}
+	return versions
+}
+
+// IsAbiVersionSupported returns whether the host can execute guests built
+// against the given ABI version.
+func IsAbiVersionSupported(version AbiVersion) bool {
+	_, ok := jumpTables[version]
+	return ok
+}
@@ -0,0 +1,72 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+// This file will be ignored when building with tinygo to prevent compatibility
+// issues.
+
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAbiVersions(t *testing.T) {
+	r := require.New(t)
+
+	r.True(IsAbiVersionSupported(AbiVersion1))
+	r.True(IsAbiVersionSupported(LatestAbiVersion))
+	r.False(IsAbiVersionSupported(0))
+	r.False(IsAbiVersionSupported(LatestAbiVersion + 1))
+	r.Contains(SupportedAbiVersions(), LatestAbiVersion)
+
+	env, _, _, _ := NewMockEnvironment()
+	r.NoError(env.SetAbiVersion(AbiVersion1))
+	r.ErrorIs(env.SetAbiVersion(LatestAbiVersion+1), ErrUnsupportedAbiVersion)
+	r.Equal(GasQuickStep, env.table[GetAddress_OpCode].constantGas)
+
+	// Tables are built once and shared by all environments
+	other, _, _, _ := NewMockEnvironment()
+	r.NoError(other.SetAbiVersion(AbiVersion1))
+	r.Same(env.table, other.table)
+}
+
+func TestAbiVersion1Table(t *testing.T) {
+	r := require.New(t)
+
+	env, _, _, _ := NewMockEnvironment(WithTrusted(true))
+	r.NoError(env.SetAbiVersion(AbiVersion1))
+
+	// Operations added in version 2 are undefined in version 1
+	for _, op := range []OpCode{
+		GetExecutionMode_OpCode,
+		GetChainID_OpCode,
+		GetTxL1Fee_OpCode,
+		IsAddressWarm_OpCode,
+		GetExternalStorage_OpCode,
+		MintBalance_OpCode,
+	} {
+		_, err := env._execute(op, env, nil)
+		r.ErrorIs(err, ErrInvalidOpCode, op)
+	}
+
+	// TimeNow is only restricted to simulations from version 2
+	r.NotPanics(func() { env.TimeNow() })
+	r.NoError(env.SetAbiVersion(AbiVersion2))
+	r.PanicsWithError(ErrSimulationOnly.Error(), func() { env.TimeNow() })
+}
@@ -22,8 +22,15 @@ import (
 	"strings"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/codegen/bindgen"
 	"github.com/ethereum/go-ethereum/concrete/codegen/datamod"
+	"github.com/ethereum/go-ethereum/concrete/codegen/scaffold"
 	"github.com/ethereum/go-ethereum/concrete/codegen/solgen"
+	"github.com/ethereum/go-ethereum/concrete/plugin"
+	"github.com/ethereum/go-ethereum/concrete/testtool"
+	"github.com/ethereum/go-ethereum/concrete/wasm"
 	"github.com/ethereum/go-ethereum/internal/version"
 	"github.com/naoina/toml"
 	"github.com/spf13/cobra"
@@ -66,6 +73,16 @@ func getStringFlags(cmd *cobra.Command, flagPairs ...interface{}) error {
 	return nil
 }
 
+func parseAddress(addressHex string) (common.Address, error) {
+	if strings.HasPrefix(addressHex, "0x") && len(addressHex) < 42 {
+		addressHex = "0x" + strings.Repeat("0", 42-len(addressHex)) + addressHex[2:]
+	}
+	if !common.IsHexAddress(addressHex) {
+		return common.Address{}, fmt.Errorf("invalid precompile address: %s", addressHex)
+	}
+	return common.HexToAddress(addressHex), nil
+}
+
 func logMustBeProvided(cmd *cobra.Command, flagName string) {
 	logFatalNoContext(fmt.Errorf("%s must be provided", flagName))
 }
@@ -95,7 +112,7 @@ func main() {
 
 	var cmdSolgen = &cobra.Command{
 		Use:   "solgen",
-		Short: "Generate a solidity precompile caller library from an ABI file",
+		Short: "Generate a solidity precompile caller library or interface from an ABI file",
 		Run:   runSolgen,
 	}
 
@@ -105,8 +122,21 @@ func main() {
 	cmdSolgen.Flags().String("abi", "", "path to the ABI file")
 	cmdSolgen.Flags().StringP("out", "o", "./", "path to the output file")
 	cmdSolgen.Flags().StringP("import", "i", "", "solidity file to import in the generated LIBRARY")
+	cmdSolgen.Flags().Bool("interface", false, "generate an interface instead of a library")
 	rootCmd.AddCommand(cmdSolgen)
 
+	var cmdBindgen = &cobra.Command{
+		Use:   "bindgen",
+		Short: "Generate go bindings to call a solidity contract from a precompile",
+		Run:   runBindgen,
+	}
+
+	cmdBindgen.Flags().StringP("name", "n", "", "name for the generated binding (default derived from the ABI file name)")
+	cmdBindgen.Flags().String("abi", "", "path to the ABI file")
+	cmdBindgen.Flags().StringP("out", "o", "./", "path to the output file")
+	cmdBindgen.Flags().StringP("pkg", "p", "main", "package name for the generated file")
+	rootCmd.AddCommand(cmdBindgen)
+
 	var cmdDatamod = &cobra.Command{
 		Use:   "datamod <path>",
 		Short: "Generate type safe go wrappers for datastore structures from a json definition",
@@ -119,6 +149,64 @@ func main() {
 	cmdDatamod.Flags().Bool("table-type-experimental", false, "whether to enable experimental features for table types")
 	rootCmd.AddCommand(cmdDatamod)
 
+	var cmdWasm = &cobra.Command{
+		Use:   "wasm",
+		Short: "Tools for WASM precompiles",
+	}
+
+	var cmdWasmInspect = &cobra.Command{
+		Use:   "inspect <path>",
+		Short: "Validate a WASM precompile and print its exports, imports and memory limits",
+		Args:  cobra.ExactArgs(1),
+		Run:   runWasmInspect,
+	}
+
+	cmdWasmInspect.Flags().Bool("strict", false, "reject WASI imports outside the deterministic shim")
+	cmdWasmInspect.Flags().Bool("smoke", false, "run IsStatic and Run against a mock environment")
+	cmdWasmInspect.Flags().String("input", "", "hex encoded input for the smoke run")
+	cmdWasmInspect.Flags().Uint64("gas", 1e7, "gas limit for the smoke run")
+	cmdWasm.AddCommand(cmdWasmInspect)
+	rootCmd.AddCommand(cmdWasm)
+
+	var cmdInit = &cobra.Command{
+		Use:   "init [dir]",
+		Short: "Create a new precompile project",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runInit,
+	}
+
+	cmdInit.Flags().StringP("name", "n", "", "precompile name (default derived from the dir name)")
+	cmdInit.Flags().StringP("module", "m", "", "go module path (default the dir name)")
+	cmdInit.Flags().StringP("address", "a", "0x80", "precompile address")
+	cmdInit.Flags().StringP("pragma", "p", "^0.8.0", "solidity pragma")
+	cmdInit.Flags().String("replace", "", "replacement for the go-ethereum module, e.g. a concrete-geth checkout (default concrete-geth at the version of this binary)")
+	rootCmd.AddCommand(cmdInit)
+
+	var cmdTest = &cobra.Command{
+		Use:   "test",
+		Short: "Run the Solidity tests of a Foundry project against concrete precompiles",
+		Run:   runTest,
+	}
+
+	cmdTest.Flags().String("out", "./out", "path to the Foundry build artifacts")
+	cmdTest.Flags().String("test-dir", "./test", "dir with the Solidity test files")
+	cmdTest.Flags().String("contract", "", "single test contract to run, as Path:Contract")
+	cmdTest.Flags().StringArrayP("precompile", "p", nil, "WASM precompile to load, as address=path (can be repeated)")
+	cmdTest.Flags().String("registry", "", "path to a TOML file listing the precompiles to load")
+	cmdTest.Flags().String("match-test", "", "only run tests whose name matches this regular expression")
+	cmdTest.Flags().String("match-contract", "", "only run test contracts whose name matches this regular expression")
+	cmdTest.Flags().Int("verbosity", 0, "0: failures only, 1: every test result, 2: also the events emitted by tests")
+	cmdTest.Flags().Int("fuzz-runs", 0, "number of inputs tried per fuzz test (default 256)")
+	cmdTest.Flags().Int64("fuzz-seed", 0, "seed of the fuzz input generator, random if zero")
+	cmdTest.Flags().Bool("gas-report", false, "print the gas used by tests and precompiles (implies verbosity 1)")
+	cmdTest.Flags().String("snapshot", "", "path of the gas snapshot file to check and update")
+	cmdTest.Flags().Float64("snapshot-tolerance", 0, "fraction of the snapshot gas a test can exceed it by")
+	cmdTest.Flags().String("json", "", "path of a file to write the test results to as JSON lines")
+	cmdTest.Flags().String("junit", "", "path of a file to write the test results to as JUnit XML")
+	cmdTest.Flags().String("fork-datadir", "", "geth datadir of a local chain to run the tests on")
+	cmdTest.Flags().Uint64("fork-block", 0, "block of the forked chain to run the tests on, the head if zero")
+	rootCmd.AddCommand(cmdTest)
+
 	if err := rootCmd.Execute(); err != nil {
 		logFatalNoContext(err)
 	}
@@ -140,17 +228,16 @@ func runSolgen(cmd *cobra.Command, args []string) {
 		logMustBeProvided(cmd, "precompile address")
 	}
 
-	var address common.Address
-	if strings.HasPrefix(addressHex, "0x") && len(addressHex) < 42 {
-		addressHex = "0x" + strings.Repeat("0", 42-len(addressHex)) + addressHex[2:]
+	address, err := parseAddress(addressHex)
+	if err != nil {
+		logFatalNoContext(err)
 	}
-	if common.IsHexAddress(addressHex) {
-		address = common.HexToAddress(addressHex)
-	} else {
-		logFatalNoContext(fmt.Errorf("invalid precompile address: %s", addressHex))
+
+	var isInterface bool
+	if isInterface, err = cmd.Flags().GetBool("interface"); err != nil {
+		logFatal(err)
 	}
 
-	var err error
 	var abiIsDir, outIsDir bool
 
 	if abiIsDir, err = isDir(abiPath); err != nil {
@@ -163,12 +250,17 @@ func runSolgen(cmd *cobra.Command, args []string) {
 	if outIsDir, err = isDir(outPath); err != nil {
 		logFatal(err)
 	}
-	if outIsDir {
-		outPath = filepath.Join(outPath, name+".sol")
-	}
 
 	if name == "" {
-		name = fileName(abiPath) + "Precompile"
+		if isInterface {
+			name = "I" + fileName(abiPath)
+		} else {
+			name = fileName(abiPath) + "Precompile"
+		}
+	}
+
+	if outIsDir {
+		outPath = filepath.Join(outPath, name+".sol")
 	}
 
 	config := solgen.Config{
@@ -178,6 +270,7 @@ func runSolgen(cmd *cobra.Command, args []string) {
 		AbiPath:    abiPath,
 		OutPath:    outPath,
 		ImportPath: importPath,
+		Interface:  isInterface,
 	}
 
 	if v, err := cmd.Flags().GetBool("verbose"); err != nil {
@@ -194,6 +287,63 @@ func runSolgen(cmd *cobra.Command, args []string) {
 	logInfo("Library written to: %s", outPath)
 }
 
+func runBindgen(cmd *cobra.Command, args []string) {
+	var name, abiPath, outPath, pkg string
+	if err := getStringFlags(cmd, &name, "name", &abiPath, "abi", &outPath, "out", &pkg, "pkg"); err != nil {
+		logFatal(err)
+	}
+
+	if abiPath == "" {
+		logMustBeProvided(cmd, "abi path")
+	}
+	if outPath == "" {
+		logMustBeProvided(cmd, "output path")
+	}
+
+	var err error
+	var abiIsDir, outIsDir bool
+
+	if abiIsDir, err = isDir(abiPath); err != nil {
+		logFatal(err)
+	}
+	if abiIsDir {
+		logFatalNoContext(fmt.Errorf("ABI path must be a file"))
+	}
+
+	if outIsDir, err = isDir(outPath); err != nil {
+		logFatal(err)
+	}
+
+	if name == "" {
+		name = fileName(abiPath)
+		name = strings.ToUpper(name[:1]) + name[1:]
+	}
+
+	if outIsDir {
+		outPath = filepath.Join(outPath, strings.ToLower(name)+".go")
+	}
+
+	config := bindgen.Config{
+		Name:    name,
+		Package: pkg,
+		AbiPath: abiPath,
+		OutPath: outPath,
+	}
+
+	if v, err := cmd.Flags().GetBool("verbose"); err != nil {
+		logFatal(err)
+	} else if v {
+		logConfig(config)
+	}
+
+	if err := bindgen.GenerateBindings(config); err != nil {
+		logFatal(err)
+	}
+
+	logInfo("Bindings generated successfully.")
+	logInfo("Bindings written to: %s", outPath)
+}
+
 func runDatamod(cmd *cobra.Command, args []string) {
 	jsonPath := args[0]
 
@@ -243,3 +393,305 @@ func runDatamod(cmd *cobra.Command, args []string) {
 	logInfo("Data model wrappers generated successfully.")
 	logInfo("Files written to: %s", outPath)
 }
+
+func runWasmInspect(cmd *cobra.Command, args []string) {
+	wasmPath := args[0]
+
+	var inputHex string
+	if err := getStringFlags(cmd, &inputHex, "input"); err != nil {
+		logFatal(err)
+	}
+	smoke, err := cmd.Flags().GetBool("smoke")
+	if err != nil {
+		logFatal(err)
+	}
+	strict, err := cmd.Flags().GetBool("strict")
+	if err != nil {
+		logFatal(err)
+	}
+	gas, err := cmd.Flags().GetUint64("gas")
+	if err != nil {
+		logFatal(err)
+	}
+
+	code, err := os.ReadFile(wasmPath)
+	if err != nil {
+		logFatalNoContext(err)
+	}
+
+	info, err := wasm.InspectModule(code)
+	if err != nil {
+		logFatalNoContext(fmt.Errorf("invalid WASM module: %w", err))
+	}
+
+	logInfo("Size: %d bytes", info.Size)
+	logInfo("Code hash: %s", info.CodeHash.Hex())
+	if info.HasMemoryMax {
+		logInfo("Memory: min %d pages, max %d pages", info.MemoryMin, info.MemoryMax)
+	} else {
+		logInfo("Memory: min %d pages, no max", info.MemoryMin)
+	}
+	if info.ExportsAbiVersion {
+		logInfo("ABI version: exported")
+	} else {
+		logInfo("ABI version: not exported, assuming %d", api.AbiVersion1)
+	}
+	logInfo("Exports:")
+	for _, name := range info.Exports {
+		logInfo("  %s", name)
+	}
+	logInfo("Imports:")
+	for _, imp := range info.Imports {
+		logInfo("  %s", imp)
+	}
+
+	for _, imp := range info.UnsupportedWasi {
+		logWarning(fmt.Sprintf("%s is not provided by the WASI shim and will fail with ENOSYS", imp))
+	}
+
+	validate := info.Validate
+	if strict {
+		validate = info.ValidateStrict
+	}
+	if err := validate(); err != nil {
+		logFatalNoContext(err)
+	}
+	green.Println("Module is a valid precompile.")
+
+	if !smoke {
+		return
+	}
+
+	input := common.FromHex(inputHex)
+	res, err := wasm.SmokeTest(code, input, gas)
+	if err != nil {
+		logFatalNoContext(err)
+	}
+	logInfo("ABI version: %d", res.AbiVersion)
+	logInfo("IsStatic: %t", res.IsStatic)
+	logInfo("Gas used: %d", res.GasUsed)
+	logInfo("Output: 0x%x", res.Output)
+	if res.Err != nil {
+		logFatalNoContext(fmt.Errorf("run failed: %w", res.Err))
+	}
+	green.Println("Smoke run succeeded.")
+}
+
+func runInit(cmd *cobra.Command, args []string) {
+	outPath := "."
+	if len(args) > 0 {
+		outPath = args[0]
+	}
+
+	var name, module, addressHex, pragma, replace string
+	if err := getStringFlags(cmd, &name, "name", &module, "module", &addressHex, "address", &pragma, "pragma", &replace, "replace"); err != nil {
+		logFatal(err)
+	}
+
+	absOutPath, err := filepath.Abs(outPath)
+	if err != nil {
+		logFatal(err)
+	}
+	if name == "" {
+		name = scaffold.NameFromDir(absOutPath)
+	}
+	if module == "" {
+		module = filepath.Base(absOutPath)
+	}
+	address, err := parseAddress(addressHex)
+	if err != nil {
+		logFatalNoContext(err)
+	}
+
+	config := scaffold.Config{
+		Name:    name,
+		Module:  module,
+		Address: address,
+		Pragma:  pragma,
+		Replace: replace,
+		OutDir:  outPath,
+	}
+
+	if v, err := cmd.Flags().GetBool("verbose"); err != nil {
+		logFatal(err)
+	} else if v {
+		logConfig(config)
+	}
+
+	if err := scaffold.GenerateProject(config); err != nil {
+		logFatalNoContext(err)
+	}
+
+	logInfo("Project generated successfully.")
+	logInfo("Files written to: %s", outPath)
+	logInfo("Run go mod tidy to fetch the dependencies, then make test to run the tests.")
+}
+
+// registryConfig is the format of the file passed to the test command with the
+// --registry flag. Relative paths are resolved from the dir of the file.
+type registryConfig struct {
+	Plugins     []string
+	Precompiles []precompileConfig
+}
+
+type precompileConfig struct {
+	Address string
+	Wasm    string
+	Block   uint64 // Block the precompile is active from
+}
+
+func readRegistryConfig(path string) (registryConfig, error) {
+	var config registryConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	if err := toml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid registry file %s: %w", path, err)
+	}
+	dir := filepath.Dir(path)
+	for ii, pluginPath := range config.Plugins {
+		if !filepath.IsAbs(pluginPath) {
+			config.Plugins[ii] = filepath.Join(dir, pluginPath)
+		}
+	}
+	for ii, pc := range config.Precompiles {
+		if !filepath.IsAbs(pc.Wasm) {
+			config.Precompiles[ii].Wasm = filepath.Join(dir, pc.Wasm)
+		}
+	}
+	return config, nil
+}
+
+// parsePrecompileFlag parses a precompile given as address=path.
+func parsePrecompileFlag(value string) (precompileConfig, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return precompileConfig{}, fmt.Errorf("invalid precompile %s: must follow format address=path", value)
+	}
+	return precompileConfig{Address: parts[0], Wasm: parts[1]}, nil
+}
+
+// loadRegistry returns a registry with the given WASM precompiles and the
+// precompiles of the given plugins.
+func loadRegistry(precompiles []precompileConfig, plugins []string) (*concrete.GenericPrecompileRegistry, error) {
+	registries := make([]*concrete.GenericPrecompileRegistry, 0, len(precompiles)+1)
+	for _, pc := range precompiles {
+		address, err := parseAddress(pc.Address)
+		if err != nil {
+			return nil, err
+		}
+		code, err := os.ReadFile(pc.Wasm)
+		if err != nil {
+			return nil, err
+		}
+		registry := concrete.NewRegistry()
+		registry.AddPrecompile(pc.Block, address, wasm.NewWazeroPrecompile(code))
+		registries = append(registries, registry)
+	}
+	if len(plugins) > 0 {
+		registry, err := plugin.Load(plugins...)
+		if err != nil {
+			return nil, err
+		}
+		registries = append(registries, registry)
+	}
+	return concrete.MergeRegistries(registries...)
+}
+
+func runTest(cmd *cobra.Command, args []string) {
+	var config testtool.TestConfig
+	var registryPath string
+	if err := getStringFlags(cmd,
+		&config.OutDir, "out",
+		&config.TestDir, "test-dir",
+		&config.Contract, "contract",
+		&registryPath, "registry",
+		&config.MatchTest, "match-test",
+		&config.MatchContract, "match-contract",
+		&config.GasSnapshot, "snapshot",
+		&config.JSONOutput, "json",
+		&config.JUnitOutput, "junit",
+		&config.Fork.Datadir, "fork-datadir",
+	); err != nil {
+		logFatal(err)
+	}
+
+	var err error
+	var verbosity int
+	if verbosity, err = cmd.Flags().GetInt("verbosity"); err != nil {
+		logFatal(err)
+	}
+	if config.Fuzz.Runs, err = cmd.Flags().GetInt("fuzz-runs"); err != nil {
+		logFatal(err)
+	}
+	if config.Fuzz.Seed, err = cmd.Flags().GetInt64("fuzz-seed"); err != nil {
+		logFatal(err)
+	}
+	if config.GasReport, err = cmd.Flags().GetBool("gas-report"); err != nil {
+		logFatal(err)
+	}
+	if config.GasTolerance, err = cmd.Flags().GetFloat64("snapshot-tolerance"); err != nil {
+		logFatal(err)
+	}
+	if config.Fork.Block, err = cmd.Flags().GetUint64("fork-block"); err != nil {
+		logFatal(err)
+	}
+	precompileFlags, err := cmd.Flags().GetStringArray("precompile")
+	if err != nil {
+		logFatal(err)
+	}
+
+	var outIsDir bool
+	if outIsDir, err = isDir(config.OutDir); err != nil {
+		logFatal(err)
+	}
+	if !outIsDir {
+		logFatalNoContext(fmt.Errorf("artifacts dir %s not found, run forge build first", config.OutDir))
+	}
+
+	var precompiles []precompileConfig
+	var plugins []string
+	if registryPath != "" {
+		registryConfig, err := readRegistryConfig(registryPath)
+		if err != nil {
+			logFatalNoContext(err)
+		}
+		precompiles = append(precompiles, registryConfig.Precompiles...)
+		plugins = registryConfig.Plugins
+	}
+	for _, value := range precompileFlags {
+		pc, err := parsePrecompileFlag(value)
+		if err != nil {
+			logFatalNoContext(err)
+		}
+		precompiles = append(precompiles, pc)
+	}
+	registry, err := loadRegistry(precompiles, plugins)
+	if err != nil {
+		logFatalNoContext(err)
+	}
+
+	if v, err := cmd.Flags().GetBool("verbose"); err != nil {
+		logFatal(err)
+	} else if v {
+		logConfig(config)
+	}
+
+	testtool.PrintLogs = verbosity > 1
+	results, err := testtool.Run(os.Stdout, registry, config, verbosity > 0)
+	if err != nil {
+		logFatalNoContext(err)
+	}
+	var failed int
+	for _, result := range results {
+		if !result.Passed {
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("FAIL: %d of %d tests failed\n", failed, len(results))
+		os.Exit(1)
+	}
+	fmt.Printf("ok: %d tests passed\n", len(results))
+}
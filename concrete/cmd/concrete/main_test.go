@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -44,6 +45,30 @@ func TestSolgen(t *testing.T) {
 	t.Log(stdout.String())
 }
 
+func TestBindgen(t *testing.T) {
+	tmpDir := "./tmp-bindgen"
+	os.Mkdir(tmpDir, 0755)
+	defer os.RemoveAll(tmpDir)
+
+	cmd := exec.Command(
+		"go", "run", ".", "bindgen",
+		"--abi", filepath.Join("..", "..", "codegen", "bindgen", "testdata", "Token.abi.json"),
+		"--out", tmpDir,
+		"--pkg", "token",
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Log(stderr.String())
+		t.Fatal(err)
+	}
+	t.Log(stdout.String())
+	if _, err := os.Stat(filepath.Join(tmpDir, "token.go")); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestDatamod(t *testing.T) {
 	tmpDir := "./tmp-datamod"
 	os.Mkdir(tmpDir, 0755)
@@ -69,3 +94,79 @@ func TestDatamod(t *testing.T) {
 	}
 	t.Log(stdout.String())
 }
+
+func TestInit(t *testing.T) {
+	tmpDir := "./tmp-init"
+	defer os.RemoveAll(tmpDir)
+	// Binaries built by go run have no version, so the replacement is required
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(
+		"go", "run", ".", "init", tmpDir,
+		"--name", "Counter",
+		"--module", "example.com/counter",
+		"--replace", root,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Log(stderr.String())
+		t.Fatal(err)
+	}
+	t.Log(stdout.String())
+	if _, err := os.Stat(filepath.Join(tmpDir, "src", "CounterPrecompile.sol")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTest(t *testing.T) {
+	tmpDir := "./tmp-test"
+	os.MkdirAll(filepath.Join(tmpDir, "test"), 0755)
+	os.MkdirAll(filepath.Join(tmpDir, "out", "Simple.t.sol"), 0755)
+	defer os.RemoveAll(tmpDir)
+
+	// The test contract stops on every call, so testA passes and testFailB fails
+	artifact := `{
+		"abi": [
+			{"type": "function", "name": "testA", "inputs": [], "outputs": []},
+			{"type": "function", "name": "testFailB", "inputs": [], "outputs": []}
+		],
+		"deployedBytecode": {"object": "0x00"},
+		"metadata": {"settings": {"compilationTarget": {"test/Simple.t.sol": "SimpleTest"}}}
+	}`
+	os.WriteFile(filepath.Join(tmpDir, "test", "Simple.t.sol"), nil, 0644)
+	os.WriteFile(filepath.Join(tmpDir, "out", "Simple.t.sol", "SimpleTest.json"), []byte(artifact), 0644)
+
+	run := func(args ...string) (string, error) {
+		args = append([]string{"run", ".", "test",
+			"--out", filepath.Join(tmpDir, "out"),
+			"--test-dir", filepath.Join(tmpDir, "test"),
+		}, args...)
+		cmd := exec.Command("go", args...)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		t.Log(stderr.String())
+		return stdout.String(), err
+	}
+
+	out, err := run()
+	if err == nil {
+		t.Fatal("expected the command to fail")
+	}
+	if !strings.Contains(out, "--- FAIL: SimpleTest/testFailB") {
+		t.Fatalf("expected testFailB to fail, got:\n%s", out)
+	}
+
+	out, err = run("--match-test", "^testA$", "--verbosity", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "--- PASS: SimpleTest/testA") || strings.Contains(out, "testFailB") {
+		t.Fatalf("expected only testA to run, got:\n%s", out)
+	}
+}
@@ -0,0 +1,712 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package bindgen
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/concrete/codegen/solgen"
+)
+
+//go:embed bindgen.tpl
+var bindgenTpl string
+
+type Config struct {
+	Name    string
+	Package string
+	AbiPath string
+	OutPath string
+}
+
+func isValidGoName(name string) bool {
+	re := regexp.MustCompile(`^[A-Z][a-zA-Z0-9_]*$`)
+	return re.MatchString(name)
+}
+
+func lowerFirst(name string) string {
+	if len(name) == 0 {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// reservedNames are the identifiers used by the generated methods, which
+// parameters are renamed to avoid.
+var reservedNames = map[string]bool{
+	"c": true, "value": true, "input": true, "ret": true, "elem": true, "err": true,
+	"abi": true, "big": true, "codec": true, "common": true, "api": true, "uint256": true,
+}
+
+// paramName returns the name of a method parameter, e.g. "_to" becomes "to".
+func paramName(name string, idx int) string {
+	name = lowerFirst(abi.ToCamelCase(name))
+	if name == "" {
+		return fmt.Sprintf("arg%d", idx)
+	}
+	if token.IsKeyword(name) || reservedNames[name] || regexp.MustCompile(`^out[0-9]+$`).MatchString(name) {
+		return name + "_"
+	}
+	return name
+}
+
+// fieldName returns the name of a struct field, e.g. "_to" becomes "To".
+func fieldName(name string, idx int) string {
+	if name = abi.ToCamelCase(name); name == "" {
+		return fmt.Sprintf("Arg%d", idx)
+	}
+	return name
+}
+
+// byteList returns a byte slice literal, e.g. []byte{0x01, 0x02}.
+func byteList(data []byte) string {
+	items := make([]string, len(data))
+	for i, b := range data {
+		items[i] = fmt.Sprintf("0x%02x", b)
+	}
+	return "[]byte{" + strings.Join(items, ", ") + "}"
+}
+
+type structField struct {
+	Name string
+	Type string
+}
+
+type structDef struct {
+	Name   string
+	Fields []structField
+}
+
+// generator keeps the declarations needed by the types of a contract ABI: the
+// structs of its tuples and the functions encoding and decoding every
+// composite type, prefixed with the name of the binding to avoid conflicts
+// with other bindings in the same package.
+type generator struct {
+	prefix       string
+	structs      []structDef
+	structFields map[string]string // Fields of each struct, to detect conflicts
+	helpers      []string
+	helperNames  map[string]bool
+	imports      map[string]bool
+}
+
+func newGenerator(name string) *generator {
+	return &generator{
+		prefix:       lowerFirst(name),
+		structFields: make(map[string]string),
+		helperNames:  make(map[string]bool),
+		imports:      make(map[string]bool),
+	}
+}
+
+func isDynamic(t abi.Type) bool {
+	switch t.T {
+	case abi.StringTy, abi.BytesTy, abi.SliceTy:
+		return true
+	case abi.ArrayTy:
+		return isDynamic(*t.Elem)
+	case abi.TupleTy:
+		for _, elem := range t.TupleElems {
+			if isDynamic(*elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// headSize returns the size of a type in the head of a tuple.
+func headSize(t abi.Type) int {
+	if isDynamic(t) {
+		return 32
+	}
+	switch t.T {
+	case abi.ArrayTy:
+		return t.Size * headSize(*t.Elem)
+	case abi.TupleTy:
+		size := 0
+		for _, elem := range t.TupleElems {
+			size += headSize(*elem)
+		}
+		return size
+	}
+	return 32
+}
+
+func isNativeInt(t abi.Type) bool {
+	return t.Size == 8 || t.Size == 16 || t.Size == 32 || t.Size == 64
+}
+
+// structName returns the name of the struct of a tuple type.
+func (g *generator) structName(t abi.Type) string {
+	if t.TupleRawName != "" {
+		return abi.ToCamelCase(t.TupleRawName)
+	}
+	return abi.ToCamelCase(g.prefix) + "Tuple" + strings.Join(strings.FieldsFunc(t.String(), func(r rune) bool {
+		return !('a' <= r && r <= 'z' || '0' <= r && r <= '9')
+	}), "")
+}
+
+// typeID returns a name for a type to use in the name of its helpers.
+func (g *generator) typeID(t abi.Type) string {
+	switch t.T {
+	case abi.SliceTy:
+		return g.typeID(*t.Elem) + "Slice"
+	case abi.ArrayTy:
+		return fmt.Sprintf("%sArray%d", g.typeID(*t.Elem), t.Size)
+	case abi.TupleTy:
+		return g.structName(t)
+	}
+	return abi.ToCamelCase(t.String())
+}
+
+// goType returns the Go type of an ABI type.
+func (g *generator) goType(t abi.Type) (string, error) {
+	switch t.T {
+	case abi.UintTy, abi.IntTy:
+		if !isNativeInt(t) {
+			g.imports["math/big"] = true
+			return "*big.Int", nil
+		}
+		if t.T == abi.UintTy {
+			return fmt.Sprintf("uint%d", t.Size), nil
+		}
+		return fmt.Sprintf("int%d", t.Size), nil
+	case abi.BoolTy:
+		return "bool", nil
+	case abi.StringTy:
+		return "string", nil
+	case abi.BytesTy:
+		return "[]byte", nil
+	case abi.AddressTy:
+		return "common.Address", nil
+	case abi.FixedBytesTy:
+		return fmt.Sprintf("[%d]byte", t.Size), nil
+	case abi.FunctionTy:
+		return "[24]byte", nil
+	case abi.SliceTy, abi.ArrayTy:
+		elem, err := g.goType(*t.Elem)
+		if err != nil {
+			return "", err
+		}
+		if t.T == abi.SliceTy {
+			return "[]" + elem, nil
+		}
+		return fmt.Sprintf("[%d]%s", t.Size, elem), nil
+	case abi.TupleTy:
+		return g.addStruct(t)
+	}
+	return "", fmt.Errorf("unsupported type: %s", t.String())
+}
+
+func (g *generator) addStruct(t abi.Type) (string, error) {
+	name := g.structName(t)
+	fields := make([]structField, len(t.TupleElems))
+	for i, elem := range t.TupleElems {
+		typ, err := g.goType(*elem)
+		if err != nil {
+			return "", err
+		}
+		fields[i] = structField{Name: fieldName(t.TupleRawNames[i], i), Type: typ}
+	}
+	key := fmt.Sprint(fields)
+	if prev, ok := g.structFields[name]; ok {
+		if prev != key {
+			return "", fmt.Errorf("conflicting definitions of struct %s", name)
+		}
+		return name, nil
+	}
+	g.structFields[name] = key
+	g.structs = append(g.structs, structDef{Name: name, Fields: fields})
+	return name, nil
+}
+
+// part returns an expression building the codec.Part of a value.
+func (g *generator) part(t abi.Type, value string) (string, error) {
+	expr, err := g.encode(t, value)
+	if err != nil {
+		return "", err
+	}
+	if isDynamic(t) {
+		return fmt.Sprintf("codec.Part{Data: %s, Dynamic: true}", expr), nil
+	}
+	return fmt.Sprintf("codec.Part{Data: %s}", expr), nil
+}
+
+// encode returns an expression encoding a value of the given type.
+func (g *generator) encode(t abi.Type, value string) (string, error) {
+	switch t.T {
+	case abi.UintTy:
+		if isNativeInt(t) {
+			return fmt.Sprintf("codec.EncodeUint(uint64(%s))", value), nil
+		}
+		return fmt.Sprintf("codec.EncodeBig(%s)", value), nil
+	case abi.IntTy:
+		if isNativeInt(t) {
+			return fmt.Sprintf("codec.EncodeInt(int64(%s))", value), nil
+		}
+		return fmt.Sprintf("codec.EncodeBig(%s)", value), nil
+	case abi.BoolTy:
+		return fmt.Sprintf("codec.EncodeBool(%s)", value), nil
+	case abi.StringTy:
+		return fmt.Sprintf("codec.EncodeString(%s)", value), nil
+	case abi.BytesTy:
+		return fmt.Sprintf("codec.EncodeBytes(%s)", value), nil
+	case abi.AddressTy:
+		return fmt.Sprintf("codec.EncodeAddress(%s)", value), nil
+	case abi.FixedBytesTy, abi.FunctionTy:
+		return fmt.Sprintf("codec.EncodeFixedBytes(%s[:])", value), nil
+	}
+
+	goType, err := g.goType(t)
+	if err != nil {
+		return "", err
+	}
+	name := g.prefix + "Encode" + g.typeID(t)
+	if g.helperNames[name] {
+		return fmt.Sprintf("%s(%s)", name, value), nil
+	}
+	g.helperNames[name] = true
+
+	var body string
+	switch t.T {
+	case abi.SliceTy, abi.ArrayTy:
+		part, err := g.part(*t.Elem, "v[i]")
+		if err != nil {
+			return "", err
+		}
+		encodeFn := "codec.EncodeTuple"
+		if t.T == abi.SliceTy {
+			encodeFn = "codec.EncodeArray"
+		}
+		body = fmt.Sprintf("parts := make([]codec.Part, len(v))\nfor i := range v {\nparts[i] = %s\n}\nreturn %s(parts...)", part, encodeFn)
+	case abi.TupleTy:
+		parts := make([]string, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			if parts[i], err = g.part(*elem, "v."+fieldName(t.TupleRawNames[i], i)); err != nil {
+				return "", err
+			}
+		}
+		body = fmt.Sprintf("return codec.EncodeTuple(\n%s,\n)", strings.Join(parts, ",\n"))
+	default:
+		return "", fmt.Errorf("unsupported type: %s", t.String())
+	}
+	g.helpers = append(g.helpers, fmt.Sprintf("func %s(v %s) []byte {\n%s\n}", name, goType, body))
+	return fmt.Sprintf("%s(%s)", name, value), nil
+}
+
+// decoder returns the name of a function decoding a value of the given type.
+func (g *generator) decoder(t abi.Type) (string, error) {
+	switch t.T {
+	case abi.BoolTy:
+		return "codec.DecodeBool", nil
+	case abi.StringTy:
+		return "codec.DecodeString", nil
+	case abi.BytesTy:
+		return "codec.DecodeBytes", nil
+	case abi.AddressTy:
+		return "codec.DecodeAddress", nil
+	}
+
+	goType, err := g.goType(t)
+	if err != nil {
+		return "", err
+	}
+	name := g.prefix + "Decode" + g.typeID(t)
+	if g.helperNames[name] {
+		return name, nil
+	}
+	g.helperNames[name] = true
+
+	var body string
+	switch t.T {
+	case abi.UintTy, abi.IntTy:
+		kind := "Uint"
+		if t.T == abi.IntTy {
+			kind = "Int"
+		}
+		if isNativeInt(t) {
+			body = fmt.Sprintf("v, err := codec.Decode%s(data, %d)\nreturn %s(v), err", kind, t.Size, goType)
+		} else {
+			body = fmt.Sprintf("return codec.DecodeBig%s(data, %d)", kind, t.Size)
+		}
+	case abi.FixedBytesTy, abi.FunctionTy:
+		body = fmt.Sprintf("var v %s\nerr := codec.DecodeFixedBytes(data, v[:])\nreturn v, err", goType)
+	case abi.SliceTy, abi.ArrayTy:
+		decodeElem, err := g.decoder(*t.Elem)
+		if err != nil {
+			return "", err
+		}
+		var head string
+		if t.T == abi.SliceTy {
+			head = fmt.Sprintf("length, elems, err := codec.DecodeLength(data)\nif err != nil {\nreturn nil, err\n}\nv := make(%s, length)\n", goType)
+		} else {
+			head = fmt.Sprintf("var v %s\nelems := data\n", goType)
+		}
+		body = head + fmt.Sprintf("for i := range v {\nelem, err := codec.Field(elems, i*%d, %t)\nif err != nil {\nreturn v, err\n}\nif v[i], err = %s(elem); err != nil {\nreturn v, err\n}\n}\nreturn v, nil",
+			headSize(*t.Elem), isDynamic(*t.Elem), decodeElem)
+	case abi.TupleTy:
+		targets := make([]string, len(t.TupleElems))
+		types := make([]abi.Type, len(t.TupleElems))
+		for i, elem := range t.TupleElems {
+			targets[i] = "v." + fieldName(t.TupleRawNames[i], i)
+			types[i] = *elem
+		}
+		stmts, err := g.decodeTuple("data", targets, types, "v, err")
+		if err != nil {
+			return "", err
+		}
+		body = fmt.Sprintf("var (\nv %s\nelem []byte\nerr error\n)\n%sreturn v, nil", goType, stmts)
+	default:
+		return "", fmt.Errorf("unsupported type: %s", t.String())
+	}
+	g.helpers = append(g.helpers, fmt.Sprintf("func %s(data []byte) (%s, error) {\n%s\n}", name, goType, body))
+	return name, nil
+}
+
+// decodeTuple returns the statements decoding the elements of a tuple into
+// the given targets, which expect variables elem and err to be declared.
+func (g *generator) decodeTuple(data string, targets []string, types []abi.Type, ret string) (string, error) {
+	var buf strings.Builder
+	pos := 0
+	for i, t := range types {
+		decode, err := g.decoder(t)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&buf, "if elem, err = codec.Field(%s, %d, %t); err != nil {\nreturn %s\n}\n", data, pos, isDynamic(t), ret)
+		fmt.Fprintf(&buf, "if %s, err = %s(elem); err != nil {\nreturn %s\n}\n", targets[i], decode, ret)
+		pos += headSize(t)
+	}
+	return buf.String(), nil
+}
+
+// varBlock returns a var declaration of the given variables.
+func varBlock(vars []string) string {
+	if len(vars) == 0 {
+		return ""
+	}
+	return "var (\n" + strings.Join(vars, "\n") + "\n)\n"
+}
+
+type methodDef struct {
+	Name    string
+	Sig     string
+	Params  string
+	Results string
+	Body    string
+}
+
+type eventDef struct {
+	Name      string
+	Method    string
+	Sig       string
+	Topic     string
+	Anonymous bool
+	Fields    []structField
+	Body      string
+}
+
+type errorDef struct {
+	Name     string
+	RawName  string
+	Sig      string
+	Selector string
+	Fields   []structField
+	Body     string
+}
+
+func (g *generator) method(method abi.Method) (methodDef, error) {
+	def := methodDef{
+		Name: abi.ToCamelCase(method.Name),
+		Sig:  method.Sig,
+	}
+	params := []string{}
+	if method.IsPayable() {
+		g.imports["github.com/holiman/uint256"] = true
+		params = append(params, "value *uint256.Int")
+	}
+	parts := []string{byteList(method.ID)}
+	for i, input := range method.Inputs {
+		typ, err := g.goType(input.Type)
+		if err != nil {
+			return def, err
+		}
+		name := paramName(input.Name, i)
+		params = append(params, name+" "+typ)
+		part, err := g.part(input.Type, name)
+		if err != nil {
+			return def, err
+		}
+		parts = append(parts, part)
+	}
+	def.Params = strings.Join(params, ", ")
+
+	vars := []string{}
+	results := []string{}
+	outputs := []string{}
+	types := make([]abi.Type, len(method.Outputs))
+	for i, output := range method.Outputs {
+		typ, err := g.goType(output.Type)
+		if err != nil {
+			return def, err
+		}
+		name := fmt.Sprintf("out%d", i)
+		vars = append(vars, name+" "+typ)
+		results = append(results, typ)
+		outputs = append(outputs, name)
+		types[i] = output.Type
+	}
+	if len(outputs) > 0 {
+		vars = append(vars, "elem []byte")
+	}
+	def.Results = strings.Join(append(results, "error"), ", ")
+	if len(results) > 0 {
+		def.Results = "(" + def.Results + ")"
+	}
+
+	var call string
+	switch {
+	case method.IsConstant():
+		call = "c.env.CallStatic(c.address, input, c.gas)"
+	case method.IsPayable():
+		call = "c.env.Call(c.address, input, c.gas, value)"
+	default:
+		g.imports["github.com/holiman/uint256"] = true
+		call = "c.env.Call(c.address, input, c.gas, new(uint256.Int))"
+	}
+	ret := strings.Join(append(append([]string{}, outputs...), "err"), ", ")
+	decode, err := g.decodeTuple("ret", outputs, types, ret)
+	if err != nil {
+		return def, err
+	}
+	def.Body = varBlock(vars) +
+		fmt.Sprintf("input := codec.EncodeWithSelector(\n%s,\n)\n", strings.Join(parts, ",\n")) +
+		fmt.Sprintf("ret, err := %s\nif err != nil {\nreturn %s\n}\n", call, strings.Join(append(append([]string{}, outputs...), "c.decodeError(ret, err)"), ", ")) +
+		decode +
+		fmt.Sprintf("return %s", strings.Join(append(outputs, "nil"), ", "))
+	return def, nil
+}
+
+func (g *generator) event(name string, event abi.Event) (eventDef, error) {
+	def := eventDef{
+		Name:      name + abi.ToCamelCase(event.Name),
+		Method:    "Unpack" + abi.ToCamelCase(event.Name) + "Event",
+		Sig:       event.Sig,
+		Topic:     event.ID.Hex(),
+		Anonymous: event.Anonymous,
+	}
+	var (
+		buf     strings.Builder
+		topic   int
+		targets []string
+		types   []abi.Type
+		hasErr  bool
+	)
+	if !event.Anonymous {
+		topic = 1
+	}
+	for i, input := range event.Inputs {
+		field := structField{Name: fieldName(input.Name, i)}
+		if input.Indexed && isDynamic(input.Type) {
+			// Only the hash of indexed dynamic values is logged
+			field.Type = "common.Hash"
+			fmt.Fprintf(&buf, "v.%s = topics[%d]\n", field.Name, topic)
+			topic++
+		} else {
+			typ, err := g.goType(input.Type)
+			if err != nil {
+				return def, err
+			}
+			field.Type = typ
+			hasErr = true
+			if input.Indexed {
+				decode, err := g.decoder(input.Type)
+				if err != nil {
+					return def, err
+				}
+				fmt.Fprintf(&buf, "if v.%s, err = %s(topics[%d][:]); err != nil {\nreturn nil, err\n}\n", field.Name, decode, topic)
+				topic++
+			} else {
+				targets = append(targets, "v."+field.Name)
+				types = append(types, input.Type)
+			}
+		}
+		def.Fields = append(def.Fields, field)
+	}
+	decode, err := g.decodeTuple("data", targets, types, "nil, err")
+	if err != nil {
+		return def, err
+	}
+
+	check := fmt.Sprintf("len(topics) != %d", topic)
+	if !event.Anonymous {
+		check += " || topics[0] != " + def.Name + "Topic"
+	}
+	vars := []string{"v = new(" + def.Name + ")"}
+	if len(targets) > 0 {
+		vars = append(vars, "elem []byte")
+	}
+	if hasErr {
+		vars = append(vars, "err error")
+	}
+	def.Body = fmt.Sprintf("if %s {\nreturn nil, codec.ErrInvalidEvent\n}\n", check) +
+		varBlock(vars) + buf.String() + decode + "return v, nil"
+	return def, nil
+}
+
+func (g *generator) error(name string, abiError abi.Error) (errorDef, error) {
+	def := errorDef{
+		Name:     name + abi.ToCamelCase(abiError.Name),
+		RawName:  abiError.Name,
+		Sig:      abiError.Sig,
+		Selector: byteList(abiError.ID[:4]),
+	}
+	targets := make([]string, len(abiError.Inputs))
+	types := make([]abi.Type, len(abiError.Inputs))
+	for i, input := range abiError.Inputs {
+		typ, err := g.goType(input.Type)
+		if err != nil {
+			return def, err
+		}
+		field := structField{Name: fieldName(input.Name, i), Type: typ}
+		if field.Name == "Error" {
+			// Conflicts with the method of the error interface
+			field.Name += "_"
+		}
+		def.Fields = append(def.Fields, field)
+		targets[i] = "v." + field.Name
+		types[i] = input.Type
+	}
+	decode, err := g.decodeTuple("data", targets, types, "nil, err")
+	if err != nil {
+		return def, err
+	}
+	vars := []string{"v = new(" + def.Name + ")"}
+	if len(targets) > 0 {
+		vars = append(vars, "elem []byte", "err error")
+	}
+	def.Body = varBlock(vars) + decode + "return v, nil"
+	return def, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func generateBindings(ABI abi.ABI, config Config) (string, error) {
+	if !isValidGoName(config.Name) {
+		return "", fmt.Errorf("invalid binding name: '%s'", config.Name)
+	}
+	if !token.IsIdentifier(config.Package) {
+		return "", fmt.Errorf("invalid package name: '%s'", config.Package)
+	}
+
+	tmpl, err := template.New("bindgen").Parse(bindgenTpl)
+	if err != nil {
+		return "", err
+	}
+
+	g := newGenerator(config.Name)
+	methods := []methodDef{}
+	events := []eventDef{}
+	errs := []errorDef{}
+
+	for _, name := range sortedKeys(ABI.Methods) {
+		method, err := g.method(ABI.Methods[name])
+		if err != nil {
+			return "", fmt.Errorf("method %s: %w", name, err)
+		}
+		methods = append(methods, method)
+	}
+	for _, name := range sortedKeys(ABI.Events) {
+		event, err := g.event(config.Name, ABI.Events[name])
+		if err != nil {
+			return "", fmt.Errorf("event %s: %w", name, err)
+		}
+		events = append(events, event)
+	}
+	for _, name := range sortedKeys(ABI.Errors) {
+		abiError, err := g.error(config.Name, ABI.Errors[name])
+		if err != nil {
+			return "", fmt.Errorf("error %s: %w", name, err)
+		}
+		errs = append(errs, abiError)
+	}
+
+	g.imports["math"] = true
+	g.imports["github.com/ethereum/go-ethereum/common"] = true
+	g.imports["github.com/ethereum/go-ethereum/concrete/api"] = true
+	g.imports["github.com/ethereum/go-ethereum/concrete/codegen/bindgen/codec"] = true
+	imports := [][]string{{}, {}} // Standard library and other imports
+	for _, path := range sortedKeys(g.imports) {
+		if strings.Contains(path, ".") {
+			imports[1] = append(imports[1], path)
+		} else {
+			imports[0] = append(imports[0], path)
+		}
+	}
+
+	data := map[string]interface{}{
+		"Name":    config.Name,
+		"Package": config.Package,
+		"Imports": imports,
+		"Structs": g.structs,
+		"Methods": methods,
+		"Events":  events,
+		"Errors":  errs,
+		"Helpers": g.helpers,
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	code, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return string(code), nil
+}
+
+// GenerateBindings generates Go bindings to call the contract with the given
+// ABI from a precompile. The bindings only depend on the concrete API and the
+// bindgen codec, so they can be compiled with tinygo.
+func GenerateBindings(config Config) error {
+	ABI, _, err := solgen.GetABI(config.AbiPath)
+	if err != nil {
+		return err
+	}
+	code, err := generateBindings(ABI, config)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(config.OutPath, []byte(code), 0644)
+}
@@ -0,0 +1,313 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package bindgen
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/codegen/bindgen/codec"
+	"github.com/ethereum/go-ethereum/concrete/codegen/bindgen/testdata"
+	"github.com/ethereum/go-ethereum/concrete/codegen/solgen"
+	"github.com/holiman/uint256"
+)
+
+var updateFlag = flag.Bool("update", false, "Overwrite the generated bindings in testdata/")
+
+func TestParamName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"", "arg3"},
+		{"owner", "owner"},
+		{"_to", "to"},
+		{"new_owner", "newOwner"},
+		{"Amount", "amount"},
+		{"type", "type_"},
+		{"value", "value_"},
+		{"out0", "out0_"},
+	}
+	for _, testCase := range testCases {
+		if name := paramName(testCase.name, 3); name != testCase.expected {
+			t.Errorf("unexpected name for %q: %q", testCase.name, name)
+		}
+	}
+}
+
+func TestInvalidConfig(t *testing.T) {
+	for _, config := range []Config{
+		{Name: "token", Package: "testdata"},
+		{Name: "Token", Package: "test-data"},
+	} {
+		if _, err := generateBindings(abi.ABI{}, config); err == nil {
+			t.Errorf("expected error for config %+v", config)
+		}
+	}
+}
+
+// TestBindgen checks that the bindings in testdata are up to date.
+func TestBindgen(t *testing.T) {
+	outPath := filepath.Join(t.TempDir(), "token.go")
+	config := Config{
+		Name:    "Token",
+		Package: "testdata",
+		AbiPath: filepath.Join("testdata", "Token.abi.json"),
+		OutPath: outPath,
+	}
+	if err := GenerateBindings(config); err != nil {
+		t.Fatal(err)
+	}
+	have, err := os.ReadFile(outPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	goldenPath := filepath.Join("testdata", "token.go")
+	if *updateFlag {
+		if err := os.WriteFile(goldenPath, have, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, want) {
+		t.Fatalf("generated bindings differ from %s, run the test with -update to regenerate them", goldenPath)
+	}
+}
+
+// tokenContract implements the test ABI with the ABI package, to check the
+// generated bindings against it.
+type tokenContract struct {
+	t   *testing.T
+	abi abi.ABI
+}
+
+func (c *tokenContract) call(input []byte, value *uint256.Int) ([]byte, error) {
+	method, err := c.abi.MethodById(input[:4])
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		c.t.Fatalf("failed to unpack %s input: %v", method.Name, err)
+	}
+	var outputs []interface{}
+	switch method.Sig {
+	case "balanceOf(address)":
+		if args[0].(common.Address) != (common.Address{0x01}) {
+			return c.revert("InsufficientBalance", big.NewInt(1), big.NewInt(2))
+		}
+		outputs = []interface{}{big.NewInt(1000)}
+	case "transfer(address,uint256)":
+		if args[1].(*big.Int).Sign() == 0 {
+			return c.revertReason("zero amount")
+		}
+		outputs = []interface{}{true}
+	case "transfer((address,(uint256,uint8)))":
+		request := args[0].(struct {
+			To     common.Address `json:"to"`
+			Amount struct {
+				Value    *big.Int `json:"value"`
+				Decimals uint8    `json:"decimals"`
+			} `json:"amount"`
+		})
+		outputs = []interface{}{request.To == common.Address{0x02} && request.Amount.Value.Int64() == 5 && request.Amount.Decimals == 18}
+	case "balances(address[])":
+		type amount struct {
+			Value    *big.Int
+			Decimals uint8
+		}
+		owners := args[0].([]common.Address)
+		amounts := make([]amount, len(owners))
+		for i := range owners {
+			amounts[i] = amount{big.NewInt(int64(i)), uint8(i)}
+		}
+		outputs = []interface{}{amounts}
+	case "deposit()":
+		if value.Uint64() != 7 {
+			c.t.Fatalf("unexpected deposit value: %v", value)
+		}
+	case "metadata()":
+		outputs = []interface{}{"Token", "TKN", uint8(18), [32]byte{0xff}}
+	case "annotate(string[],int64,int128,bytes,uint24[3])":
+		tags := args[0].([]string)
+		levels := args[4].([3]*big.Int)
+		values := []*big.Int{big.NewInt(args[1].(int64)), args[2].(*big.Int)}
+		for _, level := range levels {
+			values = append(values, level)
+		}
+		note := struct {
+			Label  string
+			Values []*big.Int
+		}{strings.Join(tags, ",") + ":" + string(args[3].([]byte)), values}
+		outputs = []interface{}{note}
+	default:
+		c.t.Fatalf("unexpected method: %s", method.Sig)
+	}
+	return method.Outputs.Pack(outputs...)
+}
+
+func (c *tokenContract) revert(name string, args ...interface{}) ([]byte, error) {
+	abiError := c.abi.Errors[name]
+	data, err := abiError.Inputs.Pack(args...)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return append(abiError.ID[:4], data...), errors.New("execution reverted")
+}
+
+func (c *tokenContract) revertReason(reason string) ([]byte, error) {
+	typ, _ := abi.NewType("string", "", nil)
+	data, err := abi.Arguments{{Type: typ}}.Pack(reason)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return append(codec.ErrorSelector, data...), errors.New("execution reverted")
+}
+
+func newTestToken(t *testing.T) (*testdata.Token, abi.ABI) {
+	ABI, _, err := solgen.GetABI(filepath.Join("testdata", "Token.abi.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := common.Address{0xaa}
+	contract := &tokenContract{t: t, abi: ABI}
+	caller := api.NewMockCaller()
+	caller.SetCallStaticFn(func(addr common.Address, input []byte, gas uint64) ([]byte, uint64, error) {
+		if addr != address {
+			t.Fatalf("unexpected address: %v", addr)
+		}
+		ret, err := contract.call(input, nil)
+		return ret, 0, err
+	})
+	caller.SetCallFn(func(addr common.Address, input []byte, gas uint64, value *uint256.Int) ([]byte, uint64, error) {
+		if addr != address {
+			t.Fatalf("unexpected address: %v", addr)
+		}
+		ret, err := contract.call(input, value)
+		return ret, 0, err
+	})
+	env, _, _, _ := api.NewMockEnvironment(api.WithCaller(caller))
+	return testdata.NewToken(env, address), ABI
+}
+
+func TestBindingMethods(t *testing.T) {
+	token, _ := newTestToken(t)
+
+	if balance, err := token.BalanceOf(common.Address{0x01}); err != nil || balance.Int64() != 1000 {
+		t.Errorf("BalanceOf: %v %v", balance, err)
+	}
+	if ok, err := token.Transfer(common.Address{0x02}, big.NewInt(5)); err != nil || !ok {
+		t.Errorf("Transfer: %v %v", ok, err)
+	}
+	request := testdata.TokenTransfer{To: common.Address{0x02}, Amount: testdata.TokenAmount{Value: big.NewInt(5), Decimals: 18}}
+	if ok, err := token.Transfer0(request); err != nil || !ok {
+		t.Errorf("Transfer0: %v %v", ok, err)
+	}
+	amounts, err := token.Balances([]common.Address{{0x01}, {0x02}, {0x03}})
+	if err != nil || len(amounts) != 3 {
+		t.Fatalf("Balances: %v %v", amounts, err)
+	}
+	for i, amount := range amounts {
+		if amount.Value.Int64() != int64(i) || amount.Decimals != uint8(i) {
+			t.Errorf("Balances[%d]: %+v", i, amount)
+		}
+	}
+	if err := token.Deposit(uint256.NewInt(7)); err != nil {
+		t.Errorf("Deposit: %v", err)
+	}
+	name, symbol, decimals, id, err := token.Metadata()
+	if err != nil || name != "Token" || symbol != "TKN" || decimals != 18 || id != [32]byte{0xff} {
+		t.Errorf("Metadata: %v %v %v %x %v", name, symbol, decimals, id, err)
+	}
+	note, err := token.Annotate([]string{"a", "b"}, -3, big.NewInt(-4), []byte("data"), [3]*big.Int{big.NewInt(1), big.NewInt(2), big.NewInt(3)})
+	if err != nil {
+		t.Fatalf("Annotate: %v", err)
+	}
+	if note.Label != "a,b:data" || len(note.Values) != 5 || note.Values[0].Int64() != -3 || note.Values[1].Int64() != -4 || note.Values[4].Int64() != 3 {
+		t.Errorf("Annotate: %+v", note)
+	}
+}
+
+func TestBindingErrors(t *testing.T) {
+	token, _ := newTestToken(t)
+
+	_, err := token.BalanceOf(common.Address{0x02})
+	var balanceErr *testdata.TokenInsufficientBalance
+	if !errors.As(err, &balanceErr) {
+		t.Fatalf("expected InsufficientBalance error, got %v", err)
+	}
+	if balanceErr.Available.Int64() != 1 || balanceErr.Required.Int64() != 2 {
+		t.Errorf("unexpected error fields: %+v", balanceErr)
+	}
+
+	_, err = token.Transfer(common.Address{0x02}, big.NewInt(0))
+	var revertErr *codec.RevertError
+	if !errors.As(err, &revertErr) || revertErr.Reason != "zero amount" {
+		t.Errorf("expected revert reason, got %v", err)
+	}
+}
+
+func TestBindingEvents(t *testing.T) {
+	token, ABI := newTestToken(t)
+
+	from, to := common.Address{0x01}, common.Address{0x02}
+	event := ABI.Events["Transferred"]
+	data, err := event.Inputs.NonIndexed().Pack(big.NewInt(42))
+	if err != nil {
+		t.Fatal(err)
+	}
+	topics := []common.Hash{event.ID, common.BytesToHash(from.Bytes()), common.BytesToHash(to.Bytes())}
+	transferred, err := token.UnpackTransferredEvent(topics, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if transferred.From != from || transferred.To != to || transferred.Amount.Int64() != 42 {
+		t.Errorf("unexpected event: %+v", transferred)
+	}
+	if _, err := token.UnpackTransferredEvent(topics[:2], data); err != codec.ErrInvalidEvent {
+		t.Errorf("expected invalid event error, got %v", err)
+	}
+
+	event = ABI.Events["Annotated"]
+	if data, err = event.Inputs.NonIndexed().Pack([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	tagHash := common.Hash{0x01}
+	annotated, err := token.UnpackAnnotatedEvent([]common.Hash{event.ID, tagHash}, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if annotated.Tag != tagHash || string(annotated.Data) != "data" {
+		t.Errorf("unexpected event: %+v", annotated)
+	}
+
+	swept, err := token.UnpackSweptEvent([]common.Hash{common.BigToHash(big.NewInt(9))}, nil)
+	if err != nil || swept.Amount.Int64() != 9 {
+		t.Errorf("unexpected event: %+v %v", swept, err)
+	}
+}
@@ -0,0 +1,195 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !tinygo
+
+package codec
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func mustArguments(t *testing.T, types ...string) abi.Arguments {
+	args := abi.Arguments{}
+	for _, typeStr := range types {
+		typ, err := abi.NewType(typeStr, "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		args = append(args, abi.Argument{Type: typ})
+	}
+	return args
+}
+
+func TestEncodeTuple(t *testing.T) {
+	var (
+		addr   = common.HexToAddress("0xc0ffee")
+		amount = new(big.Int).Lsh(big.NewInt(1), 200)
+		neg    = big.NewInt(-12345)
+		b4     = [4]byte{1, 2, 3, 4}
+		data   = []byte("some bytes that take more than one word to encode")
+		str    = "hello"
+		nums   = []uint64{1, 2, 3}
+	)
+	numParts := make([]Part, len(nums))
+	for i, n := range nums {
+		numParts[i] = Part{Data: EncodeUint(n)}
+	}
+	strParts := []Part{{Data: EncodeString("a"), Dynamic: true}, {Data: EncodeString("bc"), Dynamic: true}}
+
+	have := EncodeTuple(
+		Part{Data: EncodeAddress(addr)},
+		Part{Data: EncodeBig(amount)},
+		Part{Data: EncodeBig(neg)},
+		Part{Data: EncodeInt(-7)},
+		Part{Data: EncodeBool(true)},
+		Part{Data: EncodeFixedBytes(b4[:])},
+		Part{Data: EncodeBytes(data), Dynamic: true},
+		Part{Data: EncodeString(str), Dynamic: true},
+		Part{Data: EncodeArray(numParts...), Dynamic: true},
+		Part{Data: EncodeTuple(strParts...), Dynamic: true},
+	)
+
+	args := mustArguments(t, "address", "uint256", "int256", "int8", "bool", "bytes4", "bytes", "string", "uint64[]", "string[2]")
+	want, err := args.Pack(addr, amount, neg, int8(-7), true, b4, data, str, nums, [2]string{"a", "bc"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(have, want) {
+		t.Fatalf("encoding mismatch\nhave %x\nwant %x", have, want)
+	}
+
+	// Decode the encoding back
+	field := func(pos int, dynamic bool) []byte {
+		elem, err := Field(have, pos, dynamic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return elem
+	}
+	if v, err := DecodeAddress(field(0, false)); err != nil || v != addr {
+		t.Errorf("address: %v %v", v, err)
+	}
+	if v, err := DecodeBigUint(field(32, false), 256); err != nil || v.Cmp(amount) != 0 {
+		t.Errorf("uint256: %v %v", v, err)
+	}
+	if v, err := DecodeBigInt(field(64, false), 256); err != nil || v.Cmp(neg) != 0 {
+		t.Errorf("int256: %v %v", v, err)
+	}
+	if v, err := DecodeInt(field(96, false), 8); err != nil || v != -7 {
+		t.Errorf("int8: %v %v", v, err)
+	}
+	if v, err := DecodeBool(field(128, false)); err != nil || !v {
+		t.Errorf("bool: %v %v", v, err)
+	}
+	var v4 [4]byte
+	if err := DecodeFixedBytes(field(160, false), v4[:]); err != nil || v4 != b4 {
+		t.Errorf("bytes4: %v %v", v4, err)
+	}
+	if v, err := DecodeBytes(field(192, true)); err != nil || !bytes.Equal(v, data) {
+		t.Errorf("bytes: %x %v", v, err)
+	}
+	if v, err := DecodeString(field(224, true)); err != nil || v != str {
+		t.Errorf("string: %v %v", v, err)
+	}
+	n, elems, err := DecodeLength(field(256, true))
+	if err != nil || n != len(nums) {
+		t.Fatalf("uint64[] length: %v %v", n, err)
+	}
+	for i := 0; i < n; i++ {
+		elem, err := Field(elems, 32*i, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := DecodeUint(elem, 64); err != nil || v != nums[i] {
+			t.Errorf("uint64[%d]: %v %v", i, v, err)
+		}
+	}
+	strs := field(288, true)
+	for i, want := range []string{"a", "bc"} {
+		elem, err := Field(strs, 32*i, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v, err := DecodeString(elem); err != nil || v != want {
+			t.Errorf("string[2][%d]: %v %v", i, v, err)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	word := func(v uint64) []byte { return EncodeUint(v) }
+	max := EncodeBig(new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)))
+
+	if _, err := DecodeUint(word(256), 8); err != ErrOverflow {
+		t.Errorf("uint8 overflow: %v", err)
+	}
+	if _, err := DecodeUint(max, 64); err != ErrOverflow {
+		t.Errorf("uint64 overflow: %v", err)
+	}
+	if _, err := DecodeInt(EncodeInt(-129), 8); err != ErrOverflow {
+		t.Errorf("int8 underflow: %v", err)
+	}
+	if _, err := DecodeInt(word(128), 8); err != ErrOverflow {
+		t.Errorf("int8 overflow: %v", err)
+	}
+	if _, err := DecodeBigUint(max, 128); err != ErrOverflow {
+		t.Errorf("uint128 overflow: %v", err)
+	}
+	if _, err := DecodeBool(word(2)); err != ErrInvalidBool {
+		t.Errorf("bool: %v", err)
+	}
+	if _, err := DecodeAddress(word(1)[:31]); err != ErrShortData {
+		t.Errorf("short address: %v", err)
+	}
+	if _, err := Field(word(64), 0, true); err != ErrInvalidOffset {
+		t.Errorf("offset out of bounds: %v", err)
+	}
+	if _, err := Field(max, 0, true); err != ErrInvalidOffset {
+		t.Errorf("offset overflow: %v", err)
+	}
+	if _, _, err := DecodeLength(append(word(2), word(1)...)); err != ErrInvalidLength {
+		t.Errorf("array length: %v", err)
+	}
+	if _, err := DecodeBytes(append(word(33), word(1)...)); err != ErrShortData {
+		t.Errorf("bytes length: %v", err)
+	}
+}
+
+func TestDecodeRevert(t *testing.T) {
+	reason, err := mustArguments(t, "string").Pack("not enough")
+	if err != nil {
+		t.Fatal(err)
+	}
+	revertErr, ok := DecodeRevert(append(ErrorSelector, reason...)).(*RevertError)
+	if !ok || revertErr.Reason != "not enough" {
+		t.Errorf("unexpected revert error: %v", revertErr)
+	}
+	panicErr, ok := DecodeRevert(append(PanicSelector, EncodeUint(0x11)...)).(*PanicError)
+	if !ok || panicErr.Code.Uint64() != 0x11 {
+		t.Errorf("unexpected panic error: %v", panicErr)
+	}
+	if err := DecodeRevert([]byte{0xde, 0xad, 0xbe, 0xef}); err != nil {
+		t.Errorf("unexpected error for unknown selector: %v", err)
+	}
+	if err := DecodeRevert(append(ErrorSelector, 0x01)); err != nil {
+		t.Errorf("unexpected error for malformed data: %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package codec
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrShortData     = errors.New("codec: data too short")
+	ErrInvalidOffset = errors.New("codec: invalid offset")
+	ErrInvalidLength = errors.New("codec: invalid length")
+	ErrOverflow      = errors.New("codec: value overflows type")
+	ErrInvalidBool   = errors.New("codec: invalid bool")
+	ErrInvalidEvent  = errors.New("codec: log does not match event")
+)
+
+// Field returns the encoding of the element of a tuple whose head is at the
+// given position. The encoding of a dynamic element starts at the offset
+// stored in its head, relative to the start of the tuple.
+func Field(data []byte, pos int, dynamic bool) ([]byte, error) {
+	if pos < 0 || pos+wordSize > len(data) {
+		return nil, ErrShortData
+	}
+	if !dynamic {
+		return data[pos:], nil
+	}
+	offset, err := decodeSize(data[pos:])
+	if err != nil {
+		return nil, err
+	}
+	if offset > len(data) {
+		return nil, ErrInvalidOffset
+	}
+	return data[offset:], nil
+}
+
+// DecodeLength returns the length of a dynamic size array and the encoding of
+// its elements as a tuple. Every element takes at least one word in the head
+// of the tuple, so the length is bounded by the size of the data.
+func DecodeLength(data []byte) (int, []byte, error) {
+	length, err := decodeSize(data)
+	if err != nil {
+		return 0, nil, err
+	}
+	elems := data[wordSize:]
+	if length > len(elems)/wordSize {
+		return 0, nil, ErrInvalidLength
+	}
+	return length, elems, nil
+}
+
+// decodeSize decodes a word holding an offset or a length, which the caller
+// checks against the size of the data.
+func decodeSize(data []byte) (int, error) {
+	size, err := DecodeUint(data, 32)
+	if err == ErrOverflow {
+		return 0, ErrInvalidOffset
+	} else if err != nil {
+		return 0, err
+	}
+	return int(size), nil
+}
+
+// DecodeUint decodes an unsigned integer of the given number of bits, at most
+// 64.
+func DecodeUint(data []byte, bits int) (uint64, error) {
+	if len(data) < wordSize {
+		return 0, ErrShortData
+	}
+	for _, b := range data[:wordSize-8] {
+		if b != 0 {
+			return 0, ErrOverflow
+		}
+	}
+	var v uint64
+	for _, b := range data[wordSize-8 : wordSize] {
+		v = v<<8 | uint64(b)
+	}
+	if bits < 64 && v>>bits != 0 {
+		return 0, ErrOverflow
+	}
+	return v, nil
+}
+
+// DecodeInt decodes a signed integer of the given number of bits, at most 64.
+func DecodeInt(data []byte, bits int) (int64, error) {
+	v, err := DecodeBigInt(data, bits)
+	if err != nil {
+		return 0, err
+	}
+	return v.Int64(), nil
+}
+
+// DecodeBigUint decodes an unsigned integer of the given number of bits.
+func DecodeBigUint(data []byte, bits int) (*big.Int, error) {
+	if len(data) < wordSize {
+		return nil, ErrShortData
+	}
+	v := new(big.Int).SetBytes(data[:wordSize])
+	if v.BitLen() > bits {
+		return nil, ErrOverflow
+	}
+	return v, nil
+}
+
+// DecodeBigInt decodes a signed integer of the given number of bits, in two's
+// complement.
+func DecodeBigInt(data []byte, bits int) (*big.Int, error) {
+	if len(data) < wordSize {
+		return nil, ErrShortData
+	}
+	v := new(big.Int).SetBytes(data[:wordSize])
+	if data[0]&0x80 != 0 {
+		v.Sub(v, new(big.Int).Lsh(big.NewInt(1), 8*wordSize))
+	}
+	// The value must fit in [-2^(bits-1), 2^(bits-1))
+	limit := new(big.Int).Lsh(big.NewInt(1), uint(bits-1))
+	if v.Cmp(limit) >= 0 || v.Cmp(new(big.Int).Neg(limit)) < 0 {
+		return nil, ErrOverflow
+	}
+	return v, nil
+}
+
+// DecodeBool decodes a bool.
+func DecodeBool(data []byte) (bool, error) {
+	v, err := DecodeUint(data, 64)
+	if err != nil {
+		return false, err
+	}
+	switch v {
+	case 0:
+		return false, nil
+	case 1:
+		return true, nil
+	}
+	return false, ErrInvalidBool
+}
+
+// DecodeAddress decodes an address.
+func DecodeAddress(data []byte) (common.Address, error) {
+	if len(data) < wordSize {
+		return common.Address{}, ErrShortData
+	}
+	return common.BytesToAddress(data[wordSize-common.AddressLength : wordSize]), nil
+}
+
+// DecodeFixedBytes decodes a bytes1 to bytes32 value, which is copied to the
+// given array.
+func DecodeFixedBytes(data []byte, v []byte) error {
+	if len(data) < wordSize {
+		return ErrShortData
+	}
+	copy(v, data[:len(v)])
+	return nil
+}
+
+// DecodeBytes decodes a dynamic bytes value.
+func DecodeBytes(data []byte) ([]byte, error) {
+	length, err := decodeSize(data)
+	if err != nil {
+		return nil, err
+	}
+	if wordSize+length > len(data) {
+		return nil, ErrShortData
+	}
+	return common.CopyBytes(data[wordSize : wordSize+length]), nil
+}
+
+// DecodeString decodes a string.
+func DecodeString(data []byte) (string, error) {
+	v, err := DecodeBytes(data)
+	if err != nil {
+		return "", err
+	}
+	return string(v), nil
+}
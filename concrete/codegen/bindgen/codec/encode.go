@@ -0,0 +1,136 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package codec implements the Solidity ABI encoding for the code generated by
+// concrete bindgen. It does not depend on go-ethereum/accounts/abi, which
+// cannot be compiled with tinygo, and it does not use reflection: generated
+// code encodes and decodes every value with the helpers of this package.
+package codec
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const wordSize = 32
+
+// Part is the encoding of a value in a tuple, together with whether its type
+// is dynamic, in which case it is placed after the head of the tuple.
+type Part struct {
+	Data    []byte
+	Dynamic bool
+}
+
+// EncodeWithSelector returns the calldata of a call to the method with the
+// given selector and arguments.
+func EncodeWithSelector(selector []byte, parts ...Part) []byte {
+	return append(append([]byte{}, selector...), EncodeTuple(parts...)...)
+}
+
+// EncodeTuple returns the encoding of a tuple, or of a fixed size array,
+// with the given elements.
+func EncodeTuple(parts ...Part) []byte {
+	headSize := 0
+	for _, part := range parts {
+		if part.Dynamic {
+			headSize += wordSize
+		} else {
+			headSize += len(part.Data)
+		}
+	}
+	head := make([]byte, 0, headSize)
+	var tail []byte
+	for _, part := range parts {
+		if part.Dynamic {
+			head = append(head, EncodeUint(uint64(headSize+len(tail)))...)
+			tail = append(tail, part.Data...)
+		} else {
+			head = append(head, part.Data...)
+		}
+	}
+	return append(head, tail...)
+}
+
+// EncodeArray returns the encoding of a dynamic size array with the given
+// elements.
+func EncodeArray(parts ...Part) []byte {
+	return append(EncodeUint(uint64(len(parts))), EncodeTuple(parts...)...)
+}
+
+// EncodeUint returns the encoding of an unsigned integer of up to 64 bits.
+func EncodeUint(v uint64) []byte {
+	word := make([]byte, wordSize)
+	for i := 0; i < 8; i++ {
+		word[wordSize-1-i] = byte(v >> (8 * i))
+	}
+	return word
+}
+
+// EncodeInt returns the encoding of a signed integer of up to 64 bits.
+func EncodeInt(v int64) []byte {
+	word := EncodeUint(uint64(v))
+	if v < 0 {
+		for i := 0; i < wordSize-8; i++ {
+			word[i] = 0xff
+		}
+	}
+	return word
+}
+
+// EncodeBig returns the encoding of a signed or unsigned integer of more than
+// 64 bits, with negative values in two's complement. A nil value is encoded as
+// zero.
+func EncodeBig(v *big.Int) []byte {
+	word := make([]byte, wordSize)
+	if v == nil {
+		return word
+	}
+	if v.Sign() >= 0 {
+		return v.FillBytes(word)
+	}
+	// Two's complement of -v in 256 bits
+	neg := new(big.Int).Lsh(big.NewInt(1), 8*wordSize)
+	return neg.Add(neg, v).FillBytes(word)
+}
+
+// EncodeBool returns the encoding of a bool.
+func EncodeBool(v bool) []byte {
+	if v {
+		return EncodeUint(1)
+	}
+	return EncodeUint(0)
+}
+
+// EncodeAddress returns the encoding of an address.
+func EncodeAddress(v common.Address) []byte {
+	return common.LeftPadBytes(v.Bytes(), wordSize)
+}
+
+// EncodeFixedBytes returns the encoding of a bytes1 to bytes32 value.
+func EncodeFixedBytes(v []byte) []byte {
+	return common.RightPadBytes(v, wordSize)
+}
+
+// EncodeBytes returns the encoding of a dynamic bytes value.
+func EncodeBytes(v []byte) []byte {
+	padded := (len(v) + wordSize - 1) / wordSize * wordSize
+	return append(EncodeUint(uint64(len(v))), common.RightPadBytes(v, padded)...)
+}
+
+// EncodeString returns the encoding of a string.
+func EncodeString(v string) []byte {
+	return EncodeBytes([]byte(v))
+}
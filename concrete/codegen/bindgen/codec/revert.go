@@ -0,0 +1,77 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package codec
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+)
+
+var (
+	// Selectors of the errors raised by require and revert, and by failed
+	// assertions and other runtime checks
+	ErrorSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+	PanicSelector = []byte{0x4e, 0x48, 0x7b, 0x71}
+)
+
+// RevertError is the error of a call that reverted with a reason string.
+type RevertError struct {
+	Reason string
+}
+
+func (e *RevertError) Error() string {
+	return "execution reverted: " + e.Reason
+}
+
+// PanicError is the error of a call that reverted with a Solidity panic code.
+type PanicError struct {
+	Code *big.Int
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("execution reverted: panic code 0x%x", e.Code)
+}
+
+// HasSelector reports whether the revert data of a call starts with the given
+// error selector.
+func HasSelector(data []byte, selector []byte) bool {
+	return len(data) >= len(selector) && bytes.Equal(data[:len(selector)], selector)
+}
+
+// DecodeRevert decodes the revert data of a call raised by require, revert or
+// a panic. It returns nil if the data is not one of those.
+func DecodeRevert(data []byte) error {
+	switch {
+	case HasSelector(data, ErrorSelector):
+		elem, err := Field(data[4:], 0, true)
+		if err != nil {
+			return nil
+		}
+		reason, err := DecodeString(elem)
+		if err != nil {
+			return nil
+		}
+		return &RevertError{Reason: reason}
+	case HasSelector(data, PanicSelector):
+		code, err := DecodeBigUint(data[4:], 256)
+		if err != nil {
+			return nil
+		}
+		return &PanicError{Code: code}
+	}
+	return nil
+}
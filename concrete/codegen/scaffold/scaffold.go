@@ -0,0 +1,243 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"text/template"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/codegen/datamod"
+	"github.com/ethereum/go-ethereum/concrete/codegen/solgen"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+//go:embed templates/*.tpl
+var templates embed.FS
+
+var (
+	ErrDirNotEmpty    = errors.New("output dir is not empty")
+	ErrUnknownVersion = errors.New("concrete-geth version unknown, a replacement for the go-ethereum module must be provided")
+)
+
+const (
+	gethModule = "github.com/ethereum/go-ethereum"
+	// ConcreteModule is the module of concrete-geth. Upstream go-ethereum has no
+	// concrete packages, so generated projects replace go-ethereum with it.
+	ConcreteModule = "github.com/therealbytes/concrete-geth"
+)
+
+type Config struct {
+	Name    string // Name of the precompile, e.g. Counter
+	Module  string // Go module path of the project
+	Address common.Address
+	Pragma  string
+	Replace string // Replacement for the go-ethereum module, DefaultReplace if empty
+	OutDir  string
+}
+
+// DefaultReplace returns the concrete-geth module at the version this binary was
+// built from, or an empty string if the version is unknown, e.g. in development
+// builds or builds from a modified checkout.
+func DefaultReplace() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	return replaceFromBuildInfo(info)
+}
+
+func replaceFromBuildInfo(info *debug.BuildInfo) string {
+	var mod *debug.Module
+	if info.Main.Path == gethModule {
+		mod = &info.Main
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == gethModule {
+			mod = dep
+		}
+	}
+	if mod == nil {
+		return ""
+	}
+	if mod.Replace != nil {
+		// Built in a project that already replaces go-ethereum, unless by a
+		// local dir
+		if mod.Replace.Version == "" {
+			return ""
+		}
+		return mod.Replace.Path + " " + mod.Replace.Version
+	}
+	// Development builds have version (devel), and builds from a modified
+	// checkout have a +dirty suffix
+	if !strings.HasPrefix(mod.Version, "v") || strings.Contains(mod.Version, "+") {
+		return ""
+	}
+	return ConcreteModule + " " + mod.Version
+}
+
+// projectFiles maps the templates to the paths of the files they generate,
+// relative to the project dir.
+func projectFiles(name string) map[string]string {
+	return map[string]string{
+		"go.mod.tpl":             "go.mod",
+		"gitignore.tpl":          ".gitignore",
+		"Makefile.tpl":           "Makefile",
+		"foundry.toml.tpl":       "foundry.toml",
+		"concrete.toml.tpl":      "concrete.toml",
+		"datamod.json.tpl":       "datamod.json",
+		"abi.json.tpl":           filepath.Join("precompile", name+".abi.json"),
+		"precompile.go.tpl":      filepath.Join("precompile", "precompile.go"),
+		"precompile_test.go.tpl": filepath.Join("precompile", "precompile_test.go"),
+		"wasm.go.tpl":            filepath.Join("wasm", "main.go"),
+		"geth.go.tpl":            filepath.Join("geth", "main.go"),
+		"test.sol.tpl":           filepath.Join("test", name+".t.sol"),
+	}
+}
+
+// NameFromDir returns a precompile name derived from the name of the project
+// dir, e.g. my-counter becomes MyCounter.
+func NameFromDir(dir string) string {
+	words := regexp.MustCompile(`[^a-zA-Z0-9]+`).Split(filepath.Base(dir), -1)
+	var name string
+	for _, word := range words {
+		if word != "" {
+			name += strings.ToUpper(word[:1]) + word[1:]
+		}
+	}
+	return name
+}
+
+func isValidName(name string) bool {
+	return regexp.MustCompile(`^[A-Z][a-zA-Z0-9]*$`).MatchString(name)
+}
+
+func methodID(signature string) string {
+	return common.Bytes2Hex(crypto.Keccak256([]byte(signature))[:4])
+}
+
+func isEmptyDir(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	return len(entries) == 0, err
+}
+
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0644)
+}
+
+// GenerateProject writes a precompile project to the output dir: a Go
+// precompile with a datamod schema and its generated wrappers, a Solidity
+// library for the precompile, Solidity tests run with testtool, a custom geth
+// main and a TinyGo WASM build target. The output dir must be empty.
+func GenerateProject(config Config) error {
+	if !isValidName(config.Name) {
+		return fmt.Errorf("invalid precompile name: '%s', must be an upper case identifier", config.Name)
+	}
+	if config.Module == "" {
+		return errors.New("module path must be provided")
+	}
+	if config.Replace == "" {
+		if config.Replace = DefaultReplace(); config.Replace == "" {
+			return ErrUnknownVersion
+		}
+	}
+	if empty, err := isEmptyDir(config.OutDir); err != nil {
+		return err
+	} else if !empty {
+		return fmt.Errorf("%w: %s", ErrDirNotEmpty, config.OutDir)
+	}
+
+	data := map[string]interface{}{
+		"Name":              config.Name,
+		"LowerName":         strings.ToLower(config.Name),
+		"Module":            config.Module,
+		"Address":           config.Address.Hex(),
+		"Pragma":            config.Pragma,
+		"Replace":           config.Replace,
+		"GethVersion":       params.GethVersion,
+		"IncrementMethodID": methodID("increment()"),
+		"GetMethodID":       methodID("get(address)"),
+	}
+
+	files := projectFiles(config.Name)
+	for tplName, path := range files {
+		tplContent, err := templates.ReadFile("templates/" + tplName)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(tplName).Parse(string(tplContent))
+		if err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return err
+		}
+		content := buf.Bytes()
+		if filepath.Ext(path) == ".go" {
+			if content, err = format.Source(content); err != nil {
+				return fmt.Errorf("formatting %s: %w", path, err)
+			}
+		}
+		if err := writeFile(filepath.Join(config.OutDir, path), content); err != nil {
+			return err
+		}
+	}
+
+	datamodDir := filepath.Join(config.OutDir, "precompile", "datamod")
+	if err := os.MkdirAll(datamodDir, 0755); err != nil {
+		return err
+	}
+	if err := datamod.GenerateDataModel(datamod.Config{
+		SchemaFilePath: filepath.Join(config.OutDir, files["datamod.json.tpl"]),
+		OutDir:         datamodDir,
+		Package:        "datamod",
+	}, false); err != nil {
+		return fmt.Errorf("generating data model: %w", err)
+	}
+
+	srcDir := filepath.Join(config.OutDir, "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		return err
+	}
+	if err := solgen.GenerateSolidityLibrary(solgen.Config{
+		Name:    config.Name + "Precompile",
+		Address: config.Address,
+		Pragma:  config.Pragma,
+		AbiPath: filepath.Join(config.OutDir, files["abi.json.tpl"]),
+		OutPath: filepath.Join(srcDir, config.Name+"Precompile.sol"),
+	}); err != nil {
+		return fmt.Errorf("generating solidity library: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,172 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package scaffold
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestNameFromDir(t *testing.T) {
+	testCases := []struct {
+		dir      string
+		expected string
+	}{
+		{"counter", "Counter"},
+		{"my-counter", "MyCounter"},
+		{filepath.Join("path", "to", "my_kv_store"), "MyKvStore"},
+	}
+	for _, testCase := range testCases {
+		if name := NameFromDir(testCase.dir); name != testCase.expected {
+			t.Errorf("unexpected name for %q: %q", testCase.dir, name)
+		}
+	}
+}
+
+func TestReplaceFromBuildInfo(t *testing.T) {
+	testCases := []struct {
+		name     string
+		info     debug.BuildInfo
+		expected string
+	}{
+		{
+			name:     "release",
+			info:     debug.BuildInfo{Main: debug.Module{Path: gethModule, Version: "v1.13.15"}},
+			expected: ConcreteModule + " v1.13.15",
+		},
+		{
+			name:     "pseudo-version",
+			info:     debug.BuildInfo{Main: debug.Module{Path: gethModule, Version: "v0.0.0-20240101000000-0123456789ab"}},
+			expected: ConcreteModule + " v0.0.0-20240101000000-0123456789ab",
+		},
+		{
+			name: "devel",
+			info: debug.BuildInfo{Main: debug.Module{Path: gethModule, Version: "(devel)"}},
+		},
+		{
+			name: "dirty",
+			info: debug.BuildInfo{Main: debug.Module{Path: gethModule, Version: "v0.0.0-20240101000000-0123456789ab+dirty"}},
+		},
+		{
+			name: "dependency",
+			info: debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/node"},
+				Deps: []*debug.Module{{
+					Path:    gethModule,
+					Version: "v1.13.15",
+					Replace: &debug.Module{Path: "example.com/fork", Version: "v1.0.0"},
+				}},
+			},
+			expected: "example.com/fork v1.0.0",
+		},
+		{
+			name: "local replace",
+			info: debug.BuildInfo{
+				Main: debug.Module{Path: "example.com/node"},
+				Deps: []*debug.Module{{
+					Path:    gethModule,
+					Version: "v1.13.15",
+					Replace: &debug.Module{Path: "../concrete-geth"},
+				}},
+			},
+		},
+		{
+			name: "missing",
+			info: debug.BuildInfo{Main: debug.Module{Path: "example.com/node"}},
+		},
+	}
+	for _, testCase := range testCases {
+		if replace := replaceFromBuildInfo(&testCase.info); replace != testCase.expected {
+			t.Errorf("unexpected replace for %s: %q", testCase.name, replace)
+		}
+	}
+}
+
+func TestGenerateProject(t *testing.T) {
+	// Build the project against the local tree
+	root, err := filepath.Abs(filepath.Join("..", "..", ".."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	outDir := t.TempDir()
+	config := Config{
+		Name:    "Counter",
+		Module:  "example.com/counter",
+		Address: common.HexToAddress("0x80"),
+		Pragma:  "^0.8.0",
+		Replace: root,
+		OutDir:  outDir,
+	}
+	if err := GenerateProject(config); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{
+		"go.mod",
+		filepath.Join("precompile", "datamod", "counters.go"),
+		filepath.Join("src", "CounterPrecompile.sol"),
+		filepath.Join("test", "Counter.t.sol"),
+	} {
+		if _, err := os.Stat(filepath.Join(outDir, path)); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := GenerateProject(config); !errors.Is(err, ErrDirNotEmpty) {
+		t.Errorf("expected ErrDirNotEmpty, got %v", err)
+	}
+
+	gomod, err := os.ReadFile(filepath.Join(outDir, "go.mod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"require github.com/ethereum/go-ethereum v" + params.GethVersion,
+		"replace github.com/ethereum/go-ethereum => " + root,
+	} {
+		if !strings.Contains(string(gomod), line) {
+			t.Errorf("go.mod is missing %q", line)
+		}
+	}
+
+	// Vet the project with its own go.mod. The dependencies are resolved from
+	// the local tree, so its go.sum is enough to do it offline. The WASM
+	// target needs TinyGo, so only the precompile and geth packages are vetted.
+	gosum, err := os.ReadFile(filepath.Join(root, "go.sum"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "go.sum"), gosum, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command("go", "vet", "./precompile/...", "./geth")
+	cmd.Dir = outDir
+	cmd.Env = append(os.Environ(), "GOFLAGS=-mod=mod", "GOWORK=off")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		t.Log(stderr.String())
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,25 @@
+.PHONY: all datamod solgen wasm geth forge test test-wasm
+
+all: datamod solgen wasm geth
+
+datamod:
+	mkdir -p precompile/datamod
+	concrete datamod datamod.json --out precompile/datamod --pkg datamod
+
+solgen:
+	concrete solgen --abi precompile/{{.Name}}.abi.json --name {{.Name}}Precompile --address {{.Address}} --pragma "{{.Pragma}}" --out src/{{.Name}}Precompile.sol
+
+wasm:
+	tinygo build -opt=2 -o build/{{.LowerName}}.wasm -target=wasi ./wasm
+
+geth:
+	go build -o build/geth ./geth
+
+forge:
+	forge build
+
+test: forge
+	go test ./...
+
+test-wasm: forge wasm
+	concrete test --registry concrete.toml
@@ -0,0 +1,4 @@
+# Precompiles loaded by `concrete test --registry concrete.toml`
+[[precompiles]]
+address = "{{.Address}}"
+wasm = "build/{{.LowerName}}.wasm"
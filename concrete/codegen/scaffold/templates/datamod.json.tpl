@@ -0,0 +1,10 @@
+{
+    "counters": {
+        "keySchema": {
+            "owner": "address"
+        },
+        "schema": {
+            "value": "uint256"
+        }
+    }
+}
@@ -0,0 +1,5 @@
+[profile.default]
+src = "src"
+test = "test"
+out = "out"
+libs = ["lib"]
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ethereum/go-ethereum/cmd/geth"
+	"github.com/ethereum/go-ethereum/concrete"
+	concrete_rpc "github.com/ethereum/go-ethereum/concrete/rpc"
+	"github.com/ethereum/go-ethereum/concrete/wasm"
+
+	"{{.Module}}/precompile"
+)
+
+func main() {
+	registry := concrete.NewRegistry()
+	registry.AddPrecompile(0, precompile.Address, &precompile.{{.Name}}Precompile{})
+	// Load WASM modules that override precompiles in eth_call and eth_estimateGas
+	// when the node runs with --rpc.precompileoverrides
+	registry.SetLoader(wasm.LoadWazeroPrecompile)
+	app := geth.NewConcreteGethApp(registry, []concrete_rpc.APIConstructor{})
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
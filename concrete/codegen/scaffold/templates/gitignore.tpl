@@ -0,0 +1,3 @@
+build/
+cache/
+out/
@@ -0,0 +1,7 @@
+module {{.Module}}
+
+go 1.21
+
+require github.com/ethereum/go-ethereum v{{.GethVersion}}
+
+replace github.com/ethereum/go-ethereum => {{.Replace}}
@@ -0,0 +1,58 @@
+package precompile
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/lib"
+	"github.com/ethereum/go-ethereum/concrete/utils"
+	"github.com/holiman/uint256"
+
+	"{{.Module}}/precompile/datamod"
+)
+
+// Address is the address the precompile is registered at.
+var Address = common.HexToAddress("{{.Address}}")
+
+var (
+	ErrMethodNotFound = errors.New("method not found")
+	ErrInvalidInput   = errors.New("invalid input")
+)
+
+var (
+	IncrementMethodID = common.Hex2Bytes("{{.IncrementMethodID}}") // increment()
+	GetMethodID       = common.Hex2Bytes("{{.GetMethodID}}") // get(address)
+)
+
+// {{.Name}}Precompile keeps a counter for every address. Callers can increment
+// their own counter and read the counter of any address.
+type {{.Name}}Precompile struct {
+	lib.BlankPrecompile
+}
+
+func (p *{{.Name}}Precompile) IsStatic(input []byte) bool {
+	methodID, _ := utils.SplitInput(input)
+	return !bytes.Equal(methodID, IncrementMethodID)
+}
+
+func (p *{{.Name}}Precompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	methodID, data := utils.SplitInput(input)
+	counters := datamod.NewCounters(lib.NewDatastore(env))
+	if bytes.Equal(methodID, IncrementMethodID) {
+		counter := counters.Get(env.GetCaller())
+		counter.SetValue(new(uint256.Int).AddUint64(counter.GetValue(), 1))
+		return nil, nil
+	} else if bytes.Equal(methodID, GetMethodID) {
+		if len(data) != 32 {
+			return nil, ErrInvalidInput
+		}
+		value := counters.Get(common.BytesToAddress(data)).GetValue()
+		return value.PaddedBytes(32), nil
+	}
+	return nil, ErrMethodNotFound
+}
+
+var _ concrete.Precompile = &{{.Name}}Precompile{}
@@ -0,0 +1,19 @@
+package precompile
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/testtool"
+)
+
+// TestPrecompile runs the Solidity tests in the test dir against the
+// precompile. Run forge build first.
+func TestPrecompile(t *testing.T) {
+	registry := concrete.NewRegistry()
+	registry.AddPrecompile(0, Address, &{{.Name}}Precompile{})
+	testtool.Test(t, registry, testtool.TestConfig{
+		TestDir: "../test",
+		OutDir:  "../out",
+	})
+}
@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: MIT
+pragma solidity {{.Pragma}};
+
+import "../src/{{.Name}}Precompile.sol";
+
+contract {{.Name}}Test {
+    function testIncrement() public {
+        uint256 before = {{.Name}}Precompile.get(address(this));
+        {{.Name}}Precompile.increment();
+        require({{.Name}}Precompile.get(address(this)) == before + 1, "counter not incremented");
+    }
+
+    function testGetUnset() public view {
+        require({{.Name}}Precompile.get(address(0xdead)) == 0, "counter not zero");
+    }
+}
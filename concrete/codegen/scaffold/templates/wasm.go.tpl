@@ -0,0 +1,14 @@
+package main
+
+import (
+	"github.com/ethereum/go-ethereum/tinygo"
+
+	"{{.Module}}/precompile"
+)
+
+func init() {
+	tinygo.WasmWrap(&precompile.{{.Name}}Precompile{})
+}
+
+// main is REQUIRED for TinyGo to compile to WASM
+func main() {}
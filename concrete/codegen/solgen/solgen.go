@@ -22,9 +22,11 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"text/template"
+	"unicode"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
@@ -40,6 +42,7 @@ type Config struct {
 	AbiPath    string
 	ImportPath string
 	OutPath    string
+	Interface  bool // Generate an interface instead of a library
 }
 
 func isValidSolidityContractName(name string) bool {
@@ -63,29 +66,173 @@ func formatPath(path string) string {
 	}
 }
 
-func getTypeString(internalType string, arg abi.Argument) string {
-	if strings.HasPrefix(internalType, "struct ") {
-		return strings.TrimPrefix(internalType, "struct ")
+// getTypeString returns the solidity type of an argument, using the name of
+// the struct declared by the generated code for struct types.
+func getTypeString(arg abi.ArgumentMarshaling) string {
+	if strings.HasPrefix(arg.InternalType, "struct ") {
+		return structTypeName(arg.InternalType)
 	} else {
-		return arg.Type.String()
+		return arg.Type
 	}
 }
 
-func withLocation(typeStr string, arg abi.Argument) string {
+// structTypeName returns the unqualified name of a struct type, keeping any
+// array suffix, e.g. "struct Foo.Bar[]" becomes "Bar[]".
+func structTypeName(internalType string) string {
+	name := strings.TrimPrefix(internalType, "struct ")
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	return name
+}
+
+func withLocation(typeStr string, arg abi.ArgumentMarshaling) string {
 	var argName string
 	if len(arg.Name) > 0 {
 		argName = " " + arg.Name
 	}
-	if typeStr == arg.Type.String() {
-		if typeStr == "bytes" || typeStr == "string" || strings.Contains(typeStr, "[") {
-			return typeStr + " memory" + argName
+	if arg.Type == "bytes" || arg.Type == "string" || strings.Contains(arg.Type, "[") || strings.HasPrefix(arg.Type, "tuple") {
+		return typeStr + " memory" + argName
+	}
+	return typeStr + argName
+}
+
+func withIndexed(typeStr string, arg abi.ArgumentMarshaling) string {
+	if arg.Indexed {
+		typeStr += " indexed"
+	}
+	if len(arg.Name) > 0 {
+		typeStr += " " + arg.Name
+	}
+	return typeStr
+}
+
+// canonicalSignature returns the signature an ABI entry is identified by in the
+// devdoc and userdoc, e.g. "transfer(address,(uint256,bool))".
+func canonicalSignature(entry customEntry) (string, error) {
+	types := make([]string, len(entry.Inputs))
+	for i, input := range entry.Inputs {
+		typ, err := abi.NewType(input.Type, input.InternalType, input.Components)
+		if err != nil {
+			return "", err
 		}
-		return typeStr + argName
+		types[i] = typ.String()
 	}
-	return typeStr + " memory" + argName
+	return fmt.Sprintf("%s(%s)", entry.Name, strings.Join(types, ",")), nil
+}
+
+type structDef struct {
+	Name   string
+	Fields []string
+}
+
+// structCollector collects the structs used by the arguments of an ABI, with
+// the structs a struct depends on before it.
+type structCollector struct {
+	structs []structDef
+	byName  map[string]string // Fields of each struct, to detect conflicts
 }
 
-func generateSolidityLibrary(ABI abi.ABI, cABI customABI, config Config) (string, error) {
+func (c *structCollector) add(args []abi.ArgumentMarshaling) error {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg.InternalType, "struct ") {
+			continue
+		}
+		if err := c.add(arg.Components); err != nil {
+			return err
+		}
+		name := strings.TrimRight(structTypeName(arg.InternalType), "[]0123456789")
+		fields := make([]string, len(arg.Components))
+		for i, component := range arg.Components {
+			fields[i] = getTypeString(component) + " " + component.Name
+		}
+		key := strings.Join(fields, ";")
+		if prev, ok := c.byName[name]; ok {
+			if prev != key {
+				return fmt.Errorf("conflicting definitions of struct %s", name)
+			}
+			continue
+		}
+		c.byName[name] = key
+		c.structs = append(c.structs, structDef{Name: name, Fields: fields})
+	}
+	return nil
+}
+
+// docLines returns the NatSpec comment lines of an ABI entry or contract.
+func docLines(notice, details string, params map[string]string, paramNames []string, returns map[string]string, returnNames []string) []string {
+	lines := []string{}
+	addTag := func(tag, text string) {
+		for i, line := range strings.Split(strings.TrimSpace(text), "\n") {
+			if i == 0 {
+				lines = append(lines, strings.TrimSpace(tag+" "+strings.TrimSpace(line)))
+			} else {
+				lines = append(lines, strings.TrimSpace(line))
+			}
+		}
+	}
+	if notice != "" {
+		addTag("@notice", notice)
+	}
+	if details != "" {
+		addTag("@dev", details)
+	}
+	for _, name := range paramNames {
+		if doc, ok := params[name]; ok {
+			addTag("@param "+name, doc)
+		}
+	}
+	for i, name := range returnNames {
+		key := name
+		if key == "" {
+			key = fmt.Sprintf("_%d", i)
+		}
+		if doc, ok := returns[key]; ok {
+			addTag(strings.TrimSpace("@return "+name), doc)
+		}
+	}
+	return lines
+}
+
+func argNames(args []abi.ArgumentMarshaling) []string {
+	names := make([]string, len(args))
+	for i, arg := range args {
+		names[i] = arg.Name
+	}
+	return names
+}
+
+func mutabilityKeyword(entry customEntry, method abi.Method) string {
+	switch {
+	case entry.StateMutability == "pure", entry.StateMutability == "view", entry.StateMutability == "payable":
+		return entry.StateMutability
+	case method.IsConstant():
+		return "view"
+	case method.IsPayable():
+		return "payable"
+	}
+	return ""
+}
+
+// addressConstantName returns the name of the address constant declared with
+// an interface, e.g. ICounter becomes I_COUNTER_ADDRESS.
+func addressConstantName(name string) string {
+	var out []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				out = append(out, '_')
+			}
+		}
+		out = append(out, unicode.ToUpper(r))
+	}
+	return string(out) + "_ADDRESS"
+}
+
+func generateSolidityLibrary(ABI abi.ABI, cABI customABI, doc natSpec, config Config) (string, error) {
 	config.ImportPath = filepath.ToSlash(config.ImportPath)
 
 	tmpl, err := template.New("solgen").Parse(solgenTpl)
@@ -107,46 +254,117 @@ func generateSolidityLibrary(ABI abi.ABI, cABI customABI, config Config) (string
 		importPaths = append(importPaths, importPath)
 	}
 
+	methodsBySig := make(map[string]abi.Method)
+	for _, method := range ABI.Methods {
+		methodsBySig[method.Sig] = method
+	}
+
+	structs := &structCollector{byName: make(map[string]string)}
+	methods := []map[string]interface{}{}
+	events := []map[string]interface{}{}
+	errs := []map[string]interface{}{}
+
+	for _, entry := range cABI.Entries {
+		sig, err := canonicalSignature(entry)
+		if err != nil {
+			return "", err
+		}
+		if err := structs.add(entry.Inputs); err != nil {
+			return "", err
+		}
+		if err := structs.add(entry.Outputs); err != nil {
+			return "", err
+		}
+
+		switch entry.Type {
+		case "function", "":
+			method, ok := methodsBySig[sig]
+			if !ok {
+				return "", fmt.Errorf("method not found: %s", sig)
+			}
+
+			inputSig := []string{}
+			inputNames := []string{}
+			for _, input := range entry.Inputs {
+				inputSig = append(inputSig, withLocation(getTypeString(input), input))
+				inputNames = append(inputNames, input.Name)
+			}
+
+			outputSig := []string{}
+			outputTypes := []string{}
+			for _, output := range entry.Outputs {
+				typeStr := getTypeString(output)
+				outputSig = append(outputSig, withLocation(typeStr, output))
+				outputTypes = append(outputTypes, typeStr)
+			}
+
+			dev := doc.DevDoc.Methods[sig]
+			methods = append(methods, map[string]interface{}{
+				"Name":        method.RawName,
+				"Signature":   method.Sig,
+				"IsStatic":    method.IsConstant(),
+				"Mutability":  mutabilityKeyword(entry, method),
+				"Inputs":      strings.Join(inputSig, ", "),
+				"Outputs":     strings.Join(outputSig, ", "),
+				"OutputTypes": strings.Join(outputTypes, ", "),
+				"InputNames":  strings.Join(inputNames, ", "),
+				"Doc":         docLines(doc.UserDoc.Methods[sig].Notice, dev.Details, dev.Params, argNames(entry.Inputs), dev.Returns, argNames(entry.Outputs)),
+			})
+
+		case "event":
+			inputSig := []string{}
+			for _, input := range entry.Inputs {
+				inputSig = append(inputSig, withIndexed(getTypeString(input), input))
+			}
+			dev := doc.DevDoc.Events[sig]
+			events = append(events, map[string]interface{}{
+				"Name":      entry.Name,
+				"Inputs":    strings.Join(inputSig, ", "),
+				"Anonymous": entry.Anonymous,
+				"Doc":       docLines(doc.UserDoc.Events[sig].Notice, dev.Details, dev.Params, argNames(entry.Inputs), nil, nil),
+			})
+
+		case "error":
+			inputSig := []string{}
+			for _, input := range entry.Inputs {
+				inputSig = append(inputSig, withIndexed(getTypeString(input), input))
+			}
+			var notice string
+			var dev devDocEntry
+			if docs := doc.UserDoc.Errors[sig]; len(docs) > 0 {
+				notice = docs[0].Notice
+			}
+			if docs := doc.DevDoc.Errors[sig]; len(docs) > 0 {
+				dev = docs[0]
+			}
+			errs = append(errs, map[string]interface{}{
+				"Name":   entry.Name,
+				"Inputs": strings.Join(inputSig, ", "),
+				"Doc":    docLines(notice, dev.Details, dev.Params, argNames(entry.Inputs), nil, nil),
+			})
+		}
+	}
+
+	contractDoc := docLines(doc.UserDoc.Notice, doc.DevDoc.Details, nil, nil, nil, nil)
+	if doc.DevDoc.Title != "" {
+		contractDoc = append([]string{"@title " + doc.DevDoc.Title}, contractDoc...)
+	}
+	if doc.DevDoc.Author != "" {
+		contractDoc = append(contractDoc, "@author "+doc.DevDoc.Author)
+	}
+
 	data := map[string]interface{}{
-		"Name":        config.Name,
-		"Address":     config.Address.Hex(),
-		"Pragma":      config.Pragma,
-		"Methods":     []map[string]interface{}{},
-		"ImportPaths": importPaths,
-	}
-
-	for mIdx, method := range ABI.Methods {
-		inputSig := []string{}
-		inputTypes := []string{}
-		inputNames := []string{}
-		for inIdx, input := range method.Inputs {
-			internalType := cABI.MethodsByName[mIdx].Inputs[inIdx].InternalType
-			typeStr := getTypeString(internalType, input)
-			inputSig = append(inputSig, withLocation(typeStr, input))
-			inputTypes = append(inputTypes, typeStr)
-			inputNames = append(inputNames, input.Name)
-		}
-
-		outputSig := []string{}
-		outputTypes := []string{}
-		for outIdx, output := range method.Outputs {
-			internalType := cABI.MethodsByName[mIdx].Outputs[outIdx].InternalType
-			typeStr := getTypeString(internalType, output)
-			outputSig = append(outputSig, withLocation(typeStr, output))
-			outputTypes = append(outputTypes, typeStr)
-		}
-
-		methodData := map[string]interface{}{
-			"Name":        method.Name,
-			"Signature":   method.Sig,
-			"IsStatic":    method.IsConstant(),
-			"Inputs":      strings.Join(inputSig, ", "),
-			"Outputs":     strings.Join(outputSig, ", "),
-			"InputTypes":  strings.Join(inputTypes, ", "),
-			"OutputTypes": strings.Join(outputTypes, ", "),
-			"InputNames":  strings.Join(inputNames, ", "),
-		}
-		data["Methods"] = append(data["Methods"].([]map[string]interface{}), methodData)
+		"Name":            config.Name,
+		"Interface":       config.Interface,
+		"Address":         config.Address.Hex(),
+		"AddressConstant": addressConstantName(config.Name),
+		"Pragma":          config.Pragma,
+		"Structs":         structs.structs,
+		"Events":          events,
+		"Errors":          errs,
+		"Methods":         methods,
+		"ImportPaths":     importPaths,
+		"Doc":             contractDoc,
 	}
 
 	var buf bytes.Buffer
@@ -154,32 +372,79 @@ func generateSolidityLibrary(ABI abi.ABI, cABI customABI, config Config) (string
 		return "", err
 	}
 
-	return buf.String(), nil
+	code := buf.String()
+	if config.Interface {
+		// Interfaces have no address constant before their first declaration
+		code = strings.Replace(code, " {\n\n", " {\n", 1)
+	}
+	return code, nil
 }
 
-type customMethod struct {
-	Name    string                   `json:"name"`
-	Inputs  []abi.ArgumentMarshaling `json:"inputs"`
-	Outputs []abi.ArgumentMarshaling `json:"outputs"`
+// customEntry is an ABI entry as it appears in the ABI file, which keeps the
+// internal types of the arguments and the order of the entries.
+type customEntry struct {
+	Type            string                   `json:"type"`
+	Name            string                   `json:"name"`
+	Inputs          []abi.ArgumentMarshaling `json:"inputs"`
+	Outputs         []abi.ArgumentMarshaling `json:"outputs"`
+	StateMutability string                   `json:"stateMutability"`
+	Anonymous       bool                     `json:"anonymous"`
 }
 
 type customABI struct {
-	Methods       []customMethod           `json:"methods"`
-	MethodsByName map[string]*customMethod `json:"-"`
+	Entries []customEntry
 }
 
 func (c *customABI) UnmarshalJSON(data []byte) error {
-	type Alias []customMethod
-	var a Alias
-	if err := json.Unmarshal(data, &a); err != nil {
-		return err
+	return json.Unmarshal(data, &c.Entries)
+}
+
+type devDocEntry struct {
+	Details string            `json:"details"`
+	Params  map[string]string `json:"params"`
+	Returns map[string]string `json:"returns"`
+}
+
+type userDocEntry struct {
+	Notice string `json:"notice"`
+}
+
+// natSpec is the documentation of a contract as output by solc, keyed by the
+// canonical signatures of the ABI entries.
+type natSpec struct {
+	DevDoc struct {
+		Title   string                   `json:"title"`
+		Author  string                   `json:"author"`
+		Details string                   `json:"details"`
+		Methods map[string]devDocEntry   `json:"methods"`
+		Events  map[string]devDocEntry   `json:"events"`
+		Errors  map[string][]devDocEntry `json:"errors"`
+	} `json:"devdoc"`
+	UserDoc struct {
+		Notice  string                    `json:"notice"`
+		Methods map[string]userDocEntry   `json:"methods"`
+		Events  map[string]userDocEntry   `json:"events"`
+		Errors  map[string][]userDocEntry `json:"errors"`
+	} `json:"userdoc"`
+}
+
+// getNatSpec returns the devdoc and userdoc of a build artifact, either at the
+// top level or in its metadata as in Foundry artifacts. It returns empty docs
+// for plain ABI files.
+func getNatSpec(content []byte) natSpec {
+	var jsonData struct {
+		natSpec
+		Metadata struct {
+			Output natSpec `json:"output"`
+		} `json:"metadata"`
 	}
-	c.Methods = a
-	c.MethodsByName = make(map[string]*customMethod)
-	for i := range c.Methods {
-		c.MethodsByName[c.Methods[i].Name] = &c.Methods[i]
+	if err := json.Unmarshal(content, &jsonData); err != nil {
+		return natSpec{}
 	}
-	return nil
+	if reflect.DeepEqual(jsonData.natSpec, natSpec{}) {
+		return jsonData.Metadata.Output
+	}
+	return jsonData.natSpec
 }
 
 func GetABI(path string) (abi.ABI, customABI, error) {
@@ -187,14 +452,17 @@ func GetABI(path string) (abi.ABI, customABI, error) {
 	if err != nil {
 		return abi.ABI{}, customABI{}, err
 	}
+	return getABI(content)
+}
 
+func getABI(content []byte) (abi.ABI, customABI, error) {
 	var jsonData struct {
 		ABI abi.ABI `json:"abi"`
 	}
 	var cJsonData struct {
 		ABI customABI `json:"abi"`
 	}
-	err = json.Unmarshal(content, &jsonData)
+	err := json.Unmarshal(content, &jsonData)
 	if err == nil {
 		err = json.Unmarshal(content, &cJsonData)
 		if err != nil {
@@ -217,12 +485,20 @@ func GetABI(path string) (abi.ABI, customABI, error) {
 	return abi.ABI{}, customABI{}, err
 }
 
+// GenerateSolidityLibrary generates a solidity library, or an interface if
+// config.Interface is set, to call the precompile with the given ABI. The ABI
+// file can be a plain ABI or a build artifact, in which case the NatSpec of the
+// contract is copied to the generated code.
 func GenerateSolidityLibrary(config Config) error {
-	ABI, cABI, err := GetABI(config.AbiPath)
+	content, err := os.ReadFile(config.AbiPath)
+	if err != nil {
+		return err
+	}
+	ABI, cABI, err := getABI(content)
 	if err != nil {
 		return err
 	}
-	code, err := generateSolidityLibrary(ABI, cABI, config)
+	code, err := generateSolidityLibrary(ABI, cABI, getNatSpec(content), config)
 	if err != nil {
 		return err
 	}
@@ -8,11 +8,44 @@ pragma solidity {{.Pragma}};
 import "{{.}}";
 {{- end }}
 {{- end }}
+{{- if $.Interface }}
 
-library {{$.Name}} {
+address constant {{$.AddressConstant}} = address({{$.Address}});
+{{- end }}
+{{ range $.Doc }}
+/// {{.}}
+{{- end }}
+{{ if $.Interface }}interface{{ else }}library{{ end }} {{$.Name}} {
+    {{- if not $.Interface }}
     address constant precompileAddress = address({{$.Address}});
-    {{- range $method := $.Methods }}
+    {{- end }}
+    {{- range $struct := $.Structs }}
 
+    struct {{$struct.Name}} {
+        {{- range $struct.Fields }}
+        {{.}};
+        {{- end }}
+    }
+    {{- end }}
+    {{- range $event := $.Events }}
+{{ range $event.Doc }}
+    /// {{.}}
+    {{- end }}
+    event {{$event.Name}}({{$event.Inputs}}){{if $event.Anonymous}} anonymous{{end}};
+    {{- end }}
+    {{- range $error := $.Errors }}
+{{ range $error.Doc }}
+    /// {{.}}
+    {{- end }}
+    error {{$error.Name}}({{$error.Inputs}});
+    {{- end }}
+    {{- range $method := $.Methods }}
+{{ range $method.Doc }}
+    /// {{.}}
+    {{- end }}
+    {{- if $.Interface }}
+    function {{$method.Name}}({{$method.Inputs}}) external{{if $method.Mutability}} {{$method.Mutability}}{{end}}{{if $method.Outputs}} returns ({{$method.Outputs}}){{end}};
+    {{- else }}
     function {{$method.Name}}({{$method.Inputs}}) internal{{if $method.IsStatic}} view{{end}}{{if $method.Outputs}} returns ({{$method.Outputs}}){{end}} {
         (bool success, {{if $method.Outputs}}bytes memory data{{end}}) = precompileAddress.{{if $method.IsStatic}}staticcall{{else}}call{{end}}(
             abi.encodeWithSignature("{{$method.Signature}}"{{if $method.InputNames}}, {{$method.InputNames}}{{end}})
@@ -23,4 +56,5 @@ library {{$.Name}} {
         {{- end }}
     }
     {{- end }}
+    {{- end }}
 }
@@ -16,7 +16,9 @@
 package solgen
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -56,3 +58,107 @@ func TestSolgen(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestStructTypeName(t *testing.T) {
+	testCases := []struct {
+		internalType string
+		expected     string
+	}{
+		{"struct Point", "Point"},
+		{"struct Geometry.Point", "Point"},
+		{"struct Geometry.Point[]", "Point[]"},
+		{"struct Geometry.Point[2][]", "Point[2][]"},
+	}
+	for _, testCase := range testCases {
+		if name := structTypeName(testCase.internalType); name != testCase.expected {
+			t.Errorf("unexpected name for %q: %q", testCase.internalType, name)
+		}
+	}
+}
+
+func TestAddressConstantName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"Counter", "COUNTER_ADDRESS"},
+		{"ICounter", "I_COUNTER_ADDRESS"},
+		{"KVStore2", "KV_STORE2_ADDRESS"},
+	}
+	for _, testCase := range testCases {
+		if name := addressConstantName(testCase.name); name != testCase.expected {
+			t.Errorf("unexpected name for %q: %q", testCase.name, name)
+		}
+	}
+}
+
+func TestSolgenArtifact(t *testing.T) {
+	testCases := []struct {
+		config   Config
+		expected []string
+	}{
+		{
+			config: Config{Name: "TokenPrecompile", OutPath: filepath.Join("testdata", "TokenPrecompile.sol")},
+			expected: []string{
+				"/// @title Token precompile\n/// @notice A token implemented as a precompile.\n",
+				"library TokenPrecompile {\n    address constant precompileAddress",
+				"    struct Amount {\n        uint256 value;\n        uint8 decimals;\n    }\n\n    struct Transfer {\n        address to;\n        Amount amount;\n    }\n",
+				"    /// @notice Emitted on transfers.\n    /// @param from The sender\n    event Transferred(address indexed from, address indexed to, uint256 amount);\n",
+				"    error InsufficientBalance(uint256 available, uint256 required);\n",
+				"    /// @param amount The amount to send\n    /// @return Whether the transfer succeeded\n    function transfer(address to, uint256 amount) internal returns (bool) {\n",
+				"    function transfer(Transfer memory request) internal returns (bool ok) {\n",
+				"abi.encodeWithSignature(\"transfer((address,(uint256,uint8)))\", request)",
+				"    function balances(address[] memory owners) internal view returns (Amount[] memory amounts) {\n",
+				"return abi.decode(data, (Amount[]));",
+			},
+		},
+		{
+			config: Config{Name: "IToken", OutPath: filepath.Join("testdata", "IToken.sol"), Interface: true},
+			expected: []string{
+				"address constant I_TOKEN_ADDRESS = address(0x8000000000000000000000000000000000000000);\n",
+				"interface IToken {\n    struct Amount {\n",
+				"    error InsufficientBalance(uint256 available, uint256 required);\n",
+				"    function transfer(address to, uint256 amount) external returns (bool);\n",
+				"    function balances(address[] memory owners) external view returns (Amount[] memory amounts);\n",
+				"    function deposit() external payable;\n",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		config := testCase.config
+		config.Address = common.Address{0x80}
+		config.Pragma = "^0.8.0"
+		config.AbiPath = filepath.Join("testdata", "Token.json")
+		if err := GenerateSolidityLibrary(config); err != nil {
+			t.Fatal(err)
+		}
+		code, err := os.ReadFile(config.OutPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, expected := range testCase.expected {
+			if !strings.Contains(string(code), expected) {
+				t.Errorf("%s does not contain:\n%s", config.Name, expected)
+			}
+		}
+	}
+}
+
+func TestSolgenConflictingStructs(t *testing.T) {
+	abiJSON := `[
+		{"type": "function", "name": "a", "stateMutability": "view", "outputs": [], "inputs": [
+			{"name": "p", "type": "tuple", "internalType": "struct A.Point", "components": [{"name": "x", "type": "uint256", "internalType": "uint256"}]}
+		]},
+		{"type": "function", "name": "b", "stateMutability": "view", "outputs": [], "inputs": [
+			{"name": "p", "type": "tuple", "internalType": "struct B.Point", "components": [{"name": "y", "type": "bool", "internalType": "bool"}]}
+		]}
+	]`
+	ABI, cABI, err := getABI([]byte(abiJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = generateSolidityLibrary(ABI, cABI, natSpec{}, Config{Name: "Points"})
+	if err == nil || !strings.Contains(err.Error(), "conflicting definitions of struct Point") {
+		t.Fatalf("expected a struct conflict, got %v", err)
+	}
+}
@@ -16,7 +16,10 @@
 package concrete
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete/api"
@@ -35,6 +38,12 @@ func RunPrecompile(p Precompile, env *api.Env, input []byte, gas uint64, value *
 	inputCopy := make([]byte, len(input))
 	copy(inputCopy, input)
 
+	if versioned, ok := p.(PrecompileAbiVersion); ok {
+		if err := env.SetAbiVersion(versioned.AbiVersion()); err != nil {
+			return nil, env.Gas(), err
+		}
+	}
+
 	static := env.Config().IsStatic
 	if static && !p.IsStatic(inputCopy) {
 		return nil, env.Gas(), api.ErrWriteProtection
@@ -82,6 +91,27 @@ func RunPrecompile(p Precompile, env *api.Env, input []byte, gas uint64, value *
 	return ret, env.Gas(), err
 }
 
+// PrecompileAbiVersion is optionally implemented by precompiles to run against
+// an ABI version other than api.AbiVersion1, like WASM modules do by exporting
+// concrete_AbiVersion.
+type PrecompileAbiVersion interface {
+	AbiVersion() api.AbiVersion
+}
+
+// PrecompileMetadata is optionally implemented by precompiles to describe
+// themselves to tooling, e.g. through the concrete RPC namespace.
+type PrecompileMetadata interface {
+	Name() string
+	Version() string
+	ABI() string // JSON ABI of the precompile
+}
+
+// PrecompileCode is implemented by precompiles that run code loaded at runtime,
+// like WASM precompiles.
+type PrecompileCode interface {
+	CodeHash() common.Hash
+}
+
 type PrecompileMap = map[common.Address]Precompile
 
 type PrecompileRegistry interface {
@@ -91,10 +121,57 @@ type PrecompileRegistry interface {
 	PrecompiledAddressesSet(blockNumber uint64) map[common.Address]struct{}
 }
 
+// DefaultPlaceholderCode is the INVALID opcode. Calling it directly as a
+// contract would fail, but it makes the address look like a deployed contract to
+// EXTCODESIZE, eth_getCode and ABI-based tooling.
+var DefaultPlaceholderCode = []byte{0xFE}
+
+// PrecompileLoader creates a precompile from code, like a WASM module. The
+// precompile runs under ctx and releases its resources once ctx is done.
+type PrecompileLoader func(ctx context.Context, code []byte) (Precompile, error)
+
+// LoaderRegistry is optionally implemented by registries that can create
+// precompiles from code, e.g. to override a precompile for a single call.
+type LoaderRegistry interface {
+	LoadPrecompile(ctx context.Context, code []byte) (Precompile, error)
+}
+
+var ErrNoLoader = errors.New("registry cannot load precompile code")
+
+// PlaceholderRegistry is optionally implemented by registries that install
+// placeholder code at the addresses of active precompiles. The code is installed
+// at the blocks where the set of precompiles changes.
+type PlaceholderRegistry interface {
+	PlaceholderCode() []byte
+	StartingBlocks() []uint64
+}
+
+// CapabilityMap maps precompile addresses to the system operations they are
+// allowed to execute.
+type CapabilityMap = map[common.Address]api.Capability
+
+// CapabilityRegistry is optionally implemented by registries that grant
+// capabilities to precompiles. Precompiles have no capabilities by default.
+type CapabilityRegistry interface {
+	Capabilities(blockNumber uint64) CapabilityMap
+}
+
+// RegistryCapabilities returns the capabilities granted by a registry to the
+// precompiles active at the given block, or nil if it does not grant any.
+func RegistryCapabilities(registry PrecompileRegistry, blockNumber uint64) CapabilityMap {
+	if registry, ok := registry.(CapabilityRegistry); ok {
+		return registry.Capabilities(blockNumber)
+	}
+	return nil
+}
+
 type GenericPrecompileRegistry struct {
-	startingBlocks []uint64
-	precompiles    []PrecompileMap
-	addresses      [][]common.Address
+	startingBlocks  []uint64
+	precompiles     []PrecompileMap
+	addresses       [][]common.Address
+	placeholderCode []byte
+	loader          PrecompileLoader
+	capabilities    []CapabilityMap
 }
 
 var _ PrecompileRegistry = (*GenericPrecompileRegistry)(nil)
@@ -104,6 +181,7 @@ func NewRegistry() *GenericPrecompileRegistry {
 		startingBlocks: make([]uint64, 0),
 		precompiles:    make([]PrecompileMap, 0),
 		addresses:      make([][]common.Address, 0),
+		capabilities:   make([]CapabilityMap, 0),
 	}
 }
 
@@ -122,14 +200,16 @@ func (c *GenericPrecompileRegistry) AddPrecompiles(startingBlock uint64, precomp
 		panic("precompiles already set for this block")
 	}
 	addresses := []common.Address{}
-	for address := range precompiles {
+	precompilesCopy := make(PrecompileMap, len(precompiles))
+	for address, pc := range precompiles {
 		addresses = append(addresses, address)
+		precompilesCopy[address] = pc
 	}
 
 	c.startingBlocks = insert(c.startingBlocks, idx+1, startingBlock)
-	c.precompiles = insert(c.precompiles, idx+1, precompiles)
+	c.precompiles = insert(c.precompiles, idx+1, precompilesCopy)
 	c.addresses = insert(c.addresses, idx+1, addresses)
-
+	c.capabilities = insert(c.capabilities, idx+1, CapabilityMap{})
 }
 
 func (c *GenericPrecompileRegistry) AddPrecompile(startingBlock uint64, address common.Address, precompile Precompile) {
@@ -146,6 +226,7 @@ func (c *GenericPrecompileRegistry) AddPrecompile(startingBlock uint64, address
 		c.startingBlocks = insert(c.startingBlocks, idx+1, startingBlock)
 		c.precompiles = insert(c.precompiles, idx+1, PrecompileMap{address: precompile})
 		c.addresses = insert(c.addresses, idx+1, []common.Address{address})
+		c.capabilities = insert(c.capabilities, idx+1, CapabilityMap{})
 	}
 }
 
@@ -166,7 +247,11 @@ func (c *GenericPrecompileRegistry) Precompiles(blockNumber uint64) PrecompileMa
 	if idx < 0 {
 		return PrecompileMap{}
 	}
-	return c.precompiles[idx]
+	precompiles := make(PrecompileMap, len(c.precompiles[idx]))
+	for address, pc := range c.precompiles[idx] {
+		precompiles[address] = pc
+	}
+	return precompiles
 }
 
 func (c *GenericPrecompileRegistry) PrecompiledAddresses(blockNumber uint64) []common.Address {
@@ -189,6 +274,173 @@ func (c *GenericPrecompileRegistry) PrecompiledAddressesSet(blockNumber uint64)
 	return set
 }
 
+// SetPlaceholderCode enables installing the given code at the address of every
+// precompile that has no code when its set becomes active, see
+// InstallPlaceholderCode. A nil or empty code disables it.
+// Note this modifies the state and hence the state root of the chain, so all
+// nodes of a network must agree on it. Enabling or disabling it on an existing
+// chain is a hard fork: blocks at past starting blocks no longer replay to the
+// same state root, so the chain can only be synced with the original setting.
+func (c *GenericPrecompileRegistry) SetPlaceholderCode(code []byte) {
+	c.placeholderCode = common.CopyBytes(code)
+}
+
+// PlaceholderCode returns the placeholder code installed at precompile
+// addresses, or nil if disabled.
+func (c *GenericPrecompileRegistry) PlaceholderCode() []byte {
+	return c.placeholderCode
+}
+
+// SetLoader sets the function used to create precompiles from code.
+func (c *GenericPrecompileRegistry) SetLoader(loader PrecompileLoader) {
+	c.loader = loader
+}
+
+// LoadPrecompile creates a precompile from code using the registry loader. It
+// returns ErrNoLoader if no loader is set.
+func (c *GenericPrecompileRegistry) LoadPrecompile(ctx context.Context, code []byte) (Precompile, error) {
+	if c.loader == nil {
+		return nil, ErrNoLoader
+	}
+	return c.loader(ctx, code)
+}
+
+// SetCapabilities grants the precompile at an address in the set starting at
+// the given block the given capabilities, replacing any previously granted.
+// Capabilities only apply until the next starting block, so a precompile set
+// at the same address later on has none unless granted again. It returns an
+// error if there is no precompile at the address in the set starting at the
+// given block.
+func (c *GenericPrecompileRegistry) SetCapabilities(startingBlock uint64, address common.Address, capabilities api.Capability) error {
+	idx := c.index(startingBlock)
+	if idx < 0 || c.startingBlocks[idx] != startingBlock {
+		return fmt.Errorf("no precompiles set for block %d", startingBlock)
+	}
+	if _, ok := c.precompiles[idx][address]; !ok {
+		return fmt.Errorf("no precompile set at address %s for block %d", address.Hex(), startingBlock)
+	}
+	if capabilities == api.CapabilityNone {
+		delete(c.capabilities[idx], address)
+		return nil
+	}
+	c.capabilities[idx][address] = capabilities
+	return nil
+}
+
+// Capabilities returns the capabilities granted to the precompiles active at
+// the given block by address.
+func (c *GenericPrecompileRegistry) Capabilities(blockNumber uint64) CapabilityMap {
+	idx := c.index(blockNumber)
+	if idx < 0 {
+		return CapabilityMap{}
+	}
+	capabilities := make(CapabilityMap, len(c.capabilities[idx]))
+	for address, capability := range c.capabilities[idx] {
+		capabilities[address] = capability
+	}
+	return capabilities
+}
+
+// PlaceholderState is the subset of the state database needed to install
+// placeholder code.
+type PlaceholderState interface {
+	GetCodeSize(common.Address) int
+	SetCode(common.Address, []byte)
+}
+
+// InstallPlaceholderCode sets the registry placeholder code at every precompile
+// active at the given block that has no code yet, if the block is one of the
+// registry starting blocks. The genesis state is not processed, so precompiles
+// active from genesis get their code at block 1. It does nothing if the
+// registry does not implement PlaceholderRegistry or has no placeholder code.
+// Code is never removed, so it remains after a precompile is deactivated.
+func InstallPlaceholderCode(registry PrecompileRegistry, state PlaceholderState, blockNumber uint64) {
+	placeholder, ok := registry.(PlaceholderRegistry)
+	if !ok {
+		return
+	}
+	code := placeholder.PlaceholderCode()
+	if len(code) == 0 || !isPlaceholderBlock(placeholder.StartingBlocks(), blockNumber) {
+		return
+	}
+	for _, address := range registry.PrecompiledAddresses(blockNumber) {
+		if state.GetCodeSize(address) == 0 {
+			state.SetCode(address, code)
+		}
+	}
+}
+
+// isPlaceholderBlock reports whether the block is the first processed block of
+// the set of precompiles starting at one of the given blocks.
+func isPlaceholderBlock(startingBlocks []uint64, blockNumber uint64) bool {
+	for _, block := range startingBlocks {
+		if block == blockNumber || (block == 0 && blockNumber == 1) {
+			return true
+		}
+	}
+	return false
+}
+
+// StartingBlocks returns the blocks at which the set of precompiles changes,
+// in ascending order.
+func (c *GenericPrecompileRegistry) StartingBlocks() []uint64 {
+	return append([]uint64{}, c.startingBlocks...)
+}
+
+// MergeRegistries returns a registry where the precompiles active at any block
+// are the union of the precompiles active at that block in each registry. It
+// returns an error if two registries set a precompile at the same address for
+// the same block. Precompiles keep the capabilities granted to them, and the
+// merged registry uses the placeholder code and loader of the first registry
+// that has one.
+func MergeRegistries(registries ...*GenericPrecompileRegistry) (*GenericPrecompileRegistry, error) {
+	blocks := make(map[uint64]struct{})
+	for _, registry := range registries {
+		for _, block := range registry.startingBlocks {
+			blocks[block] = struct{}{}
+		}
+	}
+	startingBlocks := make([]uint64, 0, len(blocks))
+	for block := range blocks {
+		startingBlocks = append(startingBlocks, block)
+	}
+	sort.Slice(startingBlocks, func(i, j int) bool { return startingBlocks[i] < startingBlocks[j] })
+
+	merged := NewRegistry()
+	for _, registry := range registries {
+		if len(registry.placeholderCode) > 0 {
+			merged.SetPlaceholderCode(registry.placeholderCode)
+			break
+		}
+	}
+	for _, registry := range registries {
+		if registry.loader != nil {
+			merged.SetLoader(registry.loader)
+			break
+		}
+	}
+	for _, block := range startingBlocks {
+		precompiles := PrecompileMap{}
+		for _, registry := range registries {
+			for address, pc := range registry.Precompiles(block) {
+				if _, ok := precompiles[address]; ok {
+					return nil, fmt.Errorf("precompile already set at address %s for block %d", address.Hex(), block)
+				}
+				precompiles[address] = pc
+			}
+		}
+		merged.AddPrecompiles(block, precompiles)
+		for _, registry := range registries {
+			for address, capabilities := range registry.Capabilities(block) {
+				if err := merged.SetCapabilities(block, address, capabilities); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return merged, nil
+}
+
 func insert[T any](slice []T, index int, value T) []T {
 	if index < 0 || index > len(slice) {
 		panic("index out of bounds")
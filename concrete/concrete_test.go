@@ -16,6 +16,7 @@
 package concrete
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -206,6 +207,125 @@ func TestPrecompileRegistry(t *testing.T) {
 			}
 		})
 	})
+	t.Run("Merge", func(t *testing.T) {
+		r := require.New(t)
+		a, b := NewRegistry(), NewRegistry()
+		a.AddPrecompile(0, addrIncl1, &pcBlank{})
+		a.AddPrecompiles(20, PrecompileMap{})
+		b.AddPrecompile(10, addrIncl2, &pcBlank{})
+
+		merged, err := MergeRegistries(a, b)
+		r.NoError(err)
+		r.Equal([]uint64{0, 10, 20}, merged.StartingBlocks())
+		r.Len(merged.Precompiles(0), 1)
+		r.Len(merged.Precompiles(15), 2)
+		r.Len(merged.Precompiles(25), 1)
+		_, ok := merged.Precompile(addrIncl2, 25)
+		r.True(ok)
+
+		b.AddPrecompile(0, addrIncl1, &pcBlank{})
+		_, err = MergeRegistries(a, b)
+		r.Error(err)
+	})
+	t.Run("PlaceholderCode", func(t *testing.T) {
+		r := require.New(t)
+		registry := NewRegistry()
+		registry.AddPrecompile(0, addrIncl1, &pcBlank{})
+		registry.AddPrecompile(0, addrIncl2, &pcBlank{})
+
+		state := make(placeholderState)
+		InstallPlaceholderCode(registry, state, 0)
+		r.Empty(state)
+
+		registry.SetPlaceholderCode(DefaultPlaceholderCode)
+		state[addrIncl2] = []byte{0x00}
+		InstallPlaceholderCode(registry, state, 0)
+		r.Equal(DefaultPlaceholderCode, state[addrIncl1])
+		r.Equal([]byte{0x00}, state[addrIncl2])
+
+		// Code is only installed at the starting blocks of the registry
+		registry.AddPrecompile(5, addrExcl, &pcBlank{})
+		InstallPlaceholderCode(registry, state, 4)
+		InstallPlaceholderCode(registry, state, 6)
+		r.NotContains(state, addrExcl)
+		InstallPlaceholderCode(registry, state, 5)
+		r.Equal(DefaultPlaceholderCode, state[addrExcl])
+
+		merged, err := MergeRegistries(NewRegistry(), registry)
+		r.NoError(err)
+		r.Equal(DefaultPlaceholderCode, merged.PlaceholderCode())
+	})
+	t.Run("Loader", func(t *testing.T) {
+		r := require.New(t)
+		registry := NewRegistry()
+		_, err := registry.LoadPrecompile(context.Background(), []byte{0x01})
+		r.ErrorIs(err, ErrNoLoader)
+
+		registry.SetLoader(func(ctx context.Context, code []byte) (Precompile, error) {
+			if len(code) == 0 {
+				return nil, errors.New("empty code")
+			}
+			return &pcBlank{}, nil
+		})
+		merged, err := MergeRegistries(registry, NewRegistry())
+		r.NoError(err)
+		pc, err := merged.LoadPrecompile(context.Background(), []byte{0x01})
+		r.NoError(err)
+		r.Equal(&pcBlank{}, pc)
+		_, err = merged.LoadPrecompile(context.Background(), nil)
+		r.Error(err)
+	})
+	t.Run("Capabilities", func(t *testing.T) {
+		r := require.New(t)
+		a, b := NewRegistry(), NewRegistry()
+		r.Nil(RegistryCapabilities(nil, 0))
+		r.Empty(RegistryCapabilities(a, 0))
+
+		a.AddPrecompiles(0, PrecompileMap{addrIncl1: &pcBlank{}})
+		a.AddPrecompiles(10, PrecompileMap{addrIncl1: &pcBlank{}})
+		b.AddPrecompiles(5, PrecompileMap{addrIncl2: &pcBlank{}, addrExcl: &pcBlank{}})
+		r.NoError(a.SetCapabilities(0, addrIncl1, api.CapabilityBalance|api.CapabilityNonce))
+		r.NoError(b.SetCapabilities(5, addrIncl2, api.CapabilityStorage))
+		r.NoError(b.SetCapabilities(5, addrExcl, api.CapabilityCode))
+		r.NoError(b.SetCapabilities(5, addrExcl, api.CapabilityNone))
+		r.Equal(CapabilityMap{addrIncl2: api.CapabilityStorage}, b.Capabilities(5))
+		r.Error(a.SetCapabilities(5, addrIncl1, api.CapabilityCode))
+		r.Error(a.SetCapabilities(0, addrIncl2, api.CapabilityCode))
+
+		// The returned precompiles and capabilities are copies
+		b.Capabilities(5)[addrIncl2] = api.CapabilityCode
+		r.Equal(CapabilityMap{addrIncl2: api.CapabilityStorage}, b.Capabilities(5))
+		a.Precompiles(0)[addrExcl] = &pcBlank{}
+		r.NotContains(a.Precompiles(0), addrExcl)
+
+		// Capabilities do not carry over to the precompile at the same address
+		// in a later set
+		r.Equal(CapabilityMap{addrIncl1: api.CapabilityBalance | api.CapabilityNonce}, a.Capabilities(9))
+		r.Empty(a.Capabilities(10))
+
+		merged, err := MergeRegistries(a, b)
+		r.NoError(err)
+		r.Equal(CapabilityMap{
+			addrIncl1: api.CapabilityBalance | api.CapabilityNonce,
+		}, RegistryCapabilities(merged, 0))
+		r.Equal(CapabilityMap{
+			addrIncl1: api.CapabilityBalance | api.CapabilityNonce,
+			addrIncl2: api.CapabilityStorage,
+		}, RegistryCapabilities(merged, 5))
+		r.Equal(CapabilityMap{
+			addrIncl2: api.CapabilityStorage,
+		}, RegistryCapabilities(merged, 10))
+	})
+}
+
+type placeholderState map[common.Address][]byte
+
+func (s placeholderState) GetCodeSize(address common.Address) int {
+	return len(s[address])
+}
+
+func (s placeholderState) SetCode(address common.Address, code []byte) {
+	s[address] = code
 }
 
 type testPrecompile struct {
@@ -223,6 +343,15 @@ func (pc *testPrecompile) Run(API api.Environment, input []byte) ([]byte, error)
 	return pc.runFn(API, input)
 }
 
+type versionedTestPrecompile struct {
+	testPrecompile
+	version api.AbiVersion
+}
+
+func (pc *versionedTestPrecompile) AbiVersion() api.AbiVersion {
+	return pc.version
+}
+
 func TestRunPrecompile(t *testing.T) {
 	t.Run("NoError", func(t *testing.T) {
 		pc := &testPrecompile{}
@@ -310,4 +439,26 @@ func TestRunPrecompile(t *testing.T) {
 		require.Nil(t, ret)
 		require.Equal(t, uint64(0), remainingGas)
 	})
+	t.Run("AbiVersion", func(t *testing.T) {
+		runFn := func(API api.Environment, input []byte) ([]byte, error) {
+			return API.GetChainID().Bytes(), nil
+		}
+		isStaticFn := func(input []byte) bool { return true }
+
+		// Native precompiles run against version 1 unless they opt in
+		env, _, _, _ := api.NewMockEnvironment(api.WithStatic(true))
+		pc := &testPrecompile{isStaticFn: isStaticFn, runFn: runFn}
+		_, _, err := RunPrecompile(pc, env, nil, 1234, uint256.NewInt(0))
+		require.ErrorIs(t, err, api.ErrInvalidOpCode)
+
+		env, _, _, _ = api.NewMockEnvironment(api.WithStatic(true))
+		versioned := &versionedTestPrecompile{testPrecompile: *pc, version: api.AbiVersion2}
+		_, _, err = RunPrecompile(versioned, env, nil, 1234, uint256.NewInt(0))
+		require.NoError(t, err)
+
+		env, _, _, _ = api.NewMockEnvironment(api.WithStatic(true))
+		versioned.version = api.LatestAbiVersion + 1
+		_, _, err = RunPrecompile(versioned, env, nil, 1234, uint256.NewInt(0))
+		require.ErrorIs(t, err, api.ErrUnsupportedAbiVersion)
+	})
 }
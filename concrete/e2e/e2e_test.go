@@ -317,3 +317,60 @@ func TestE2EKkvPrecompile(t *testing.T) {
 		})
 	}
 }
+
+func newDifferentialRunner(impls []pcImplementation, config wasm.DifferentialConfig) *wasm.DifferentialRunner {
+	var backends []wasm.Backend
+	for _, impl := range impls {
+		if impl.skip {
+			continue
+		}
+		backends = append(backends, wasm.Backend{Name: impl.name, Precompile: impl.newPc()})
+	}
+	return wasm.NewDifferentialRunner(config, backends...)
+}
+
+func FuzzAddDifferential(f *testing.F) {
+	ABI := getAddABI()
+	input, err := ABI.Pack("add", big.NewInt(1), big.NewInt(2))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(input)
+	f.Add([]byte{})
+	f.Add(AddMethodID)
+
+	runner := newDifferentialRunner(addImplementations, wasm.DifferentialConfig{
+		Address: common.BytesToAddress([]byte{130}),
+		Gas:     1e5,
+	})
+	f.Fuzz(func(t *testing.T, input []byte) {
+		if err := runner.Check(input); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func FuzzKkvDifferential(f *testing.F) {
+	ABI := getKkvABI()
+	k1, k2, v := common.HexToHash("0x01"), common.HexToHash("0x02"), common.HexToHash("0x03")
+	setInput, err := ABI.Pack("set", k1, k2, v)
+	if err != nil {
+		f.Fatal(err)
+	}
+	getInput, err := ABI.Pack("get", k1, k2)
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(setInput)
+	f.Add(getInput)
+
+	runner := newDifferentialRunner(kkvImplementations, wasm.DifferentialConfig{
+		Address: common.BytesToAddress([]byte{140}),
+		Gas:     1e5,
+	})
+	f.Fuzz(func(t *testing.T, input []byte) {
+		if err := runner.Check(input); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -55,6 +55,28 @@ func (kv *envStorageKV) Get(key common.Hash) common.Hash {
 
 var _ KeyValueStore = (*envStorageKV)(nil)
 
+type envExternalStorageKV struct {
+	env     api.Environment
+	address common.Address
+}
+
+// NewEnvExternalStorageKeyValueStore returns a read-only key-value store over
+// the storage of the account at the given address. Writing to it panics with
+// api.ErrWriteProtection. Reading requires api.AbiVersion2.
+func NewEnvExternalStorageKeyValueStore(env api.Environment, address common.Address) *envExternalStorageKV {
+	return &envExternalStorageKV{env: env, address: address}
+}
+
+func (kv *envExternalStorageKV) Set(key common.Hash, value common.Hash) {
+	panic(api.ErrWriteProtection)
+}
+
+func (kv *envExternalStorageKV) Get(key common.Hash) common.Hash {
+	return kv.env.GetExternalStorage(kv.address, key)
+}
+
+var _ KeyValueStore = (*envExternalStorageKV)(nil)
+
 type Datastore interface {
 	Get(key []byte) DatastoreSlot
 }
@@ -90,6 +112,14 @@ func NewStorageDatastore(env api.Environment) Datastore {
 	return newDatastore(kv)
 }
 
+// NewExternalStorageDatastore returns a read-only datastore over the storage
+// of the account at the given address, e.g. to read the state variables of a
+// Solidity contract. Reading requires api.AbiVersion2.
+func NewExternalStorageDatastore(env api.Environment, address common.Address) Datastore {
+	kv := NewEnvExternalStorageKeyValueStore(env, address)
+	return newDatastore(kv)
+}
+
 func NewDatastore(env api.Environment) Datastore {
 	return NewStorageDatastore(env)
 }
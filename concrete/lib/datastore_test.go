@@ -21,10 +21,12 @@
 package lib
 
 import (
+	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/crypto"
 	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
 )
@@ -63,6 +65,34 @@ func TestEnvKeyValueStore(t *testing.T) {
 	}
 }
 
+func TestExternalStorageDatastore(t *testing.T) {
+	var (
+		r        = require.New(t)
+		address  = common.HexToAddress("0xc0ffee0001")
+		token    = common.HexToAddress("0xc0ffee0002")
+		holder   = common.HexToAddress("0xc0ffee0003")
+		config   = api.EnvConfig{IsStatic: true}
+		meterGas = false
+		contract = api.NewContract(common.Address{}, common.Address{}, address, new(uint256.Int))
+	)
+	env, statedb, _, _ := api.NewMockEnvironment(api.WithAbiVersion(api.AbiVersion2), api.WithConfig(config), api.WithMeterGas(meterGas), api.WithContract(contract))
+
+	// Solidity layout of mapping(address => uint256) balances at slot 0
+	balanceSlot := crypto.Keccak256Hash(common.LeftPadBytes(holder.Bytes(), 32), common.Hash{}.Bytes())
+	statedb.SetState(token, balanceSlot, common.BigToHash(big.NewInt(42)))
+	statedb.SetState(token, common.Hash{0x01}, common.Hash{0x02})
+
+	ds := NewExternalStorageDatastore(env, token)
+	balances := ds.Get(common.Hash{}.Bytes()).Mapping()
+	r.Equal(uint256.NewInt(42), balances.Get(common.LeftPadBytes(holder.Bytes(), 32)).Uint256())
+	r.Equal(common.Hash{0x02}, ds.Get(common.Hash{0x01}.Bytes()).Bytes32())
+
+	r.PanicsWithError(api.ErrWriteProtection.Error(), func() {
+		ds.Get(common.Hash{0x01}.Bytes()).SetBytes32(common.Hash{0x03})
+	})
+	r.Equal(common.Hash{0x02}, statedb.GetState(token, common.Hash{0x01}))
+}
+
 func newSlot(keyStr string) (DatastoreSlot, common.Address, []byte) {
 	var (
 		address  = common.HexToAddress("0xc0ffee0001")
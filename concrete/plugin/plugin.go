@@ -0,0 +1,153 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package plugin loads Concrete precompiles from Go plugins.
+//
+// A plugin is a main package built with -buildmode=plugin that exports a
+// ConcretePrecompiles function returning the precompile sets it provides,
+// keyed by the block at which each set activates:
+//
+//	func ConcretePrecompiles() map[uint64]concrete.PrecompileMap
+//
+// Plugins must be built with the same Go version and the same version of this
+// module as the node loading them. Go plugins are only supported on Linux.
+package plugin
+
+import (
+	"debug/buildinfo"
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// ModulePath is the module plugins must be built against.
+	ModulePath = "github.com/ethereum/go-ethereum"
+	// ConstructorSymbol is the name of the function plugins must export.
+	ConstructorSymbol = "ConcretePrecompiles"
+)
+
+var (
+	ErrUnsupported        = errors.New("go plugins are not supported on this platform")
+	ErrIncompatiblePlugin = errors.New("incompatible plugin")
+	ErrInvalidConstructor = errors.New("invalid plugin constructor")
+	ErrMissingBuildInfo   = errors.New("missing build info")
+	ErrMissingModule      = errors.New("module not found in build info")
+)
+
+// Constructor is the type of the function exported by plugins.
+type Constructor = func() map[uint64]concrete.PrecompileMap
+
+// Load opens the plugins at the given paths and returns a registry with the
+// precompiles of all of them.
+func Load(paths ...string) (*concrete.GenericPrecompileRegistry, error) {
+	registries := make([]*concrete.GenericPrecompileRegistry, 0, len(paths))
+	for _, path := range paths {
+		registry, err := LoadPlugin(path)
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", path, err)
+		}
+		registries = append(registries, registry)
+	}
+	return concrete.MergeRegistries(registries...)
+}
+
+// LoadPlugin checks the build info of the plugin at the given path against the
+// host, opens it, and returns a registry with the precompiles it provides.
+func LoadPlugin(path string) (*concrete.GenericPrecompileRegistry, error) {
+	if err := CheckBuildInfo(path); err != nil {
+		return nil, err
+	}
+	constructor, err := open(path)
+	if err != nil {
+		return nil, err
+	}
+	sets := constructor()
+	registry := concrete.NewRegistry()
+	for startingBlock, precompiles := range sets {
+		registry.AddPrecompiles(startingBlock, precompiles)
+	}
+	log.Info("Loaded Concrete plugin", "path", path, "sets", len(sets))
+	return registry, nil
+}
+
+// CheckBuildInfo returns an error if the plugin at the given path was built with
+// a different Go version or module version than the running binary. Opening
+// such a plugin would fail anyway, possibly after running its init functions.
+func CheckBuildInfo(path string) error {
+	host, ok := debug.ReadBuildInfo()
+	if !ok {
+		return fmt.Errorf("host: %w", ErrMissingBuildInfo)
+	}
+	plugin, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMissingBuildInfo, err)
+	}
+	return checkCompatible(host, plugin)
+}
+
+func checkCompatible(host, plugin *debug.BuildInfo) error {
+	if host.GoVersion != plugin.GoVersion {
+		return fmt.Errorf("%w: built with %s, host built with %s", ErrIncompatiblePlugin, plugin.GoVersion, host.GoVersion)
+	}
+	hostVersion, err := moduleVersion(host)
+	if err != nil {
+		return fmt.Errorf("host: %w", err)
+	}
+	pluginVersion, err := moduleVersion(plugin)
+	if err != nil {
+		return err
+	}
+	if hostVersion == "" || pluginVersion == "" {
+		// Development builds and local replacements carry no version. Package
+		// hashes are still checked by the runtime when the plugin is opened.
+		return nil
+	}
+	if hostVersion != pluginVersion {
+		return fmt.Errorf("%w: built against %s %s, host uses %s", ErrIncompatiblePlugin, ModulePath, pluginVersion, hostVersion)
+	}
+	return nil
+}
+
+// moduleVersion returns the version of ModulePath used in a build, or an empty
+// string if the version is unknown.
+func moduleVersion(info *debug.BuildInfo) (string, error) {
+	if info.Main.Path == ModulePath {
+		return version(&info.Main), nil
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == ModulePath {
+			return version(dep), nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrMissingModule, ModulePath)
+}
+
+func version(mod *debug.Module) string {
+	if mod.Replace != nil {
+		if mod.Replace.Version == "" {
+			// Replaced by a local directory
+			return ""
+		}
+		return mod.Replace.Path + "@" + mod.Replace.Version
+	}
+	if mod.Version == "(devel)" {
+		return ""
+	}
+	return mod.Version
+}
@@ -0,0 +1,45 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux && cgo
+
+package plugin
+
+import (
+	"fmt"
+	goplugin "plugin"
+
+	"github.com/ethereum/go-ethereum/concrete"
+)
+
+func open(path string) (Constructor, error) {
+	p, err := goplugin.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	sym, err := p.Lookup(ConstructorSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidConstructor, err)
+	}
+	switch constructor := sym.(type) {
+	case func() map[uint64]concrete.PrecompileMap:
+		return constructor, nil
+	case *func() map[uint64]concrete.PrecompileMap:
+		// Exported as a variable
+		return *constructor, nil
+	default:
+		return nil, fmt.Errorf("%w: %s has type %T", ErrInvalidConstructor, ConstructorSymbol, sym)
+	}
+}
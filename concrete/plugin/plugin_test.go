@@ -0,0 +1,70 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func buildInfo(goVersion string, main debug.Module, deps ...*debug.Module) *debug.BuildInfo {
+	return &debug.BuildInfo{GoVersion: goVersion, Main: main, Deps: deps}
+}
+
+func TestCheckCompatible(t *testing.T) {
+	var (
+		geth      = func(version string) *debug.Module { return &debug.Module{Path: ModulePath, Version: version} }
+		host      = buildInfo("go1.21.0", debug.Module{Path: "example.com/node"}, geth("v1.13.15"))
+		devel     = buildInfo("go1.21.0", debug.Module{Path: ModulePath, Version: "(devel)"})
+		replaced  = &debug.Module{Path: ModulePath, Version: "v1.13.15", Replace: &debug.Module{Path: "example.com/fork", Version: "v0.1.0"}}
+		localRepl = &debug.Module{Path: ModulePath, Version: "v1.13.15", Replace: &debug.Module{Path: "../geth"}}
+	)
+	tests := []struct {
+		name   string
+		host   *debug.BuildInfo
+		plugin *debug.BuildInfo
+		err    error
+	}{
+		{"same", host, buildInfo("go1.21.0", debug.Module{Path: "example.com/plugin"}, geth("v1.13.15")), nil},
+		{"go version", host, buildInfo("go1.21.1", debug.Module{Path: "example.com/plugin"}, geth("v1.13.15")), ErrIncompatiblePlugin},
+		{"module version", host, buildInfo("go1.21.0", debug.Module{Path: "example.com/plugin"}, geth("v1.13.14")), ErrIncompatiblePlugin},
+		{"missing module", host, buildInfo("go1.21.0", debug.Module{Path: "example.com/plugin"}), ErrMissingModule},
+		{"replaced", host, buildInfo("go1.21.0", debug.Module{Path: "example.com/plugin"}, replaced), ErrIncompatiblePlugin},
+		{"local replace", host, buildInfo("go1.21.0", debug.Module{Path: "example.com/plugin"}, localRepl), nil},
+		{"devel", devel, buildInfo("go1.21.0", debug.Module{Path: "example.com/plugin"}, geth("v1.13.15")), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCompatible(tt.host, tt.plugin)
+			if tt.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestLoadInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin.so")
+	require.NoError(t, os.WriteFile(path, []byte("not a plugin"), 0644))
+	_, err := Load(path)
+	require.ErrorIs(t, err, ErrMissingBuildInfo)
+}
@@ -0,0 +1,252 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+)
+
+var bigIntType = reflect.TypeOf(new(big.Int))
+
+// argName returns the name of an argument, or _i for the unnamed argument at
+// position i.
+func argName(arg abi.Argument, i int) string {
+	if arg.Name == "" {
+		return fmt.Sprintf("_%d", i)
+	}
+	return arg.Name
+}
+
+// packArgs encodes the call of a method with JSON arguments given by name.
+func packArgs(method abi.Method, args map[string]json.RawMessage) ([]byte, error) {
+	values := make([]interface{}, len(method.Inputs))
+	for i, input := range method.Inputs {
+		name := argName(input, i)
+		raw, ok := args[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrMissingArgument, name)
+		}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("argument %s: %w", name, err)
+		}
+		converted, err := toABIValue(input.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("argument %s: %w", name, err)
+		}
+		values[i] = converted.Interface()
+	}
+	if len(args) > len(method.Inputs) {
+		return nil, fmt.Errorf("too many arguments for %s", method.Sig)
+	}
+	input, err := method.Inputs.Pack(values...)
+	if err != nil {
+		return nil, err
+	}
+	return append(method.ID, input...), nil
+}
+
+func parseInteger(value interface{}) (*big.Int, error) {
+	var str string
+	switch v := value.(type) {
+	case json.Number:
+		str = v.String()
+	case string:
+		str = v
+	default:
+		return nil, fmt.Errorf("expected a number, got %v", value)
+	}
+	n, ok := math.ParseBig256(str)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %s", str)
+	}
+	return n, nil
+}
+
+func parseHex(value interface{}) ([]byte, error) {
+	str, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a hex string, got %v", value)
+	}
+	return hexutil.Decode(str)
+}
+
+// toABIValue converts a JSON value to the go value the ABI encoder expects for
+// the given type. Integers can be numbers or decimal or 0x-prefixed hex strings,
+// bytes are hex strings and tuples are objects by component name.
+func toABIValue(typ abi.Type, value interface{}) (reflect.Value, error) {
+	goType := typ.GetType()
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		n, err := parseInteger(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		// Unsigned values must be in [0, 2^size), signed in [-2^(size-1), 2^(size-1))
+		limit := new(big.Int).Lsh(big.NewInt(1), uint(typ.Size))
+		min := new(big.Int)
+		if typ.T == abi.IntTy {
+			limit.Rsh(limit, 1)
+			min.Neg(limit)
+		}
+		if n.Cmp(min) < 0 || n.Cmp(limit) >= 0 {
+			return reflect.Value{}, fmt.Errorf("value %s out of range for %s", n, typ)
+		}
+		if goType == bigIntType {
+			return reflect.ValueOf(n), nil
+		}
+		v := reflect.New(goType).Elem()
+		if typ.T == abi.UintTy {
+			v.SetUint(n.Uint64())
+		} else {
+			v.SetInt(n.Int64())
+		}
+		return v, nil
+	case abi.BoolTy:
+		b, ok := value.(bool)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected a bool, got %v", value)
+		}
+		return reflect.ValueOf(b), nil
+	case abi.StringTy:
+		str, ok := value.(string)
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected a string, got %v", value)
+		}
+		return reflect.ValueOf(str), nil
+	case abi.AddressTy:
+		str, ok := value.(string)
+		if !ok || !common.IsHexAddress(str) {
+			return reflect.Value{}, fmt.Errorf("invalid address %v", value)
+		}
+		return reflect.ValueOf(common.HexToAddress(str)), nil
+	case abi.BytesTy:
+		b, err := parseHex(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(b), nil
+	case abi.FixedBytesTy:
+		b, err := parseHex(value)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if len(b) != typ.Size {
+			return reflect.Value{}, fmt.Errorf("expected %d bytes, got %d", typ.Size, len(b))
+		}
+		v := reflect.New(goType).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v, nil
+	case abi.SliceTy, abi.ArrayTy:
+		elems, ok := value.([]interface{})
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected an array, got %v", value)
+		}
+		var v reflect.Value
+		if typ.T == abi.SliceTy {
+			v = reflect.MakeSlice(goType, len(elems), len(elems))
+		} else if len(elems) != typ.Size {
+			return reflect.Value{}, fmt.Errorf("expected %d elements, got %d", typ.Size, len(elems))
+		} else {
+			v = reflect.New(goType).Elem()
+		}
+		for i, elem := range elems {
+			ev, err := toABIValue(*typ.Elem, elem)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("element %d: %w", i, err)
+			}
+			v.Index(i).Set(ev)
+		}
+		return v, nil
+	case abi.TupleTy:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return reflect.Value{}, fmt.Errorf("expected an object, got %v", value)
+		}
+		v := reflect.New(goType).Elem()
+		for i, elem := range typ.TupleElems {
+			name := typ.TupleRawNames[i]
+			field, ok := fields[name]
+			if !ok {
+				return reflect.Value{}, fmt.Errorf("%w: %s", ErrMissingArgument, name)
+			}
+			fv, err := toABIValue(*elem, field)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %w", name, err)
+			}
+			v.Field(i).Set(fv)
+		}
+		return v, nil
+	}
+	return reflect.Value{}, fmt.Errorf("unsupported type %s", typ)
+}
+
+// unpackResults decodes the output of a method call into JSON values by
+// output name.
+func unpackResults(method abi.Method, output []byte) (map[string]interface{}, error) {
+	values, err := method.Outputs.Unpack(output)
+	if err != nil {
+		return nil, err
+	}
+	results := make(map[string]interface{}, len(values))
+	for i, value := range values {
+		output := method.Outputs[i]
+		results[argName(output, i)] = fromABIValue(output.Type, reflect.ValueOf(value))
+	}
+	return results, nil
+}
+
+// fromABIValue converts a decoded ABI value to a JSON value in the format
+// toABIValue accepts, with integers as decimal strings.
+func fromABIValue(typ abi.Type, v reflect.Value) interface{} {
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		if v.Type() == bigIntType {
+			return v.Interface().(*big.Int).String()
+		}
+		return fmt.Sprint(v.Interface())
+	case abi.BytesTy:
+		return hexutil.Bytes(v.Bytes())
+	case abi.FixedBytesTy:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return hexutil.Bytes(b)
+	case abi.SliceTy, abi.ArrayTy:
+		elems := make([]interface{}, v.Len())
+		for i := range elems {
+			elems[i] = fromABIValue(*typ.Elem, v.Index(i))
+		}
+		return elems
+	case abi.TupleTy:
+		fields := make(map[string]interface{}, len(typ.TupleElems))
+		for i, elem := range typ.TupleElems {
+			fields[typ.TupleRawNames[i]] = fromABIValue(*elem, v.Field(i))
+		}
+		return fields
+	}
+	return v.Interface()
+}
@@ -0,0 +1,365 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/concrete"
+	cc_api "github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	ErrNoRegistry      = errors.New("no concrete precompile registry set")
+	ErrNoEpochs        = errors.New("registry does not expose its starting blocks")
+	ErrNoABI           = errors.New("precompile does not provide an ABI")
+	ErrMethodNotFound  = errors.New("method not found")
+	ErrMissingArgument = errors.New("missing argument")
+)
+
+// NewConcreteAPI returns the default concrete RPC namespace, which exposes the
+// precompile registry of the node.
+func NewConcreteAPI(eth *eth.Ethereum) rpc.API {
+	return rpc.API{
+		Namespace: "concrete",
+		Service:   NewPrecompileAPI(eth.APIBackend),
+	}
+}
+
+// epochRegistry is implemented by registries that can list the blocks their
+// precompile sets start at, like concrete.GenericPrecompileRegistry.
+type epochRegistry interface {
+	StartingBlocks() []uint64
+}
+
+// PrecompileAPI provides introspection of the concrete precompiles of a node.
+type PrecompileAPI struct {
+	b ethapi.Backend
+}
+
+func NewPrecompileAPI(b ethapi.Backend) *PrecompileAPI {
+	return &PrecompileAPI{b: b}
+}
+
+// Epoch is a range of blocks with the same set of active precompiles.
+type Epoch struct {
+	Block       hexutil.Uint64   `json:"block"`
+	Precompiles []common.Address `json:"precompiles"`
+	Added       []common.Address `json:"added"`
+	Removed     []common.Address `json:"removed"`
+}
+
+// Activation is a range of blocks a precompile is active in. Until is the
+// first block the precompile is no longer active at, nil if it stays active.
+type Activation struct {
+	From  hexutil.Uint64  `json:"from"`
+	Until *hexutil.Uint64 `json:"until"`
+}
+
+// PrecompileInfo describes the precompile at an address at some block.
+type PrecompileInfo struct {
+	Address      common.Address  `json:"address"`
+	Active       bool            `json:"active"`
+	Name         string          `json:"name,omitempty"`
+	Version      string          `json:"version,omitempty"`
+	ABI          json.RawMessage `json:"abi,omitempty"`
+	CodeHash     *common.Hash    `json:"codeHash,omitempty"`
+	Capabilities []string        `json:"capabilities"`
+	Activations  []Activation    `json:"activations"`
+}
+
+// CallArgs are the arguments of concrete_call. Method is the name or the
+// signature of the method, Args its arguments by name.
+type CallArgs struct {
+	From   *common.Address            `json:"from"`
+	Method string                     `json:"method"`
+	Args   map[string]json.RawMessage `json:"args"`
+	Gas    *hexutil.Uint64            `json:"gas"`
+	Value  *hexutil.Big               `json:"value"`
+}
+
+// CallResult is the result of concrete_call, with the outputs of the method
+// by name, or by position as _0, _1... for unnamed outputs.
+type CallResult struct {
+	Output  hexutil.Bytes          `json:"output"`
+	Results map[string]interface{} `json:"results"`
+	GasUsed hexutil.Uint64         `json:"gasUsed"`
+	Debug   []cc_api.DebugMessage  `json:"debug,omitempty"`
+}
+
+func (api *PrecompileAPI) registry() (concrete.PrecompileRegistry, error) {
+	registry := api.b.Concrete()
+	if registry == nil {
+		return nil, ErrNoRegistry
+	}
+	return registry, nil
+}
+
+func (api *PrecompileAPI) blockNumber(ctx context.Context, blockNrOrHash *rpc.BlockNumberOrHash) (uint64, error) {
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	header, err := api.b.HeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, errors.New("header not found")
+	}
+	return header.Number.Uint64(), nil
+}
+
+func sortAddresses(addresses []common.Address) []common.Address {
+	sort.Slice(addresses, func(i, j int) bool {
+		return bytes.Compare(addresses[i].Bytes(), addresses[j].Bytes()) < 0
+	})
+	return addresses
+}
+
+// Precompiles returns the addresses of the precompiles active at the given
+// block, the latest if not given.
+func (api *PrecompileAPI) Precompiles(ctx context.Context, blockNrOrHash *rpc.BlockNumberOrHash) ([]common.Address, error) {
+	registry, err := api.registry()
+	if err != nil {
+		return nil, err
+	}
+	number, err := api.blockNumber(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	addresses := append([]common.Address{}, registry.PrecompiledAddresses(number)...)
+	return sortAddresses(addresses), nil
+}
+
+// Epochs returns the blocks the set of active precompiles changes at, with the
+// precompiles added and removed at each of them.
+func (api *PrecompileAPI) Epochs() ([]Epoch, error) {
+	registry, err := api.registry()
+	if err != nil {
+		return nil, err
+	}
+	epochs, ok := registry.(epochRegistry)
+	if !ok {
+		return nil, ErrNoEpochs
+	}
+	var (
+		result = make([]Epoch, 0)
+		prev   = make(map[common.Address]struct{})
+	)
+	for _, block := range epochs.StartingBlocks() {
+		set := registry.PrecompiledAddressesSet(block)
+		epoch := Epoch{
+			Block:       hexutil.Uint64(block),
+			Precompiles: make([]common.Address, 0, len(set)),
+			Added:       make([]common.Address, 0),
+			Removed:     make([]common.Address, 0),
+		}
+		for address := range set {
+			epoch.Precompiles = append(epoch.Precompiles, address)
+			if _, ok := prev[address]; !ok {
+				epoch.Added = append(epoch.Added, address)
+			}
+		}
+		for address := range prev {
+			if _, ok := set[address]; !ok {
+				epoch.Removed = append(epoch.Removed, address)
+			}
+		}
+		sortAddresses(epoch.Precompiles)
+		sortAddresses(epoch.Added)
+		sortAddresses(epoch.Removed)
+		result = append(result, epoch)
+		prev = set
+	}
+	return result, nil
+}
+
+// activations returns the block ranges the precompile at the given address is
+// active in.
+func (api *PrecompileAPI) activations(address common.Address) ([]Activation, error) {
+	epochs, err := api.Epochs()
+	if err != nil {
+		return nil, err
+	}
+	activations := make([]Activation, 0)
+	for _, epoch := range epochs {
+		for _, added := range epoch.Added {
+			if added == address {
+				activations = append(activations, Activation{From: epoch.Block})
+			}
+		}
+		for _, removed := range epoch.Removed {
+			if removed == address {
+				until := epoch.Block
+				activations[len(activations)-1].Until = &until
+			}
+		}
+	}
+	return activations, nil
+}
+
+// Metadata describes the precompile at the given address and block, the latest
+// if not given. Name, version and ABI are only set for precompiles that
+// implement concrete.PrecompileMetadata.
+func (api *PrecompileAPI) Metadata(ctx context.Context, address common.Address, blockNrOrHash *rpc.BlockNumberOrHash) (*PrecompileInfo, error) {
+	registry, err := api.registry()
+	if err != nil {
+		return nil, err
+	}
+	number, err := api.blockNumber(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	info := &PrecompileInfo{Address: address}
+	if info.Activations, err = api.activations(address); errors.Is(err, ErrNoEpochs) {
+		info.Activations = nil
+	} else if err != nil {
+		return nil, err
+	}
+	pc, ok := registry.Precompile(address, number)
+	if !ok {
+		return info, nil
+	}
+	info.Active = true
+	info.Capabilities = concrete.RegistryCapabilities(registry, number)[address].Names()
+	if metadata, ok := pc.(concrete.PrecompileMetadata); ok {
+		info.Name = metadata.Name()
+		info.Version = metadata.Version()
+		if abiJSON := metadata.ABI(); abiJSON != "" {
+			info.ABI = json.RawMessage(abiJSON)
+		}
+	}
+	if code, ok := pc.(concrete.PrecompileCode); ok {
+		codeHash := code.CodeHash()
+		info.CodeHash = &codeHash
+	}
+	return info, nil
+}
+
+// Call calls a method of the precompile at the given address, encoding the
+// arguments and decoding the results with the ABI of the precompile.
+func (api *PrecompileAPI) Call(ctx context.Context, address common.Address, args CallArgs, blockNrOrHash *rpc.BlockNumberOrHash) (*CallResult, error) {
+	registry, err := api.registry()
+	if err != nil {
+		return nil, err
+	}
+	number, err := api.blockNumber(ctx, blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	pc, ok := registry.Precompile(address, number)
+	if !ok {
+		return nil, fmt.Errorf("no precompile at %s at block %d", address.Hex(), number)
+	}
+	metadata, ok := pc.(concrete.PrecompileMetadata)
+	if !ok || metadata.ABI() == "" {
+		return nil, ErrNoABI
+	}
+	ABI, err := abi.JSON(bytes.NewReader([]byte(metadata.ABI())))
+	if err != nil {
+		return nil, fmt.Errorf("invalid precompile ABI: %w", err)
+	}
+	method, err := findMethod(ABI, args.Method)
+	if err != nil {
+		return nil, err
+	}
+	input, err := packArgs(method, args.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	if blockNrOrHash == nil {
+		blockNrOrHash = new(rpc.BlockNumberOrHash)
+		*blockNrOrHash = rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number))
+	}
+	data := hexutil.Bytes(input)
+	txArgs := ethapi.TransactionArgs{
+		From:  args.From,
+		To:    &address,
+		Gas:   args.Gas,
+		Value: args.Value,
+		Input: &data,
+	}
+	debug := &cc_api.DebugCollector{}
+	ctx = ethapi.WithConcreteDebug(ctx, debug)
+	result, err := ethapi.DoCall(ctx, api.b, txArgs, *blockNrOrHash, nil, nil, api.b.RPCEVMTimeout(), api.b.RPCGasCap())
+	if err != nil {
+		return nil, err
+	}
+	if result.Failed() {
+		if reason := revertReason(result.Revert()); reason != "" {
+			return nil, fmt.Errorf("%w: %s", result.Err, reason)
+		}
+		return nil, result.Err
+	}
+	results, err := unpackResults(method, result.ReturnData)
+	if err != nil {
+		return nil, err
+	}
+	return &CallResult{
+		Output:  result.ReturnData,
+		Results: results,
+		GasUsed: hexutil.Uint64(result.UsedGas),
+		Debug:   debug.Messages,
+	}, nil
+}
+
+// findMethod returns the method with the given signature, or with the given
+// name if it is not overloaded.
+func findMethod(ABI abi.ABI, name string) (abi.Method, error) {
+	var found []abi.Method
+	for _, method := range ABI.Methods {
+		if method.Sig == name {
+			return method, nil
+		}
+		if method.RawName == name {
+			found = append(found, method)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return abi.Method{}, fmt.Errorf("%w: %s", ErrMethodNotFound, name)
+	case 1:
+		return found[0], nil
+	}
+	return abi.Method{}, fmt.Errorf("method %s is overloaded, use its signature", name)
+}
+
+// revertReason decodes the revert data of a call, either a solidity error
+// string or the raw reason returned by a concrete precompile.
+func revertReason(data []byte) string {
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason
+	}
+	for _, c := range data {
+		if c < 0x20 || c > 0x7e {
+			return hexutil.Encode(data)
+		}
+	}
+	return string(data)
+}
@@ -0,0 +1,216 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+const adderABI = `[
+	{"type": "function", "name": "add", "stateMutability": "pure",
+		"inputs": [{"name": "a", "type": "uint256"}, {"name": "b", "type": "int8"}],
+		"outputs": [{"name": "sum", "type": "uint256"}]},
+	{"type": "function", "name": "sum", "stateMutability": "pure",
+		"inputs": [{"name": "items", "type": "tuple[]", "components": [{"name": "value", "type": "uint64"}, {"name": "tag", "type": "bytes4"}]}],
+		"outputs": [{"name": "", "type": "uint256"}, {"name": "", "type": "bytes4[]"}]}
+]`
+
+// adderPrecompile adds numbers and describes itself with PrecompileMetadata.
+type adderPrecompile struct {
+	abi abi.ABI
+}
+
+func newAdderPrecompile() *adderPrecompile {
+	ABI, err := abi.JSON(bytes.NewReader([]byte(adderABI)))
+	if err != nil {
+		panic(err)
+	}
+	return &adderPrecompile{abi: ABI}
+}
+
+func (pc *adderPrecompile) Name() string    { return "Adder" }
+func (pc *adderPrecompile) Version() string { return "1.0.0" }
+func (pc *adderPrecompile) ABI() string     { return adderABI }
+
+func (pc *adderPrecompile) IsStatic(input []byte) bool { return true }
+
+func (pc *adderPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	method, err := pc.abi.MethodById(input)
+	if err != nil {
+		return nil, err
+	}
+	args, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return nil, err
+	}
+	switch method.Name {
+	case "add":
+		env.Debug("adding")
+		sum := new(big.Int).Add(args[0].(*big.Int), big.NewInt(int64(args[1].(int8))))
+		if sum.Sign() < 0 {
+			return nil, errors.New("negative sum")
+		}
+		return method.Outputs.Pack(sum)
+	default:
+		items := args[0].([]struct {
+			Value uint64  `json:"value"`
+			Tag   [4]byte `json:"tag"`
+		})
+		sum := new(big.Int)
+		tags := make([][4]byte, len(items))
+		for i, item := range items {
+			sum.Add(sum, new(big.Int).SetUint64(item.Value))
+			tags[i] = item.Tag
+		}
+		return method.Outputs.Pack(sum, tags)
+	}
+}
+
+type blankPrecompile struct{}
+
+func (pc *blankPrecompile) IsStatic(input []byte) bool { return true }
+
+func (pc *blankPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	return nil, nil
+}
+
+var (
+	adderAddress = common.HexToAddress("0x80")
+	blankAddress = common.HexToAddress("0x81")
+)
+
+func newTestClient(t *testing.T) *rpc.Client {
+	stack, err := node.New(&node.Config{})
+	require.NoError(t, err)
+	t.Cleanup(func() { stack.Close() })
+	ethservice, err := eth.New(stack, &ethconfig.Config{Genesis: &core.Genesis{Config: params.AllEthashProtocolChanges}})
+	require.NoError(t, err)
+
+	// The adder is active from genesis to block 10, the blank precompile from
+	// block 5 on
+	registry := concrete.NewRegistry()
+	adder := newAdderPrecompile()
+	registry.AddPrecompiles(0, concrete.PrecompileMap{adderAddress: adder})
+	registry.AddPrecompiles(5, concrete.PrecompileMap{adderAddress: adder, blankAddress: &blankPrecompile{}})
+	registry.AddPrecompiles(10, concrete.PrecompileMap{blankAddress: &blankPrecompile{}})
+	require.NoError(t, registry.SetCapabilities(0, adderAddress, api.CapabilityStorage))
+	ethservice.APIBackend.SetConcrete(registry)
+
+	stack.RegisterAPIs([]rpc.API{NewConcreteAPI(ethservice)})
+	require.NoError(t, stack.Start())
+	return stack.Attach()
+}
+
+func TestPrecompiles(t *testing.T) {
+	client := newTestClient(t)
+
+	var addresses []common.Address
+	require.NoError(t, client.Call(&addresses, "concrete_precompiles", "latest"))
+	require.Equal(t, []common.Address{adderAddress}, addresses)
+
+	var epochs []Epoch
+	require.NoError(t, client.Call(&epochs, "concrete_epochs"))
+	require.Equal(t, []Epoch{
+		{Block: 0, Precompiles: []common.Address{adderAddress}, Added: []common.Address{adderAddress}, Removed: []common.Address{}},
+		{Block: 5, Precompiles: []common.Address{adderAddress, blankAddress}, Added: []common.Address{blankAddress}, Removed: []common.Address{}},
+		{Block: 10, Precompiles: []common.Address{blankAddress}, Added: []common.Address{}, Removed: []common.Address{adderAddress}},
+	}, epochs)
+}
+
+func TestMetadata(t *testing.T) {
+	client := newTestClient(t)
+
+	var info PrecompileInfo
+	require.NoError(t, client.Call(&info, "concrete_metadata", adderAddress, "latest"))
+	require.True(t, info.Active)
+	require.Equal(t, []string{"storage"}, info.Capabilities)
+	require.Equal(t, "Adder", info.Name)
+	require.Equal(t, "1.0.0", info.Version)
+	require.JSONEq(t, adderABI, string(info.ABI))
+	require.Nil(t, info.CodeHash)
+	until := hexutil.Uint64(10)
+	require.Equal(t, []Activation{{From: 0, Until: &until}}, info.Activations)
+
+	// Inactive at the latest block, but with known activations
+	info = PrecompileInfo{}
+	require.NoError(t, client.Call(&info, "concrete_metadata", blankAddress, "latest"))
+	require.False(t, info.Active)
+	require.Empty(t, info.Name)
+	require.Equal(t, []Activation{{From: 5}}, info.Activations)
+}
+
+func TestCall(t *testing.T) {
+	client := newTestClient(t)
+
+	call := func(method string, args map[string]interface{}) (*CallResult, error) {
+		rawArgs := make(map[string]json.RawMessage)
+		for name, arg := range args {
+			raw, err := json.Marshal(arg)
+			require.NoError(t, err)
+			rawArgs[name] = raw
+		}
+		var result *CallResult
+		err := client.Call(&result, "concrete_call", adderAddress, CallArgs{Method: method, Args: rawArgs}, "latest")
+		return result, err
+	}
+
+	result, err := call("add", map[string]interface{}{"a": "0xff", "b": -5})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"sum": "250"}, result.Results)
+	require.NotZero(t, result.GasUsed)
+	require.Equal(t, []api.DebugMessage{{Address: adderAddress, Message: "adding"}}, result.Debug)
+
+	result, err = call("sum((uint64,bytes4)[])", map[string]interface{}{"items": []map[string]interface{}{
+		{"value": 1, "tag": "0x01020304"},
+		{"value": "2", "tag": "0xaabbccdd"},
+	}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]interface{}{"_0": "3", "_1": []interface{}{"0x01020304", "0xaabbccdd"}}, result.Results)
+	require.Empty(t, result.Debug)
+
+	_, err = call("add", map[string]interface{}{"a": "1", "b": -5})
+	require.ErrorContains(t, err, "execution reverted: negative sum")
+
+	_, err = call("add", map[string]interface{}{"a": "1", "b": 128})
+	require.ErrorContains(t, err, "out of range for int8")
+
+	_, err = call("add", map[string]interface{}{"a": "1"})
+	require.ErrorContains(t, err, "missing argument: b")
+
+	_, err = call("sub", nil)
+	require.ErrorContains(t, err, "method not found")
+
+	var res *CallResult
+	err = client.Call(&res, "concrete_call", blankAddress, CallArgs{Method: "add"}, "latest")
+	require.ErrorContains(t, err, "no precompile at")
+}
@@ -0,0 +1,224 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+)
+
+const blockGasLimit = 2e9
+
+// testChain is the chain tests run in: a parent block, the genesis block or a
+// block of a forked chain, followed by a block with the setUp call and the
+// calls of a test.
+type testChain struct {
+	engine    consensus.Engine
+	registry  concrete.PrecompileRegistry
+	getHeader func(hash common.Hash, number uint64) *types.Header
+}
+
+func (c *testChain) Engine() consensus.Engine {
+	return c.engine
+}
+
+func (c *testChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return c.getHeader(hash, number)
+}
+
+func (c *testChain) Concrete() concrete.PrecompileRegistry {
+	return c.registry
+}
+
+var _ core.ChainContext = (*testChain)(nil)
+
+// testAlloc returns the accounts tests need: the sender of the test calls, the
+// test contract and the cheatcodes.
+func testAlloc(bytecode []byte) types.GenesisAlloc {
+	return types.GenesisAlloc{
+		senderAddress:   {Balance: big.NewInt(1e18)},
+		contractAddress: {Balance: common.Big0, Code: bytecode},
+		// Code is set so Solidity's extcodesize checks pass
+		HEVMAddress: {Balance: common.Big0, Code: []byte{0x01}},
+	}
+}
+
+// newTestHeader returns the header of the block tests run in.
+func newTestHeader(config *params.ChainConfig, parent *types.Header) *types.Header {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Coinbase:   parent.Coinbase,
+		GasLimit:   parent.GasLimit,
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		Time:       parent.Time + 10, // Block time is fixed at 10 seconds
+		MixDigest:  parent.MixDigest,
+	}
+	if parent.Difficulty.Sign() == 0 {
+		// Post-merge chain
+		header.Difficulty = new(big.Int)
+	} else {
+		header.Difficulty = ethash.CalcDifficulty(config, header.Time, parent)
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(config, parent, header.Time)
+	}
+	if config.IsCancun(header.Number, header.Time) {
+		var parentExcessBlobGas, parentBlobGasUsed uint64
+		if parent.ExcessBlobGas != nil {
+			parentExcessBlobGas = *parent.ExcessBlobGas
+			parentBlobGasUsed = *parent.BlobGasUsed
+		}
+		excessBlobGas := eip4844.CalcExcessBlobGas(parentExcessBlobGas, parentBlobGasUsed)
+		header.ExcessBlobGas = &excessBlobGas
+		header.BlobGasUsed = new(uint64)
+		header.ParentBeaconRoot = new(common.Hash)
+	}
+	return header
+}
+
+// setupState is the state of a test contract after setUp, which every test of
+// the contract starts from.
+type setupState struct {
+	config     *params.ChainConfig
+	header     *types.Header // Header of the block the tests run in
+	getHeader  func(hash common.Hash, number uint64) *types.Header
+	statedb    *state.StateDB
+	cheatcodes *Cheatcodes
+	output     []byte
+	usedGas    uint64
+	lock       sync.Mutex // Protects statedb while it is copied
+}
+
+// copy returns a copy of the state after setUp for a test to run in.
+func (s *setupState) copy() (*state.StateDB, *Cheatcodes) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.statedb.Copy(), s.cheatcodes.copy()
+}
+
+// setUp deploys the test contract and calls setUp. The result is cached, so
+// setUp runs once per test contract.
+func (r *runner) setUp(bytecode []byte) (*setupState, error) {
+	codeHash := crypto.Keccak256Hash(bytecode)
+	r.setupsLock.Lock()
+	defer r.setupsLock.Unlock()
+	if setup, ok := r.setups[codeHash]; ok {
+		return setup, nil
+	}
+	var (
+		setup *setupState
+		err   error
+	)
+	if r.fork != nil {
+		setup, err = r.fork.newSetupState(bytecode)
+	} else {
+		setup, err = newGenesisSetupState(bytecode)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.callSetUp(setup); err != nil {
+		return nil, err
+	}
+	r.setups[codeHash] = setup
+	return setup, nil
+}
+
+// newGenesisSetupState returns the state of a new chain with the test contract
+// deployed in its genesis block.
+func newGenesisSetupState(bytecode []byte) (*setupState, error) {
+	var (
+		gspec = &core.Genesis{
+			GasLimit: blockGasLimit,
+			Config:   params.TestChainConfig,
+			Alloc:    testAlloc(bytecode),
+		}
+		db      = rawdb.NewMemoryDatabase()
+		tdb     = triedb.NewDatabase(db, triedb.HashDefaults)
+		genesis = gspec.MustCommit(db, tdb)
+	)
+	statedb, err := state.New(genesis.Root(), state.NewDatabaseWithNodeDB(db, tdb), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &setupState{
+		config: gspec.Config,
+		header: newTestHeader(gspec.Config, genesis.Header()),
+		getHeader: func(hash common.Hash, number uint64) *types.Header {
+			if number == 0 && hash == genesis.Hash() {
+				return genesis.Header()
+			}
+			return nil
+		},
+		statedb:    statedb,
+		cheatcodes: NewCheatcodes(),
+	}, nil
+}
+
+func (r *runner) callSetUp(setup *setupState) error {
+	gasTracer := newGasTracer(r.concreteRegistry.PrecompiledAddressesSet(setup.header.Number.Uint64()))
+	receipt, output, err := setup.applyCall(r.concreteRegistry, setup.statedb, setup.cheatcodes, testCall{to: contractAddress, data: setupId}, 0, &setup.usedGas, gasTracer)
+	if err != nil {
+		return err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return errSetupFailed
+	}
+	r.gas.recordPrecompiles(gasTracer)
+	setup.output = output
+	return nil
+}
+
+// applyCall makes a call in its own transaction in the test block.
+func (s *setupState) applyCall(registry concrete.PrecompileRegistry, statedb *state.StateDB, cheatcodes *Cheatcodes, call testCall, txIndex int, usedGas *uint64, tracer vm.EVMLogger) (*types.Receipt, []byte, error) {
+	var (
+		signer = types.LatestSigner(s.config)
+		chain  = &testChain{engine: ethash.NewFaker(), registry: cheatcodes.Registry(registry), getHeader: s.getHeader}
+		tx     = types.NewTransaction(statedb.GetNonce(senderAddress), call.to, common.Big0, txGasLimit, s.header.BaseFee, call.data)
+	)
+	signed, err := types.SignTx(tx, signer, senderKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	statedb.SetTxContext(signed.Hash(), txIndex)
+	gasPool := new(core.GasPool).AddGas(txGasLimit)
+	vmConfig := vm.Config{CallHooks: cheatcodes, Tracer: tracer}
+	outputs := len(cheatcodes.outputs)
+	receipt, err := core.ApplyTransaction(s.config, chain, &s.header.Coinbase, gasPool, statedb, s.header, signed, usedGas, vmConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(cheatcodes.outputs) != outputs+1 {
+		return nil, nil, fmt.Errorf("expected %d outputs, got %d", outputs+1, len(cheatcodes.outputs))
+	}
+	return receipt, cheatcodes.outputs[outputs], nil
+}
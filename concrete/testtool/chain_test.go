@@ -0,0 +1,83 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestPrecompile is a test contract implemented as a precompile, whose
+// tests check they start from the state left by setUp.
+type setupTestPrecompile struct {
+	abi    abi.ABI
+	setUps atomic.Int32
+}
+
+func newSetupTestPrecompile() *setupTestPrecompile {
+	methods := make(map[string]abi.Method)
+	for _, name := range []string{"testA", "testB", "testC", "testD"} {
+		methods[name] = abi.NewMethod(name, name, abi.Function, "", false, false, nil, nil)
+	}
+	return &setupTestPrecompile{abi: abi.ABI{Methods: methods}}
+}
+
+func (pc *setupTestPrecompile) IsStatic(input []byte) bool { return false }
+
+func (pc *setupTestPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	if _, err := pc.abi.MethodById(input); err != nil {
+		// setUp
+		pc.setUps.Add(1)
+		env.StorageStore(common.Hash{}, common.Hash{1})
+		cheat(env, "warp(uint256)", big.NewInt(1000))
+		return nil, nil
+	}
+	// Every test changes the state set up, and expects to see it unchanged
+	if env.StorageLoad(common.Hash{}) != (common.Hash{1}) {
+		return nil, errors.New("unexpected storage")
+	}
+	if env.GetBlockTimestamp() != 1000 {
+		return nil, errors.New("unexpected timestamp")
+	}
+	env.StorageStore(common.Hash{}, common.Hash{2})
+	return nil, nil
+}
+
+func TestSetUpOnce(t *testing.T) {
+	var (
+		pc       = newSetupTestPrecompile()
+		registry = concrete.NewRegistry()
+	)
+	registry.AddPrecompiles(0, concrete.PrecompileMap{contractAddress: pc})
+	r := newRunner(registry, TestConfig{})
+	t.Run("SetupTest", func(t *testing.T) {
+		r.runTestContract(goTester{t}, "SetupTest", nil, pc.abi)
+	})
+	require.Equal(t, int32(1), pc.setUps.Load())
+	results := r.sortedResults()
+	require.Len(t, results, 4)
+	for _, result := range results {
+		require.True(t, result.Passed, result.Failure)
+	}
+}
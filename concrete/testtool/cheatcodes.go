@@ -0,0 +1,582 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/holiman/uint256"
+)
+
+// HEVMAddress is the address of the cheatcode precompile, as in Foundry.
+var HEVMAddress = common.HexToAddress("0x7109709ECfa91a80626fF3989D68f67F5b1DD12D")
+
+var ErrUnknownCheatcode = errors.New("unknown cheatcode")
+
+type cheatcodeFunc func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error)
+
+type cheatcode struct {
+	method abi.Method
+	run    cheatcodeFunc
+	static bool // Whether the cheatcode can be called from a static context
+}
+
+var cheatcodeTable = map[[4]byte]cheatcode{}
+
+func addCheatcode(signature string, outputs []string, run cheatcodeFunc) {
+	registerCheatcode(signature, outputs, run, true)
+}
+
+// addStateCheatcode adds a cheatcode that writes to the state, which cannot be
+// called from a static context.
+func addStateCheatcode(signature string, outputs []string, run cheatcodeFunc) {
+	registerCheatcode(signature, outputs, run, false)
+}
+
+func registerCheatcode(signature string, outputs []string, run cheatcodeFunc, static bool) {
+	name, params, _ := strings.Cut(strings.TrimSuffix(signature, ")"), "(")
+	method := abi.NewMethod(name, name, abi.Function, "", false, false, newArguments(params), newArguments(strings.Join(outputs, ",")))
+	var id [4]byte
+	copy(id[:], method.ID)
+	cheatcodeTable[id] = cheatcode{method: method, run: run, static: static}
+}
+
+func newArguments(types string) abi.Arguments {
+	if types == "" {
+		return abi.Arguments{}
+	}
+	var args abi.Arguments
+	for _, t := range strings.Split(types, ",") {
+		typ, err := abi.NewType(t, "", nil)
+		if err != nil {
+			panic(err)
+		}
+		args = append(args, abi.Argument{Type: typ})
+	}
+	return args
+}
+
+func init() {
+	addCheatcode("warp(uint256)", nil, func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+		timestamp := args[0].(*big.Int).Uint64()
+		c.timestamp = &timestamp
+		c.evm.Context.Time = timestamp
+		return nil, nil
+	})
+	addCheatcode("roll(uint256)", nil, func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+		c.blockNumber = new(big.Int).Set(args[0].(*big.Int))
+		c.evm.Context.BlockNumber = new(big.Int).Set(c.blockNumber)
+		return nil, nil
+	})
+	addStateCheatcode("deal(address,uint256)", nil, func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+		address := args[0].(common.Address)
+		balance, overflow := uint256.FromBig(args[1].(*big.Int))
+		if overflow {
+			return nil, errors.New("balance overflow")
+		}
+		current := env.GetExternalBalance(address)
+		if balance.Gt(current) {
+			env.MintBalance(address, new(uint256.Int).Sub(balance, current))
+		} else {
+			env.BurnBalance(address, new(uint256.Int).Sub(current, balance))
+		}
+		return nil, nil
+	})
+	addStateCheatcode("store(address,bytes32,bytes32)", nil, func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+		env.SetExternalStorage(args[0].(common.Address), args[1].([32]byte), args[2].([32]byte))
+		return nil, nil
+	})
+	addCheatcode("load(address,bytes32)", []string{"bytes32"}, func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+		value := env.GetExternalStorage(args[0].(common.Address), args[1].([32]byte))
+		return []interface{}{[32]byte(value)}, nil
+	})
+
+	startPrank := func(persistent bool) cheatcodeFunc {
+		return func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+			p := &prank{sender: args[0].(common.Address), depth: c.depth(), persistent: persistent}
+			if len(args) > 1 {
+				origin := args[1].(common.Address)
+				p.origin = &origin
+			}
+			c.prank = p
+			return nil, nil
+		}
+	}
+	addCheatcode("prank(address)", nil, startPrank(false))
+	addCheatcode("prank(address,address)", nil, startPrank(false))
+	addCheatcode("startPrank(address)", nil, startPrank(true))
+	addCheatcode("startPrank(address,address)", nil, startPrank(true))
+	addCheatcode("stopPrank()", nil, func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+		c.prank = nil
+		return nil, nil
+	})
+
+	expectRevert := func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+		if c.expectedRevert != nil {
+			return nil, errors.New("already expecting a revert")
+		}
+		e := &expectedRevert{depth: c.depth()}
+		if len(args) > 0 {
+			switch data := args[0].(type) {
+			case []byte:
+				e.data = data
+			case [4]byte:
+				e.data = data[:]
+				e.selector = true
+			}
+		}
+		c.expectedRevert = e
+		return nil, nil
+	}
+	addCheatcode("expectRevert()", nil, expectRevert)
+	addCheatcode("expectRevert(bytes)", nil, expectRevert)
+	addCheatcode("expectRevert(bytes4)", nil, expectRevert)
+
+	expectEmit := func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+		e := &expectedEmit{depth: c.depth(), checks: [4]bool{true, true, true, true}}
+		if len(args) >= 4 {
+			for ii := 0; ii < 4; ii++ {
+				e.checks[ii] = args[ii].(bool)
+			}
+			args = args[4:]
+		}
+		if len(args) > 0 {
+			emitter := args[0].(common.Address)
+			e.emitter = &emitter
+		}
+		c.expectedEmits = append(c.expectedEmits, e)
+		return nil, nil
+	}
+	addCheatcode("expectEmit()", nil, expectEmit)
+	addCheatcode("expectEmit(address)", nil, expectEmit)
+	addCheatcode("expectEmit(bool,bool,bool,bool)", nil, expectEmit)
+	addCheatcode("expectEmit(bool,bool,bool,bool,address)", nil, expectEmit)
+
+	addCheatcode("record()", nil, func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+		c.recording = true
+		c.reads = make(map[common.Address][]common.Hash)
+		c.writes = make(map[common.Address][]common.Hash)
+		return nil, nil
+	})
+	addCheatcode("accesses(address)", []string{"bytes32[]", "bytes32[]"}, func(c *Cheatcodes, env api.Environment, args []interface{}) ([]interface{}, error) {
+		address := args[0].(common.Address)
+		return []interface{}{toBytes32Slice(c.reads[address]), toBytes32Slice(c.writes[address])}, nil
+	})
+}
+
+func toBytes32Slice(hashes []common.Hash) [][32]byte {
+	out := make([][32]byte, len(hashes))
+	for ii, hash := range hashes {
+		out[ii] = hash
+	}
+	return out
+}
+
+type prank struct {
+	sender     common.Address
+	origin     *common.Address
+	depth      int
+	persistent bool
+}
+
+type expectedRevert struct {
+	data     []byte
+	selector bool
+	depth    int
+}
+
+func (e *expectedRevert) check(ret []byte, err error) error {
+	if err == nil {
+		return errors.New("call did not revert as expected")
+	}
+	if e.data == nil {
+		return nil
+	}
+	if e.selector {
+		if len(ret) < 4 || !bytes.Equal(ret[:4], e.data) {
+			return fmt.Errorf("call reverted with unexpected selector: 0x%x", ret)
+		}
+		return nil
+	}
+	if bytes.Equal(ret, e.data) {
+		return nil
+	}
+	if reason, err := abi.UnpackRevert(ret); err == nil && reason == string(e.data) {
+		return nil
+	}
+	return fmt.Errorf("call reverted with unexpected data: 0x%x", ret)
+}
+
+type expectedEmit struct {
+	checks  [4]bool // topic 1, topic 2, topic 3, data
+	emitter *common.Address
+	depth   int
+	log     *types.Log // Set when the expected log is emitted by the test
+	found   bool
+}
+
+func (e *expectedEmit) matches(log *types.Log) bool {
+	if e.emitter != nil && log.Address != *e.emitter {
+		return false
+	}
+	if len(log.Topics) != len(e.log.Topics) {
+		return false
+	}
+	if len(log.Topics) > 0 && log.Topics[0] != e.log.Topics[0] {
+		return false
+	}
+	for ii := 1; ii < len(log.Topics) && ii < 4; ii++ {
+		if e.checks[ii-1] && log.Topics[ii] != e.log.Topics[ii] {
+			return false
+		}
+	}
+	return !e.checks[3] || bytes.Equal(log.Data, e.log.Data)
+}
+
+// callFrame is a call being executed, as seen by the call hooks.
+type callFrame struct {
+	snapshot       int             // State snapshot to revert to if an expectation is not met
+	origin         *common.Address // Origin to restore on exit
+	expectedRevert *expectedRevert
+	expectedEmits  []*expectedEmit
+}
+
+// Cheatcodes implements Foundry cheatcodes. It is registered as a precompile at
+// HEVMAddress and alters the calls made in the test chain through call hooks.
+// A Cheatcodes instance holds the cheatcode state of a single test.
+type Cheatcodes struct {
+	evm    *vm.EVM
+	frames []*callFrame
+
+	timestamp      *uint64
+	blockNumber    *big.Int
+	prank          *prank
+	expectedRevert *expectedRevert
+	expectedEmits  []*expectedEmit
+	checkingEmits  []*expectedEmit
+
+	recording bool
+	reads     map[common.Address][]common.Hash
+	writes    map[common.Address][]common.Hash
+
+	// Return data of every transaction, used by the test runner
+	outputs [][]byte
+}
+
+var (
+	_ concrete.Precompile = (*Cheatcodes)(nil)
+	_ vm.CallHooks        = (*Cheatcodes)(nil)
+)
+
+func NewCheatcodes() *Cheatcodes {
+	return &Cheatcodes{}
+}
+
+// copy returns a copy of the cheatcode state kept between transactions.
+func (c *Cheatcodes) copy() *Cheatcodes {
+	cpy := &Cheatcodes{
+		recording: c.recording,
+		reads:     copyAccesses(c.reads),
+		writes:    copyAccesses(c.writes),
+		outputs:   append([][]byte(nil), c.outputs...),
+	}
+	if c.timestamp != nil {
+		timestamp := *c.timestamp
+		cpy.timestamp = &timestamp
+	}
+	if c.blockNumber != nil {
+		cpy.blockNumber = new(big.Int).Set(c.blockNumber)
+	}
+	if c.prank != nil {
+		prank := *c.prank
+		cpy.prank = &prank
+	}
+	return cpy
+}
+
+func copyAccesses(accesses map[common.Address][]common.Hash) map[common.Address][]common.Hash {
+	if accesses == nil {
+		return nil
+	}
+	cpy := make(map[common.Address][]common.Hash, len(accesses))
+	for address, slots := range accesses {
+		cpy[address] = append([]common.Hash(nil), slots...)
+	}
+	return cpy
+}
+
+// depth returns the depth of the frame calling the cheatcode, where the frame
+// of the transaction is at depth zero.
+func (c *Cheatcodes) depth() int {
+	return len(c.frames) - 2
+}
+
+// IsStatic returns false for the cheatcodes that write to the state. The others
+// only alter the test chain and some are declared as view in the Foundry
+// interfaces, so they can be called from a static context.
+func (c *Cheatcodes) IsStatic(input []byte) bool {
+	if len(input) < 4 {
+		return true
+	}
+	cc, ok := cheatcodeTable[[4]byte(input[:4])]
+	return !ok || cc.static
+}
+
+// AbiVersion opts the cheatcodes into the system operations of api.AbiVersion2.
+func (c *Cheatcodes) AbiVersion() api.AbiVersion {
+	return api.AbiVersion2
+}
+
+func (c *Cheatcodes) Run(env api.Environment, input []byte) ([]byte, error) {
+	if len(input) < 4 {
+		return nil, ErrUnknownCheatcode
+	}
+	var id [4]byte
+	copy(id[:], input[:4])
+	cc, ok := cheatcodeTable[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: 0x%x", ErrUnknownCheatcode, id)
+	}
+	args, err := cc.method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return nil, err
+	}
+	ret, err := cc.run(c, env, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", cc.method.Name, err)
+	}
+	return cc.method.Outputs.Pack(ret...)
+}
+
+func (c *Cheatcodes) CallEnter(evm *vm.EVM, caller common.Address, addr common.Address, input []byte) common.Address {
+	c.evm = evm
+	depth := len(c.frames)
+	frame := &callFrame{}
+	c.frames = append(c.frames, frame)
+
+	if depth == 0 {
+		// New transaction
+		if _, ok := evm.StateDB.(*cheatcodeStateDB); !ok {
+			evm.StateDB = &cheatcodeStateDB{StateDB: evm.StateDB, cheatcodes: c}
+		}
+		if c.timestamp != nil {
+			evm.Context.Time = *c.timestamp
+		}
+		if c.blockNumber != nil {
+			evm.Context.BlockNumber = new(big.Int).Set(c.blockNumber)
+		}
+	}
+	frame.snapshot = evm.StateDB.Snapshot()
+	if addr == HEVMAddress {
+		return caller
+	}
+
+	// Expectations apply to the next call made from the frame that set them
+	if e := c.expectedRevert; e != nil && e.depth == depth-1 {
+		frame.expectedRevert = e
+		c.expectedRevert = nil
+	}
+	if len(c.expectedEmits) > 0 && c.expectedEmits[0].depth == depth-1 && c.expectedEmits[0].log != nil {
+		frame.expectedEmits = c.expectedEmits
+		c.checkingEmits = append(c.checkingEmits, c.expectedEmits...)
+		c.expectedEmits = nil
+	}
+
+	if p := c.prank; p != nil && p.depth == depth-1 {
+		if !p.persistent {
+			c.prank = nil
+		}
+		if p.origin != nil {
+			origin := evm.TxContext.Origin
+			frame.origin = &origin
+			evm.TxContext.Origin = *p.origin
+		}
+		return p.sender
+	}
+	return caller
+}
+
+func (c *Cheatcodes) CallExit(evm *vm.EVM, addr common.Address, ret []byte, err error) ([]byte, error) {
+	frame := c.frames[len(c.frames)-1]
+	c.frames = c.frames[:len(c.frames)-1]
+
+	if frame.origin != nil {
+		evm.TxContext.Origin = *frame.origin
+	}
+	if reason := c.unmetExpectation(frame, ret, err); reason != "" {
+		// Undo the changes of the call, which may have succeeded
+		evm.StateDB.RevertToSnapshot(frame.snapshot)
+		ret, err = revertWithReason(reason)
+	} else if frame.expectedRevert != nil {
+		ret, err = nil, nil
+	}
+	if len(c.frames) == 0 {
+		// End of transaction
+		c.checkingEmits = nil
+		c.outputs = append(c.outputs, common.CopyBytes(ret))
+	}
+	return ret, err
+}
+
+// unmetExpectation returns the reason the call of a frame failed the
+// expectations set for it, or an empty string if it met them.
+func (c *Cheatcodes) unmetExpectation(frame *callFrame, ret []byte, err error) string {
+	if e := frame.expectedRevert; e != nil {
+		if checkErr := e.check(ret, err); checkErr != nil {
+			return checkErr.Error()
+		}
+	}
+	for _, e := range frame.expectedEmits {
+		if !e.found {
+			return "expected log not emitted"
+		}
+	}
+	if len(c.frames) == 0 {
+		if c.expectedRevert != nil {
+			return "expected revert not followed by a call"
+		}
+		if len(c.expectedEmits) > 0 {
+			return "expected emit not followed by a call"
+		}
+	}
+	return ""
+}
+
+func (c *Cheatcodes) onLog(log *types.Log) bool {
+	// The first log after expectEmit is the expected log and is not emitted
+	for _, e := range c.expectedEmits {
+		if e.log == nil {
+			e.log = log
+			return false
+		}
+	}
+	// Expected logs must be emitted in order
+	for _, e := range c.checkingEmits {
+		if e.found {
+			continue
+		}
+		if e.matches(log) {
+			e.found = true
+		}
+		break
+	}
+	return true
+}
+
+func (c *Cheatcodes) onStorageRead(addr common.Address, key common.Hash) {
+	if c.recording {
+		c.reads[addr] = append(c.reads[addr], key)
+	}
+}
+
+func (c *Cheatcodes) onStorageWrite(addr common.Address, key common.Hash) {
+	if c.recording {
+		c.writes[addr] = append(c.writes[addr], key)
+	}
+}
+
+// Registry returns a registry with the given precompiles and the cheatcode
+// precompile at HEVMAddress.
+func (c *Cheatcodes) Registry(registry concrete.PrecompileRegistry) concrete.PrecompileRegistry {
+	return &cheatcodeRegistry{PrecompileRegistry: registry, cheatcodes: c}
+}
+
+func revertWithReason(reason string) ([]byte, error) {
+	data, err := revertReasonArgs.Pack(reason)
+	if err != nil {
+		panic(err)
+	}
+	return append(common.CopyBytes(revertSelector), data...), vm.ErrExecutionReverted
+}
+
+var (
+	revertSelector   = []byte{0x08, 0xc3, 0x79, 0xa0} // Error(string)
+	revertReasonArgs = newArguments("string")
+)
+
+// cheatcodeStateDB observes the logs and storage accesses of a transaction.
+type cheatcodeStateDB struct {
+	vm.StateDB
+	cheatcodes *Cheatcodes
+}
+
+func (s *cheatcodeStateDB) GetState(addr common.Address, key common.Hash) common.Hash {
+	s.cheatcodes.onStorageRead(addr, key)
+	return s.StateDB.GetState(addr, key)
+}
+
+func (s *cheatcodeStateDB) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	s.cheatcodes.onStorageWrite(addr, key)
+	s.StateDB.SetState(addr, key, value)
+}
+
+func (s *cheatcodeStateDB) AddLog(log *types.Log) {
+	if s.cheatcodes.onLog(log) {
+		s.StateDB.AddLog(log)
+	}
+}
+
+type cheatcodeRegistry struct {
+	concrete.PrecompileRegistry
+	cheatcodes *Cheatcodes
+}
+
+// Capabilities grants the cheatcode precompile the capabilities to write the
+// balance and storage of any account.
+func (r *cheatcodeRegistry) Capabilities(blockNumber uint64) concrete.CapabilityMap {
+	capabilities := concrete.CapabilityMap{HEVMAddress: api.CapabilityBalance | api.CapabilityStorage}
+	for address, capability := range concrete.RegistryCapabilities(r.PrecompileRegistry, blockNumber) {
+		capabilities[address] = capability
+	}
+	return capabilities
+}
+
+func (r *cheatcodeRegistry) Precompile(address common.Address, blockNumber uint64) (concrete.Precompile, bool) {
+	if address == HEVMAddress {
+		return r.cheatcodes, true
+	}
+	return r.PrecompileRegistry.Precompile(address, blockNumber)
+}
+
+func (r *cheatcodeRegistry) Precompiles(blockNumber uint64) concrete.PrecompileMap {
+	precompiles := concrete.PrecompileMap{HEVMAddress: r.cheatcodes}
+	for address, pc := range r.PrecompileRegistry.Precompiles(blockNumber) {
+		precompiles[address] = pc
+	}
+	return precompiles
+}
+
+func (r *cheatcodeRegistry) PrecompiledAddresses(blockNumber uint64) []common.Address {
+	return append(append([]common.Address{}, r.PrecompileRegistry.PrecompiledAddresses(blockNumber)...), HEVMAddress)
+}
+
+func (r *cheatcodeRegistry) PrecompiledAddressesSet(blockNumber uint64) map[common.Address]struct{} {
+	set := map[common.Address]struct{}{HEVMAddress: {}}
+	for address := range r.PrecompileRegistry.PrecompiledAddressesSet(blockNumber) {
+		set[address] = struct{}{}
+	}
+	return set
+}
@@ -0,0 +1,233 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+)
+
+var (
+	targetAddress = common.HexToAddress("0x1000")
+	prankAddress  = common.HexToAddress("0x2000")
+	logTopic      = crypto.Keccak256Hash([]byte("Event()"))
+)
+
+func cheatInput(signature string, args ...interface{}) []byte {
+	var id [4]byte
+	copy(id[:], crypto.Keccak256([]byte(signature))[:4])
+	cc, ok := cheatcodeTable[id]
+	if !ok {
+		panic("unknown cheatcode " + signature)
+	}
+	input, err := cc.method.Inputs.Pack(args...)
+	if err != nil {
+		panic(err)
+	}
+	return append(id[:], input...)
+}
+
+func cheat(env api.Environment, signature string, args ...interface{}) []byte {
+	ret, err := env.Call(HEVMAddress, cheatInput(signature, args...), env.GetGasLeft(), uint256.NewInt(0))
+	if err != nil {
+		env.Revert(fmt.Errorf("%s: %w", signature, err))
+	}
+	return ret
+}
+
+// targetPrecompile returns its caller or origin, reverts or emits a log
+// depending on the first byte of the input.
+type targetPrecompile struct{}
+
+func (pc *targetPrecompile) IsStatic(input []byte) bool { return false }
+
+func (pc *targetPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	switch input[0] {
+	case 0:
+		return env.GetCaller().Bytes(), nil
+	case 1:
+		return env.GetTxOrigin().Bytes(), nil
+	case 2:
+		return nil, errors.New("boom")
+	case 3:
+		env.Log([]common.Hash{logTopic}, input[1:])
+		return nil, nil
+	case 4:
+		env.StorageStore(common.Hash{1}, env.StorageLoad(common.Hash{2}))
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// cheatTestPrecompile is a test contract implemented as a precompile.
+type cheatTestPrecompile struct {
+	tests map[string]func(env api.Environment) error
+}
+
+func (pc *cheatTestPrecompile) IsStatic(input []byte) bool { return false }
+
+func (pc *cheatTestPrecompile) AbiVersion() api.AbiVersion { return api.AbiVersion2 }
+
+func (pc *cheatTestPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	for name, test := range pc.tests {
+		if bytes.Equal(input[:4], crypto.Keccak256([]byte(name + "()"))[:4]) {
+			return nil, test(env)
+		}
+	}
+	return nil, nil // setUp
+}
+
+func (pc *cheatTestPrecompile) abi() abi.ABI {
+	methods := make(map[string]abi.Method)
+	for name := range pc.tests {
+		methods[name] = abi.NewMethod(name, name, abi.Function, "", false, false, nil, nil)
+	}
+	return abi.ABI{Methods: methods}
+}
+
+func callTarget(env api.Environment, input ...byte) ([]byte, error) {
+	return env.Call(targetAddress, input, env.GetGasLeft(), uint256.NewInt(0))
+}
+
+func expectEqual(a, b interface{}) error {
+	if fmt.Sprint(a) != fmt.Sprint(b) {
+		return fmt.Errorf("expected %v, got %v", b, a)
+	}
+	return nil
+}
+
+func TestCheatcodes(t *testing.T) {
+	tests := map[string]func(env api.Environment) error{
+		"testPrank": func(env api.Environment) error {
+			cheat(env, "prank(address)", prankAddress)
+			ret, _ := callTarget(env, 0)
+			if err := expectEqual(common.BytesToAddress(ret), prankAddress); err != nil {
+				return err
+			}
+			ret, _ = callTarget(env, 0)
+			return expectEqual(common.BytesToAddress(ret), contractAddress)
+		},
+		"testStartPrank": func(env api.Environment) error {
+			cheat(env, "startPrank(address,address)", prankAddress, prankAddress)
+			for ii := 0; ii < 2; ii++ {
+				ret, _ := callTarget(env, 1)
+				if err := expectEqual(common.BytesToAddress(ret), prankAddress); err != nil {
+					return err
+				}
+			}
+			cheat(env, "stopPrank()")
+			ret, _ := callTarget(env, 0)
+			return expectEqual(common.BytesToAddress(ret), contractAddress)
+		},
+		"testWarpRoll": func(env api.Environment) error {
+			cheat(env, "warp(uint256)", big.NewInt(1234))
+			cheat(env, "roll(uint256)", big.NewInt(99))
+			if err := expectEqual(env.GetBlockTimestamp(), 1234); err != nil {
+				return err
+			}
+			return expectEqual(env.GetBlockNumber(), 99)
+		},
+		"testDealStoreLoad": func(env api.Environment) error {
+			cheat(env, "deal(address,uint256)", targetAddress, big.NewInt(42))
+			if err := expectEqual(env.GetExternalBalance(targetAddress), 42); err != nil {
+				return err
+			}
+			cheat(env, "store(address,bytes32,bytes32)", targetAddress, [32]byte{1}, [32]byte{2})
+			ret := cheat(env, "load(address,bytes32)", targetAddress, [32]byte{1})
+			return expectEqual(common.BytesToHash(ret), common.Hash{2})
+		},
+		"testExpectRevert": func(env api.Environment) error {
+			cheat(env, "expectRevert(bytes)", []byte("boom"))
+			_, err := callTarget(env, 2)
+			return err
+		},
+		"testFailExpectRevert": func(env api.Environment) error {
+			cheat(env, "expectRevert()")
+			_, err := callTarget(env, 0)
+			return err
+		},
+		"testFailExpectRevertData": func(env api.Environment) error {
+			cheat(env, "expectRevert(bytes)", []byte("bang"))
+			_, err := callTarget(env, 2)
+			return err
+		},
+		"testFailExpectRevertNotCalled": func(env api.Environment) error {
+			cheat(env, "expectRevert()")
+			return nil
+		},
+		"testExpectRevertUndoesCall": func(env api.Environment) error {
+			cheat(env, "store(address,bytes32,bytes32)", targetAddress, [32]byte{2}, [32]byte{9})
+			cheat(env, "expectRevert()")
+			if _, err := callTarget(env, 4); err == nil {
+				return errors.New("call met expectRevert without reverting")
+			}
+			return expectEqual(env.GetExternalStorage(targetAddress, common.Hash{1}), common.Hash{})
+		},
+		"testStaticCheatcodes": func(env api.Environment) error {
+			gas := env.GetGasLeft() / 2
+			if _, err := env.CallStatic(HEVMAddress, cheatInput("load(address,bytes32)", targetAddress, [32]byte{1}), gas); err != nil {
+				return err
+			}
+			if _, err := env.CallStatic(HEVMAddress, cheatInput("store(address,bytes32,bytes32)", targetAddress, [32]byte{1}, [32]byte{2}), gas); err == nil {
+				return errors.New("store succeeded in a static call")
+			}
+			return nil
+		},
+		"testExpectEmit": func(env api.Environment) error {
+			cheat(env, "expectEmit(bool,bool,bool,bool,address)", true, true, true, true, targetAddress)
+			env.Log([]common.Hash{logTopic}, []byte{7})
+			_, err := callTarget(env, 3, 7)
+			return err
+		},
+		"testFailExpectEmit": func(env api.Environment) error {
+			cheat(env, "expectEmit()")
+			env.Log([]common.Hash{logTopic}, []byte{7})
+			_, err := callTarget(env, 3, 8)
+			return err
+		},
+		"testRecord": func(env api.Environment) error {
+			cheat(env, "record()")
+			callTarget(env, 4)
+			ret := cheat(env, "accesses(address)", targetAddress)
+			out, err := cheatcodeTable[[4]byte(crypto.Keccak256([]byte("accesses(address)"))[:4])].method.Outputs.Unpack(ret)
+			if err != nil {
+				return err
+			}
+			reads, writes := out[0].([][32]byte), out[1].([][32]byte)
+			if len(reads) == 0 || reads[0] != [32]byte{2} {
+				return fmt.Errorf("unexpected reads: %x", reads)
+			}
+			return expectEqual(writes, [][32]byte{{1}})
+		},
+	}
+	test := &cheatTestPrecompile{tests: tests}
+	registry := concrete.NewRegistry()
+	registry.AddPrecompiles(0, concrete.PrecompileMap{
+		contractAddress: test,
+		targetAddress:   &targetPrecompile{},
+	})
+	RunTestContract(t, registry, nil, test.abi())
+}
@@ -0,0 +1,204 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+
+// indexABI adds the events and errors of an artifact to the ones used to
+// decode logs and revert data.
+func (r *runner) indexABI(ABI abi.ABI) {
+	for _, event := range ABI.Events {
+		if !event.Anonymous {
+			r.events[event.ID] = append(r.events[event.ID], event)
+		}
+	}
+	for _, abiErr := range ABI.Errors {
+		var selector [4]byte
+		copy(selector[:], abiErr.ID[:4])
+		r.errors[selector] = append(r.errors[selector], abiErr)
+	}
+}
+
+// decoder decodes the calldata, revert data and logs of a test with the ABIs
+// of the test contract and of the artifacts.
+type decoder struct {
+	runner  *runner
+	testABI abi.ABI
+	statedb *state.StateDB
+}
+
+func (r *runner) newDecoder(testABI abi.ABI, statedb *state.StateDB) *decoder {
+	return &decoder{runner: r, testABI: testABI, statedb: statedb}
+}
+
+// abiAt returns the ABI of the contract at the given address, if it is known.
+func (d *decoder) abiAt(address common.Address) (abi.ABI, bool) {
+	if d.statedb != nil {
+		if ABI, ok := d.runner.artifacts[d.statedb.GetCodeHash(address)]; ok {
+			return ABI, true
+		}
+	}
+	if address == contractAddress {
+		return d.testABI, true
+	}
+	return abi.ABI{}, false
+}
+
+// revertReason decodes the data a call reverted with. Error(string) and
+// Panic(uint256) are decoded first, then custom errors of the test contract
+// and of the artifacts.
+func (d *decoder) revertReason(output []byte) string {
+	if len(output) == 0 {
+		return ""
+	}
+	if len(output) >= 4 && bytes.Equal(output[:4], panicSelector) {
+		if reason, err := abi.UnpackRevert(output); err == nil {
+			code := new(big.Int).SetBytes(output[4:])
+			return fmt.Sprintf("panic: %s (%#x)", reason, code)
+		}
+	}
+	if reason, err := abi.UnpackRevert(output); err == nil {
+		return reason
+	}
+	if len(output) >= 4 {
+		var selector [4]byte
+		copy(selector[:], output[:4])
+		candidates := d.runner.errors[selector]
+		if abiErr, err := d.testABI.ErrorByID(selector); err == nil {
+			candidates = append([]abi.Error{*abiErr}, candidates...)
+		}
+		for _, abiErr := range candidates {
+			if values, err := abiErr.Inputs.Unpack(output[4:]); err == nil {
+				return abiErr.Name + formatInterfaces(values)
+			}
+		}
+	}
+	// Concrete precompiles revert with the error message as output
+	if utf8.Valid(output) && strings.IndexFunc(string(output), func(r rune) bool { return !unicode.IsPrint(r) }) < 0 {
+		return string(output)
+	}
+	return hexutil.Encode(output)
+}
+
+// logs decodes logs with the ABI of the contract that emitted them, the test
+// contract and then the artifacts.
+func (d *decoder) logs(logs []*types.Log) []DecodedLog {
+	if len(logs) == 0 {
+		return nil
+	}
+	decoded := make([]DecodedLog, len(logs))
+	for ii, log := range logs {
+		decoded[ii] = d.log(log.Address, log.Topics, log.Data)
+	}
+	return decoded
+}
+
+func (d *decoder) log(address common.Address, topics []common.Hash, data []byte) DecodedLog {
+	decoded := DecodedLog{Address: address, Topics: topics, Data: data}
+	if len(topics) == 0 {
+		return decoded
+	}
+	var candidates []abi.Event
+	if ABI, ok := d.abiAt(address); ok {
+		if event, err := ABI.EventByID(topics[0]); err == nil {
+			candidates = append(candidates, *event)
+		}
+	}
+	if event, err := d.testABI.EventByID(topics[0]); err == nil {
+		candidates = append(candidates, *event)
+	}
+	candidates = append(candidates, d.runner.events[topics[0]]...)
+	for _, event := range candidates {
+		if args, err := decodeEventArgs(event, topics, data); err == nil {
+			decoded.Event = event.Sig
+			decoded.Args = args
+			break
+		}
+	}
+	return decoded
+}
+
+func decodeEventArgs(event abi.Event, topics []common.Hash, data []byte) (map[string]string, error) {
+	var indexed abi.Arguments
+	for _, input := range event.Inputs {
+		if input.Indexed {
+			indexed = append(indexed, input)
+		}
+	}
+	if len(indexed) != len(topics)-1 {
+		return nil, fmt.Errorf("expected %d topics, got %d", len(indexed)+1, len(topics))
+	}
+	values := make(map[string]interface{})
+	if err := event.Inputs.UnpackIntoMap(values, data); err != nil {
+		return nil, err
+	}
+	if err := abi.ParseTopicsIntoMap(values, indexed, topics[1:]); err != nil {
+		return nil, err
+	}
+	args := make(map[string]string, len(values))
+	for name, value := range values {
+		args[name] = formatValue(reflect.ValueOf(value))
+	}
+	return args, nil
+}
+
+// call decodes the calldata of a call to the given address, and returns the
+// method called if it is known.
+func (d *decoder) call(to common.Address, input []byte) (string, *abi.Method) {
+	if len(input) < 4 {
+		return hexutil.Encode(input), nil
+	}
+	var method *abi.Method
+	if ABI, ok := d.abiAt(to); ok {
+		method, _ = ABI.MethodById(input)
+	}
+	if cheat, ok := cheatcodeTable[[4]byte(input[:4])]; ok && to == HEVMAddress {
+		method = &cheat.method
+	}
+	if method == nil {
+		return hexutil.Encode(input), nil
+	}
+	values, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return hexutil.Encode(input), nil
+	}
+	return method.Name + formatInterfaces(values), method
+}
+
+func formatInterfaces(values []interface{}) string {
+	args := make([]reflect.Value, len(values))
+	for ii, value := range values {
+		args[ii] = reflect.ValueOf(value)
+	}
+	return formatArgs(args)
+}
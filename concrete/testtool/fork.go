@@ -0,0 +1,129 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/ethereum/go-ethereum/triedb/hashdb"
+	"github.com/ethereum/go-ethereum/triedb/pathdb"
+	"github.com/holiman/uint256"
+)
+
+type ForkConfig struct {
+	Datadir string // Path of a geth datadir to fork, tests run on a new chain if empty
+	Block   uint64 // Number of the block to fork from, the head block if zero
+}
+
+// forkChain is a read-only view of the chain in a local geth datadir. Tests run
+// on top of one of its blocks, with their changes to its state kept in memory.
+type forkChain struct {
+	db     ethdb.Database
+	triedb *triedb.Database
+	config *params.ChainConfig
+	parent *types.Header // Header of the block forked from
+}
+
+// openFork opens the chain database of a geth datadir in read-only mode.
+func openFork(config ForkConfig) (*forkChain, error) {
+	chaindata := filepath.Join(config.Datadir, "geth", "chaindata")
+	db, err := rawdb.Open(rawdb.OpenOptions{
+		Directory:         chaindata,
+		AncientsDirectory: filepath.Join(chaindata, "ancient"),
+		Namespace:         "testtool/fork/",
+		Cache:             16,
+		Handles:           16,
+		ReadOnly:          true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error opening chain database: %w", err)
+	}
+	fork, err := newForkChain(db, config.Block)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	return fork, nil
+}
+
+func newForkChain(db ethdb.Database, number uint64) (*forkChain, error) {
+	config := rawdb.ReadChainConfig(db, rawdb.ReadCanonicalHash(db, 0))
+	if config == nil {
+		return nil, errors.New("chain config not found")
+	}
+	var hash common.Hash
+	if number == 0 {
+		hash = rawdb.ReadHeadBlockHash(db)
+		headNumber := rawdb.ReadHeaderNumber(db, hash)
+		if headNumber == nil {
+			return nil, errors.New("head block not found")
+		}
+		number = *headNumber
+	} else {
+		hash = rawdb.ReadCanonicalHash(db, number)
+	}
+	parent := rawdb.ReadHeader(db, hash, number)
+	if parent == nil {
+		return nil, fmt.Errorf("block %d not found", number)
+	}
+
+	trieConfig := &triedb.Config{HashDB: hashdb.Defaults}
+	if rawdb.ReadStateScheme(db) == rawdb.PathScheme {
+		trieConfig = &triedb.Config{PathDB: pathdb.ReadOnly}
+	}
+	return &forkChain{
+		db:     db,
+		triedb: triedb.NewDatabase(db, trieConfig),
+		config: config,
+		parent: parent,
+	}, nil
+}
+
+func (f *forkChain) Close() error {
+	f.triedb.Close()
+	return f.db.Close()
+}
+
+// newSetupState returns the state of the forked block with the test contract
+// deployed. Changes to the state are never committed to the database.
+func (f *forkChain) newSetupState(bytecode []byte) (*setupState, error) {
+	statedb, err := state.New(f.parent.Root, state.NewDatabaseWithNodeDB(f.db, f.triedb), nil)
+	if err != nil {
+		return nil, fmt.Errorf("state of block %d not available: %w", f.parent.Number, err)
+	}
+	for address, account := range testAlloc(bytecode) {
+		statedb.SetBalance(address, uint256.MustFromBig(account.Balance))
+		statedb.SetCode(address, account.Code)
+	}
+	return &setupState{
+		config: f.config,
+		header: newTestHeader(f.config, f.parent),
+		getHeader: func(hash common.Hash, number uint64) *types.Header {
+			return rawdb.ReadHeader(f.db, hash, number)
+		},
+		statedb:    statedb,
+		cheatcodes: NewCheatcodes(),
+	}, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/stretchr/testify/require"
+)
+
+var forkedValue = common.Hash{7}
+
+// makeForkDatadir creates a geth datadir with a chain whose genesis sets the
+// storage of the test contract.
+func makeForkDatadir(t *testing.T) (string, *types.Block) {
+	datadir := t.TempDir()
+	chaindata := filepath.Join(datadir, "geth", "chaindata")
+	db, err := rawdb.Open(rawdb.OpenOptions{Directory: chaindata, AncientsDirectory: filepath.Join(chaindata, "ancient"), Cache: 16, Handles: 16})
+	require.NoError(t, err)
+	defer db.Close()
+	tdb := triedb.NewDatabase(db, triedb.HashDefaults)
+	defer tdb.Close()
+	gspec := &core.Genesis{
+		Config:   params.TestChainConfig,
+		GasLimit: blockGasLimit,
+		Alloc: types.GenesisAlloc{
+			contractAddress: {Balance: common.Big0, Storage: map[common.Hash]common.Hash{{}: forkedValue}},
+		},
+	}
+	return datadir, gspec.MustCommit(db, tdb)
+}
+
+// forkTestPrecompile is a test contract implemented as a precompile, whose
+// tests check they run on top of the forked chain.
+type forkTestPrecompile struct {
+	abi abi.ABI
+}
+
+func (pc *forkTestPrecompile) IsStatic(input []byte) bool { return false }
+
+func (pc *forkTestPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	if _, err := pc.abi.MethodById(input); err != nil {
+		return nil, nil // setUp
+	}
+	if env.StorageLoad(common.Hash{}) != forkedValue {
+		return nil, errors.New("unexpected storage")
+	}
+	if env.GetBlockNumber() != 1 {
+		return nil, errors.New("unexpected block number")
+	}
+	env.StorageStore(common.Hash{}, common.Hash{8})
+	return nil, nil
+}
+
+func TestFork(t *testing.T) {
+	datadir, genesis := makeForkDatadir(t)
+
+	methods := make(map[string]abi.Method)
+	for _, name := range []string{"testA", "testB"} {
+		methods[name] = abi.NewMethod(name, name, abi.Function, "", false, false, nil, nil)
+	}
+	pc := &forkTestPrecompile{abi: abi.ABI{Methods: methods}}
+	registry := concrete.NewRegistry()
+	registry.AddPrecompiles(0, concrete.PrecompileMap{contractAddress: pc})
+
+	fork, err := openFork(ForkConfig{Datadir: datadir})
+	require.NoError(t, err)
+	r := newRunner(registry, TestConfig{})
+	r.fork = fork
+	t.Run("ForkTest", func(t *testing.T) {
+		r.runTestContract(goTester{t}, "ForkTest", nil, pc.abi)
+	})
+	require.NoError(t, fork.Close())
+	for _, result := range r.sortedResults() {
+		require.True(t, result.Passed, result.Failure)
+	}
+
+	_, err = openFork(ForkConfig{Datadir: datadir, Block: 5})
+	require.ErrorContains(t, err, "block 5 not found")
+	_, err = openFork(ForkConfig{Datadir: t.TempDir()})
+	require.Error(t, err)
+
+	// The changes made by the tests are not written to the datadir
+	db, err := rawdb.Open(rawdb.OpenOptions{Directory: filepath.Join(datadir, "geth", "chaindata"), Cache: 16, Handles: 16, ReadOnly: true})
+	require.NoError(t, err)
+	defer db.Close()
+	statedb, err := state.New(genesis.Root(), state.NewDatabase(db), nil)
+	require.NoError(t, err)
+	require.Equal(t, forkedValue, statedb.GetState(contractAddress, common.Hash{}))
+	require.Empty(t, statedb.GetCode(contractAddress))
+	require.Equal(t, genesis.Hash(), rawdb.ReadHeadBlockHash(db))
+}
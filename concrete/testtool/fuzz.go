@@ -0,0 +1,411 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type FuzzConfig struct {
+	Runs           int   // Number of inputs tried per fuzz test
+	Seed           int64 // Seed of the input generator, random if zero
+	MaxShrinks     int   // Maximum number of attempts to shrink a failing case
+	InvariantRuns  int   // Number of call sequences tried per invariant test
+	InvariantDepth int   // Number of calls per sequence
+}
+
+var DefaultFuzzConfig = FuzzConfig{
+	Runs:           256,
+	MaxShrinks:     512,
+	InvariantRuns:  32,
+	InvariantDepth: 16,
+}
+
+func (c FuzzConfig) withDefaults() FuzzConfig {
+	if c.Runs == 0 {
+		c.Runs = DefaultFuzzConfig.Runs
+	}
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+	if c.MaxShrinks == 0 {
+		c.MaxShrinks = DefaultFuzzConfig.MaxShrinks
+	}
+	if c.InvariantRuns == 0 {
+		c.InvariantRuns = DefaultFuzzConfig.InvariantRuns
+	}
+	if c.InvariantDepth == 0 {
+		c.InvariantDepth = DefaultFuzzConfig.InvariantDepth
+	}
+	return c
+}
+
+// fuzzResult is the outcome of a fuzz test.
+type fuzzResult struct {
+	runs           int
+	meanGas        uint64
+	medianGas      uint64
+	counterexample []reflect.Value // Shrunk failing arguments, nil if none was found
+}
+
+// fuzz runs a test method with parameters on random inputs until one fails,
+// and shrinks the failing inputs.
+func (r *runner) fuzz(bytecode []byte, method abi.Method, shouldFail bool, config FuzzConfig) (fuzzResult, error) {
+	gen := &valueGenerator{rng: rand.New(rand.NewSource(config.Seed)), addresses: []common.Address{senderAddress, contractAddress}}
+
+	fails := func(args []reflect.Value) (bool, uint64, error) {
+		calldata, err := packArgs(method, args)
+		if err != nil {
+			return false, 0, err
+		}
+		results, _, err := r.runCalls(bytecode, []testCall{{to: contractAddress, data: calldata}})
+		if err != nil {
+			return false, 0, err
+		}
+		return results[0].failed() != shouldFail, results[0].receipt.GasUsed, nil
+	}
+
+	var totalGas uint64
+	gasUsedByRun := make([]uint64, 0, config.Runs)
+	for run := 1; run <= config.Runs; run++ {
+		args := gen.args(method.Inputs)
+		failed, gasUsed, err := fails(args)
+		if err != nil {
+			return fuzzResult{}, err
+		}
+		totalGas += gasUsed
+		gasUsedByRun = append(gasUsedByRun, gasUsed)
+		if !failed {
+			continue
+		}
+		args = shrinkArgs(method.Inputs, args, config.MaxShrinks, func(args []reflect.Value) bool {
+			failed, _, err := fails(args)
+			return err == nil && failed
+		})
+		return fuzzResult{runs: run, meanGas: totalGas / uint64(run), counterexample: args}, nil
+	}
+	sort.Slice(gasUsedByRun, func(i, j int) bool { return gasUsedByRun[i] < gasUsedByRun[j] })
+	return fuzzResult{
+		runs:      config.Runs,
+		meanGas:   totalGas / uint64(config.Runs),
+		medianGas: gasUsedByRun[len(gasUsedByRun)/2],
+	}, nil
+}
+
+// runFuzzTest runs a fuzz test and reports the counterexample if one is found.
+func (r *runner) runFuzzTest(t tester, result *TestResult, bytecode []byte, ABI abi.ABI, method abi.Method, shouldFail bool) {
+	config := r.config.Fuzz.withDefaults()
+	res, err := r.fuzz(bytecode, method, shouldFail, config)
+	if err != nil {
+		result.fatal(t, err)
+	}
+	result.Runs = res.runs
+	result.Gas = res.meanGas
+	if res.counterexample != nil {
+		calldata, _ := packArgs(method, res.counterexample)
+		r.traceFailure(t, result, bytecode, []testCall{{to: contractAddress, data: calldata}}, ABI)
+		result.errorf(t, "Counterexample found after %d runs (seed %d):\ncalldata=0x%x\nargs=%s", res.runs, config.Seed, calldata, formatArgs(res.counterexample))
+		return
+	}
+	t.Logf("Runs: %d, mean gas used: %d, median gas used: %d", res.runs, res.meanGas, res.medianGas)
+	entry := gasSnapshotEntry{Test: result.Contract + ":" + method.Sig, Gas: res.meanGas, Runs: res.runs, Median: res.medianGas}
+	if err := r.gas.recordTest(entry, r.config.GasTolerance); err != nil {
+		result.errorf(t, "%v", err)
+	}
+}
+
+func packArgs(method abi.Method, args []reflect.Value) ([]byte, error) {
+	values := make([]interface{}, len(args))
+	for ii, arg := range args {
+		values[ii] = arg.Interface()
+	}
+	input, err := method.Inputs.Pack(values...)
+	if err != nil {
+		return nil, err
+	}
+	return append(common.CopyBytes(method.ID), input...), nil
+}
+
+func formatArgs(args []reflect.Value) string {
+	strs := make([]string, len(args))
+	for ii, arg := range args {
+		strs[ii] = formatValue(arg)
+	}
+	return "(" + strings.Join(strs, ", ") + ")"
+}
+
+func formatValue(v reflect.Value) string {
+	switch value := v.Interface().(type) {
+	case []byte:
+		return fmt.Sprintf("0x%x", value)
+	case common.Address:
+		return value.Hex()
+	case *big.Int:
+		return value.String()
+	}
+	switch v.Kind() {
+	case reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			data := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(data), v)
+			return fmt.Sprintf("0x%x", data)
+		}
+		fallthrough
+	case reflect.Slice:
+		strs := make([]string, v.Len())
+		for ii := range strs {
+			strs[ii] = formatValue(v.Index(ii))
+		}
+		return "[" + strings.Join(strs, ", ") + "]"
+	case reflect.Struct:
+		strs := make([]string, v.NumField())
+		for ii := range strs {
+			strs[ii] = formatValue(v.Field(ii))
+		}
+		return "(" + strings.Join(strs, ", ") + ")"
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+const (
+	maxFuzzLength = 32 // Maximum length of generated dynamic values
+	edgeCaseRatio = 8  // One in edgeCaseRatio integers is an edge case
+)
+
+// valueGenerator generates random values of ABI types.
+type valueGenerator struct {
+	rng *rand.Rand
+	// Addresses known to the test, used for some of the generated addresses
+	addresses []common.Address
+}
+
+func (g *valueGenerator) args(arguments abi.Arguments) []reflect.Value {
+	args := make([]reflect.Value, len(arguments))
+	for ii, arg := range arguments {
+		args[ii] = g.value(arg.Type)
+	}
+	return args
+}
+
+func (g *valueGenerator) value(t abi.Type) reflect.Value {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		return intValue(t, g.integer(t))
+	case abi.BoolTy:
+		return reflect.ValueOf(g.rng.Intn(2) == 1)
+	case abi.AddressTy:
+		if len(g.addresses) > 0 && g.rng.Intn(2) == 0 {
+			return reflect.ValueOf(g.addresses[g.rng.Intn(len(g.addresses))])
+		}
+		var address common.Address
+		g.rng.Read(address[:])
+		return reflect.ValueOf(address)
+	case abi.StringTy:
+		b := make([]byte, g.rng.Intn(maxFuzzLength+1))
+		for ii := range b {
+			b[ii] = byte(' ' + g.rng.Intn('~'-' '+1))
+		}
+		return reflect.ValueOf(string(b))
+	case abi.BytesTy:
+		b := make([]byte, g.rng.Intn(maxFuzzLength+1))
+		g.rng.Read(b)
+		return reflect.ValueOf(b)
+	case abi.FixedBytesTy:
+		v := reflect.New(t.GetType()).Elem()
+		for ii := 0; ii < t.Size; ii++ {
+			v.Index(ii).SetUint(uint64(g.rng.Intn(256)))
+		}
+		return v
+	case abi.SliceTy:
+		n := g.rng.Intn(maxFuzzLength/4 + 1)
+		v := reflect.MakeSlice(t.GetType(), n, n)
+		for ii := 0; ii < n; ii++ {
+			v.Index(ii).Set(g.value(*t.Elem))
+		}
+		return v
+	case abi.ArrayTy:
+		v := reflect.New(t.GetType()).Elem()
+		for ii := 0; ii < t.Size; ii++ {
+			v.Index(ii).Set(g.value(*t.Elem))
+		}
+		return v
+	case abi.TupleTy:
+		v := reflect.New(t.GetType()).Elem()
+		for ii, elem := range t.TupleElems {
+			v.Field(ii).Set(g.value(*elem))
+		}
+		return v
+	}
+	return reflect.Zero(t.GetType())
+}
+
+// integer returns a random integer of the given type, biased towards values of
+// small bit length and edge cases.
+func (g *valueGenerator) integer(t abi.Type) *big.Int {
+	bits := t.Size
+	signed := t.T == abi.IntTy
+	if signed {
+		bits--
+	}
+	max := new(big.Int).Sub(new(big.Int).Lsh(common.Big1, uint(bits)), common.Big1)
+	if g.rng.Intn(edgeCaseRatio) == 0 {
+		edges := []*big.Int{common.Big0, common.Big1, max}
+		if signed {
+			edges = append(edges, big.NewInt(-1), new(big.Int).Neg(new(big.Int).Add(max, common.Big1)))
+		}
+		return new(big.Int).Set(edges[g.rng.Intn(len(edges))])
+	}
+	n := new(big.Int).Rand(g.rng, new(big.Int).Lsh(common.Big1, uint(1+g.rng.Intn(bits))))
+	if signed && g.rng.Intn(2) == 0 {
+		n.Neg(n)
+	}
+	return n
+}
+
+// intValue converts an integer to the Go type used by the abi package for t.
+func intValue(t abi.Type, n *big.Int) reflect.Value {
+	typ := t.GetType()
+	switch typ.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(n.Uint64()).Convert(typ)
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.ValueOf(n.Int64()).Convert(typ)
+	}
+	return reflect.ValueOf(n)
+}
+
+func bigValue(v reflect.Value) *big.Int {
+	switch v.Kind() {
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return new(big.Int).SetUint64(v.Uint())
+	case reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return big.NewInt(v.Int())
+	}
+	return v.Interface().(*big.Int)
+}
+
+// shrinkArgs repeatedly replaces the arguments with simpler values for which
+// fails still returns true, making at most maxAttempts calls to fails.
+func shrinkArgs(arguments abi.Arguments, args []reflect.Value, maxAttempts int, fails func([]reflect.Value) bool) []reflect.Value {
+	attempts := 0
+	for progress := true; progress && attempts < maxAttempts; {
+		progress = false
+		for ii := range args {
+			for _, candidate := range shrinkCandidates(arguments[ii].Type, args[ii]) {
+				if attempts >= maxAttempts {
+					return args
+				}
+				attempts++
+				trial := append([]reflect.Value{}, args...)
+				trial[ii] = candidate
+				if fails(trial) {
+					args = trial
+					progress = true
+					break
+				}
+			}
+		}
+	}
+	return args
+}
+
+// shrinkCandidates returns values simpler than v, simplest first.
+func shrinkCandidates(t abi.Type, v reflect.Value) []reflect.Value {
+	zero := reflect.Zero(t.GetType())
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		n := bigValue(v)
+		if n.Sign() == 0 {
+			return nil
+		}
+		// Binary search towards zero: n/2, 3n/4, ..., n-1
+		candidates := []reflect.Value{intValue(t, new(big.Int))}
+		for delta := new(big.Int).Quo(n, big.NewInt(2)); delta.Sign() != 0; delta.Quo(delta, big.NewInt(2)) {
+			candidates = append(candidates, intValue(t, new(big.Int).Sub(n, delta)))
+		}
+		if n.CmpAbs(common.Big1) > 0 {
+			candidates = append(candidates, intValue(t, new(big.Int).Sub(n, big.NewInt(int64(n.Sign())))))
+		}
+		return candidates
+	case abi.BoolTy, abi.AddressTy, abi.FixedBytesTy:
+		if v.IsZero() {
+			return nil
+		}
+		return []reflect.Value{zero}
+	case abi.StringTy:
+		s := v.String()
+		if len(s) == 0 {
+			return nil
+		}
+		return []reflect.Value{reflect.ValueOf(""), reflect.ValueOf(s[:len(s)/2]), reflect.ValueOf(s[1:])}
+	case abi.BytesTy, abi.SliceTy:
+		if v.Len() == 0 {
+			return nil
+		}
+		candidates := []reflect.Value{
+			reflect.MakeSlice(t.GetType(), 0, 0),
+			v.Slice(0, v.Len()/2),
+			v.Slice(1, v.Len()),
+		}
+		if t.T == abi.SliceTy {
+			candidates = append(candidates, shrinkElements(*t.Elem, v)...)
+		}
+		return candidates
+	case abi.ArrayTy:
+		return shrinkElements(*t.Elem, v)
+	case abi.TupleTy:
+		var candidates []reflect.Value
+		for ii, elem := range t.TupleElems {
+			for _, c := range shrinkCandidates(*elem, v.Field(ii)) {
+				candidate := reflect.New(v.Type()).Elem()
+				candidate.Set(v)
+				candidate.Field(ii).Set(c)
+				candidates = append(candidates, candidate)
+			}
+		}
+		return candidates
+	}
+	return nil
+}
+
+// shrinkElements returns copies of a slice or array with one element shrunk.
+func shrinkElements(elem abi.Type, v reflect.Value) []reflect.Value {
+	var candidates []reflect.Value
+	for ii := 0; ii < v.Len(); ii++ {
+		for _, c := range shrinkCandidates(elem, v.Index(ii)) {
+			var candidate reflect.Value
+			if v.Kind() == reflect.Slice {
+				candidate = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				reflect.Copy(candidate, v)
+			} else {
+				candidate = reflect.New(v.Type()).Elem()
+				candidate.Set(v)
+			}
+			candidate.Index(ii).Set(c)
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"errors"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/stretchr/testify/require"
+)
+
+// fuzzTestPrecompile is a test contract implemented as a precompile.
+type fuzzTestPrecompile struct {
+	abi abi.ABI
+}
+
+func newFuzzTestPrecompile() *fuzzTestPrecompile {
+	newMethod := func(name, mutability string, inputs string) abi.Method {
+		return abi.NewMethod(name, name, abi.Function, mutability, false, false, newArguments(inputs), nil)
+	}
+	return &fuzzTestPrecompile{abi: abi.ABI{Methods: map[string]abi.Method{
+		"testFuzzBelow":  newMethod("testFuzzBelow", "", "uint256"),
+		"testFuzzBytes":  newMethod("testFuzzBytes", "", "bytes,bool"),
+		"increment":      newMethod("increment", "", "uint8"),
+		"invariantBelow": newMethod("invariantBelow", "view", ""),
+	}}}
+}
+
+func (pc *fuzzTestPrecompile) IsStatic(input []byte) bool { return false }
+
+func (pc *fuzzTestPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	method, err := pc.abi.MethodById(input)
+	if err != nil {
+		return nil, nil // setUp
+	}
+	args, err := method.Inputs.Unpack(input[4:])
+	if err != nil {
+		return nil, err
+	}
+	counter := env.StorageLoad(common.Hash{}).Big()
+	switch method.Name {
+	case "testFuzzBelow":
+		if args[0].(*big.Int).Cmp(big.NewInt(1000)) >= 0 {
+			return nil, errors.New("too large")
+		}
+	case "testFuzzBytes":
+		if len(args[0].([]byte)) >= 3 && args[1].(bool) {
+			return nil, errors.New("too long")
+		}
+	case "increment":
+		if x := args[0].(uint8); x < 10 {
+			env.StorageStore(common.Hash{}, common.BigToHash(counter.Add(counter, big.NewInt(int64(x)))))
+		}
+	case "invariantBelow":
+		if counter.Cmp(big.NewInt(20)) >= 0 {
+			return nil, errors.New("invariant broken")
+		}
+	}
+	return nil, nil
+}
+
+func newFuzzTestRunner(pc concrete.Precompile) *runner {
+	registry := concrete.NewRegistry()
+	registry.AddPrecompiles(0, concrete.PrecompileMap{contractAddress: pc})
+	return newRunner(registry, TestConfig{})
+}
+
+func TestFuzz(t *testing.T) {
+	var (
+		pc     = newFuzzTestPrecompile()
+		r      = newFuzzTestRunner(pc)
+		config = FuzzConfig{Seed: 1}.withDefaults()
+	)
+	t.Run("Uint", func(t *testing.T) {
+		res, err := r.fuzz(nil, pc.abi.Methods["testFuzzBelow"], false, config)
+		require.NoError(t, err)
+		require.NotNil(t, res.counterexample)
+		require.Equal(t, "(1000)", formatArgs(res.counterexample))
+	})
+	t.Run("Bytes", func(t *testing.T) {
+		res, err := r.fuzz(nil, pc.abi.Methods["testFuzzBytes"], false, config)
+		require.NoError(t, err)
+		require.NotNil(t, res.counterexample)
+		require.Len(t, res.counterexample[0].Bytes(), 3)
+		require.Equal(t, true, res.counterexample[1].Bool())
+	})
+	t.Run("ShouldFail", func(t *testing.T) {
+		res, err := r.fuzz(nil, pc.abi.Methods["testFuzzBelow"], true, config)
+		require.NoError(t, err)
+		require.NotNil(t, res.counterexample)
+		require.Equal(t, "(0)", formatArgs(res.counterexample))
+	})
+}
+
+func TestFormatArgs(t *testing.T) {
+	args := []reflect.Value{
+		reflect.ValueOf([32]byte{1}),                           // Byte arrays unpacked from calls are not addressable
+		reflect.ValueOf(struct{ A [2]byte }{A: [2]byte{2, 3}}), // Nor are struct fields
+		reflect.ValueOf([]*big.Int{big.NewInt(4)}),
+	}
+	require.Equal(t, "(0x0100000000000000000000000000000000000000000000000000000000000000, (0x0203), [4])", formatArgs(args))
+}
+
+func TestInvariant(t *testing.T) {
+	var (
+		pc     = newFuzzTestPrecompile()
+		r      = newFuzzTestRunner(pc)
+		config = FuzzConfig{Seed: 1}.withDefaults()
+	)
+	seq, err := r.checkInvariant(goTester{t}, nil, pc.abi, pc.abi.Methods["invariantBelow"], config)
+	require.NoError(t, err)
+	require.NotEmpty(t, seq)
+	sum := 0
+	for _, call := range seq {
+		require.Equal(t, "increment", call.method.Name)
+		sum += int(call.args[0].Uint())
+	}
+	require.GreaterOrEqual(t, sum, 20)
+	// No call can be removed from the shrunk sequence
+	for _, call := range seq {
+		require.Less(t, sum-int(call.args[0].Uint()), 20)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bufio"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// PrecompileGas is the gas used by the calls to a concrete precompile.
+type PrecompileGas struct {
+	Calls   int
+	Total   uint64
+	Min     uint64
+	Max     uint64
+	ByClass map[string]uint64 // Gas used by environment operations, by opcode class
+}
+
+func (g *PrecompileGas) addCall(gasUsed uint64) {
+	if g.Calls == 0 || gasUsed < g.Min {
+		g.Min = gasUsed
+	}
+	if gasUsed > g.Max {
+		g.Max = gasUsed
+	}
+	g.Calls++
+	g.Total += gasUsed
+}
+
+func (g *PrecompileGas) merge(other *PrecompileGas) {
+	if other.Calls > 0 && (g.Calls == 0 || other.Min < g.Min) {
+		g.Min = other.Min
+	}
+	if other.Max > g.Max {
+		g.Max = other.Max
+	}
+	g.Calls += other.Calls
+	g.Total += other.Total
+	for class, gas := range other.ByClass {
+		g.ByClass[class] += gas
+	}
+}
+
+func newPrecompileGas() *PrecompileGas {
+	return &PrecompileGas{ByClass: make(map[string]uint64)}
+}
+
+// gasTracer accounts the gas used by the calls to concrete precompiles and by
+// the environment operations they execute.
+type gasTracer struct {
+	precompiles map[common.Address]struct{}
+	frames      []common.Address
+	gas         map[common.Address]*PrecompileGas
+}
+
+func newGasTracer(precompiles map[common.Address]struct{}) *gasTracer {
+	return &gasTracer{
+		precompiles: precompiles,
+		gas:         make(map[common.Address]*PrecompileGas),
+	}
+}
+
+func (t *gasTracer) precompileGas(address common.Address) *PrecompileGas {
+	gas, ok := t.gas[address]
+	if !ok {
+		gas = newPrecompileGas()
+		t.gas[address] = gas
+	}
+	return gas
+}
+
+func (t *gasTracer) enter(to common.Address) {
+	t.frames = append(t.frames, to)
+}
+
+func (t *gasTracer) exit(gasUsed uint64) {
+	if len(t.frames) == 0 {
+		return
+	}
+	to := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+	if _, ok := t.precompiles[to]; ok {
+		t.precompileGas(to).addCall(gasUsed)
+	}
+}
+
+func (t *gasTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *gasTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *gasTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.enter(to)
+}
+
+func (t *gasTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit(gasUsed)
+}
+
+func (t *gasTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.enter(to)
+}
+
+func (t *gasTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.exit(gasUsed)
+}
+
+func (t *gasTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+}
+
+func (t *gasTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *gasTracer) CaptureEnvGas(address common.Address, op api.OpCode, gas uint64) {
+	if _, ok := t.precompiles[address]; ok {
+		t.precompileGas(address).ByClass[op.Class()] += gas
+	}
+}
+
+var (
+	_ vm.EVMLogger  = (*gasTracer)(nil)
+	_ api.GasTracer = (*gasTracer)(nil)
+)
+
+// gasSnapshotEntry is the gas used by a test, as stored in a gas snapshot.
+type gasSnapshotEntry struct {
+	Test   string // Contract:method(types)
+	Gas    uint64 // Gas used, or mean gas used if the test is a fuzz test
+	Runs   int    // Number of runs of a fuzz test, zero otherwise
+	Median uint64 // Median gas used of a fuzz test
+}
+
+func (e gasSnapshotEntry) String() string {
+	if e.Runs > 0 {
+		return fmt.Sprintf("%s (runs: %d, μ: %d, ~: %d)", e.Test, e.Runs, e.Gas, e.Median)
+	}
+	return fmt.Sprintf("%s (gas: %d)", e.Test, e.Gas)
+}
+
+var (
+	gasSnapshotRegexp     = regexp.MustCompile(`^(\S+) \(gas: (\d+)\)$`)
+	fuzzGasSnapshotRegexp = regexp.MustCompile(`^(\S+) \(runs: (\d+), μ: (\d+), ~: (\d+)\)$`)
+)
+
+func parseGasSnapshotEntry(line string) (gasSnapshotEntry, error) {
+	if m := gasSnapshotRegexp.FindStringSubmatch(line); m != nil {
+		gas, err := strconv.ParseUint(m[2], 10, 64)
+		return gasSnapshotEntry{Test: m[1], Gas: gas}, err
+	}
+	if m := fuzzGasSnapshotRegexp.FindStringSubmatch(line); m != nil {
+		runs, err := strconv.Atoi(m[2])
+		if err != nil {
+			return gasSnapshotEntry{}, err
+		}
+		mean, err := strconv.ParseUint(m[3], 10, 64)
+		if err != nil {
+			return gasSnapshotEntry{}, err
+		}
+		median, err := strconv.ParseUint(m[4], 10, 64)
+		return gasSnapshotEntry{Test: m[1], Gas: mean, Runs: runs, Median: median}, err
+	}
+	return gasSnapshotEntry{}, fmt.Errorf("invalid gas snapshot entry: %q", line)
+}
+
+// gasSnapshot is the gas used by every test, in the format of Foundry's
+// .gas-snapshot files.
+type gasSnapshot map[string]gasSnapshotEntry
+
+// readGasSnapshot reads a gas snapshot file. A missing file is an empty snapshot.
+func readGasSnapshot(path string) (gasSnapshot, error) {
+	snapshot := make(gasSnapshot)
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return snapshot, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		entry, err := parseGasSnapshotEntry(line)
+		if err != nil {
+			return nil, err
+		}
+		snapshot[entry.Test] = entry
+	}
+	return snapshot, scanner.Err()
+}
+
+func (s gasSnapshot) String() string {
+	tests := make([]string, 0, len(s))
+	for test := range s {
+		tests = append(tests, test)
+	}
+	sort.Strings(tests)
+	var sb strings.Builder
+	for _, test := range tests {
+		sb.WriteString(s[test].String())
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+func (s gasSnapshot) write(path string) error {
+	return os.WriteFile(path, []byte(s.String()), 0644)
+}
+
+// checkGasRegression returns an error if the gas used by a test exceeds the gas
+// stored in the snapshot by more than the given fraction of it.
+func checkGasRegression(stored, current gasSnapshotEntry, tolerance float64) error {
+	limit := float64(stored.Gas) * (1 + tolerance)
+	if float64(current.Gas) <= limit {
+		return nil
+	}
+	change := 100 * (float64(current.Gas) - float64(stored.Gas)) / float64(stored.Gas)
+	return fmt.Errorf("gas regression in %s: %d -> %d (+%.2f%%, tolerance %.2f%%)", current.Test, stored.Gas, current.Gas, change, 100*tolerance)
+}
+
+// gasRecorder collects the gas used by tests and concrete precompiles during a
+// test run.
+type gasRecorder struct {
+	mu          sync.Mutex
+	stored      gasSnapshot
+	tests       gasSnapshot
+	precompiles map[common.Address]*PrecompileGas
+	regressions int
+}
+
+func newGasRecorder(stored gasSnapshot) *gasRecorder {
+	if stored == nil {
+		stored = make(gasSnapshot)
+	}
+	return &gasRecorder{
+		stored:      stored,
+		tests:       make(gasSnapshot),
+		precompiles: make(map[common.Address]*PrecompileGas),
+	}
+}
+
+// recordTest records the gas used by a test and checks it against the stored
+// snapshot.
+func (g *gasRecorder) recordTest(entry gasSnapshotEntry, tolerance float64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tests[entry.Test] = entry
+	stored, ok := g.stored[entry.Test]
+	if !ok {
+		return nil
+	}
+	if err := checkGasRegression(stored, entry, tolerance); err != nil {
+		g.regressions++
+		return err
+	}
+	return nil
+}
+
+func (g *gasRecorder) recordPrecompiles(tracer *gasTracer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for address, gas := range tracer.gas {
+		total, ok := g.precompiles[address]
+		if !ok {
+			total = newPrecompileGas()
+			g.precompiles[address] = total
+		}
+		total.merge(gas)
+	}
+}
+
+// snapshot returns the stored snapshot updated with the gas used by the tests
+// that were run.
+func (g *gasRecorder) snapshot() gasSnapshot {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	snapshot := make(gasSnapshot, len(g.stored)+len(g.tests))
+	for test, entry := range g.stored {
+		snapshot[test] = entry
+	}
+	for test, entry := range g.tests {
+		snapshot[test] = entry
+	}
+	return snapshot
+}
+
+// report formats the gas used by the tests and concrete precompiles as a table.
+func (g *gasRecorder) report() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var sb strings.Builder
+
+	sb.WriteString("\nPrecompile gas report\n")
+	addresses := make([]common.Address, 0, len(g.precompiles))
+	for address := range g.precompiles {
+		addresses = append(addresses, address)
+	}
+	sort.Slice(addresses, func(i, j int) bool { return addresses[i].Cmp(addresses[j]) < 0 })
+	for _, address := range addresses {
+		gas := g.precompiles[address]
+		var avg uint64
+		if gas.Calls > 0 {
+			avg = gas.Total / uint64(gas.Calls)
+		}
+		fmt.Fprintf(&sb, "%s  calls: %d, min: %d, avg: %d, max: %d\n", address, gas.Calls, gas.Min, avg, gas.Max)
+		classes := make([]string, 0, len(gas.ByClass))
+		for class := range gas.ByClass {
+			classes = append(classes, class)
+		}
+		sort.Strings(classes)
+		for _, class := range classes {
+			fmt.Fprintf(&sb, "  %-16s %d\n", class+":", gas.ByClass[class])
+		}
+	}
+
+	sb.WriteString("\nTest gas report\n")
+	sb.WriteString(g.tests.String())
+	return sb.String()
+}
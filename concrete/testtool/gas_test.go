@@ -0,0 +1,85 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGasTracer(t *testing.T) {
+	var (
+		pc = newFuzzTestPrecompile()
+		r  = newFuzzTestRunner(pc)
+	)
+	calldata, err := pc.abi.Pack("increment", uint8(5))
+	require.NoError(t, err)
+	results, _, err := r.runCalls(nil, []testCall{{to: contractAddress, data: calldata}})
+	require.NoError(t, err)
+	require.False(t, results[0].failed())
+
+	gas, ok := r.gas.precompiles[contractAddress]
+	require.True(t, ok)
+	require.Equal(t, 2, gas.Calls) // setUp and increment
+	require.LessOrEqual(t, gas.Min, gas.Max)
+	require.Equal(t, gas.Min+gas.Max, gas.Total)
+	require.NotZero(t, gas.ByClass["internal reads"])
+	require.NotZero(t, gas.ByClass["internal writes"])
+}
+
+func TestGasSnapshot(t *testing.T) {
+	snapshot := gasSnapshot{
+		"Test:testA()":           {Test: "Test:testA()", Gas: 21000},
+		"Test:testFuzz(uint256)": {Test: "Test:testFuzz(uint256)", Gas: 30000, Runs: 256, Median: 29000},
+	}
+	require.Equal(t, "Test:testA() (gas: 21000)\nTest:testFuzz(uint256) (runs: 256, μ: 30000, ~: 29000)\n", snapshot.String())
+
+	path := filepath.Join(t.TempDir(), ".gas-snapshot")
+	empty, err := readGasSnapshot(path)
+	require.NoError(t, err)
+	require.Empty(t, empty)
+
+	require.NoError(t, snapshot.write(path))
+	read, err := readGasSnapshot(path)
+	require.NoError(t, err)
+	require.Equal(t, snapshot, read)
+
+	require.NoError(t, os.WriteFile(path, []byte("Test:testA() 21000\n"), 0644))
+	_, err = readGasSnapshot(path)
+	require.Error(t, err)
+}
+
+func TestGasRegression(t *testing.T) {
+	stored := gasSnapshotEntry{Test: "Test:testA()", Gas: 1000}
+	require.NoError(t, checkGasRegression(stored, gasSnapshotEntry{Test: "Test:testA()", Gas: 900}, 0))
+	require.NoError(t, checkGasRegression(stored, gasSnapshotEntry{Test: "Test:testA()", Gas: 1050}, 0.05))
+	require.Error(t, checkGasRegression(stored, gasSnapshotEntry{Test: "Test:testA()", Gas: 1051}, 0.05))
+	require.Error(t, checkGasRegression(stored, gasSnapshotEntry{Test: "Test:testA()", Gas: 1001}, 0))
+
+	g := newGasRecorder(gasSnapshot{stored.Test: stored, "Test:testB()": {Test: "Test:testB()", Gas: 500}})
+	require.NoError(t, g.recordTest(gasSnapshotEntry{Test: "Test:testC()", Gas: 100}, 0))
+	require.Error(t, g.recordTest(gasSnapshotEntry{Test: "Test:testA()", Gas: 2000}, 0.1))
+	require.Equal(t, 1, g.regressions)
+
+	snapshot := g.snapshot()
+	require.Len(t, snapshot, 3)
+	require.Equal(t, uint64(2000), snapshot["Test:testA()"].Gas)
+	require.Equal(t, uint64(500), snapshot["Test:testB()"].Gas)
+	require.Equal(t, uint64(100), snapshot["Test:testC()"].Gas)
+}
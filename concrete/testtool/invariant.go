@@ -0,0 +1,236 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Methods of the test contract that are never called by invariant tests
+var excludedMethodPrefixes = []string{"setUp", "test", "invariant", "target", "exclude", "IS_TEST", "failed"}
+
+// invariantTarget is a contract called by invariant tests.
+type invariantTarget struct {
+	address common.Address
+	methods []abi.Method
+}
+
+// invariantCall is a call made by an invariant test between invariant checks.
+type invariantCall struct {
+	target common.Address
+	method abi.Method
+	args   []reflect.Value
+}
+
+func (c invariantCall) testCall() (testCall, error) {
+	data, err := packArgs(c.method, c.args)
+	return testCall{to: c.target, data: data}, err
+}
+
+func (c invariantCall) String() string {
+	data, _ := packArgs(c.method, c.args)
+	return fmt.Sprintf("%s.%s%s calldata=0x%x", c.target.Hex(), c.method.Name, formatArgs(c.args), data)
+}
+
+// targetMethods returns the state changing methods of a contract, sorted by
+// name so that sequences are reproducible from the seed.
+func targetMethods(ABI abi.ABI, isTestContract bool) []abi.Method {
+	var methods []abi.Method
+	for _, method := range ABI.Methods {
+		if method.IsConstant() {
+			continue
+		}
+		if isTestContract && hasAnyPrefix(method.Name, excludedMethodPrefixes) {
+			continue
+		}
+		methods = append(methods, method)
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Sig < methods[j].Sig })
+	return methods
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// invariantTargets returns the contracts called by invariant tests. If the test
+// contract defines targetContracts(), the contracts it returns after setUp are
+// targeted with the ABIs found in the build artifacts. Otherwise the state
+// changing methods of the test contract itself are targeted.
+func (r *runner) invariantTargets(t tester, bytecode []byte, ABI abi.ABI) ([]invariantTarget, error) {
+	method, ok := ABI.Methods["targetContracts"]
+	if !ok {
+		return []invariantTarget{{address: contractAddress, methods: targetMethods(ABI, true)}}, nil
+	}
+	results, statedb, err := r.runCalls(bytecode, []testCall{{to: contractAddress, data: method.ID}})
+	if err != nil {
+		return nil, err
+	}
+	if results[0].failed() {
+		return nil, errors.New("targetContracts() reverted")
+	}
+	out, err := method.Outputs.Unpack(results[0].output)
+	if err != nil {
+		return nil, err
+	}
+	addresses, ok := out[0].([]common.Address)
+	if !ok {
+		return nil, errors.New("targetContracts() must return address[]")
+	}
+	var targets []invariantTarget
+	for _, address := range addresses {
+		targetABI, ok := r.artifacts[statedb.GetCodeHash(address)]
+		if !ok {
+			t.Logf("No ABI found for target contract %s, skipping", address.Hex())
+			continue
+		}
+		targets = append(targets, invariantTarget{address: address, methods: targetMethods(targetABI, address == contractAddress)})
+	}
+	return targets, nil
+}
+
+// checkInvariant checks an invariant method after every call of random
+// sequences of calls to the target contracts, ignoring calls that revert. It
+// returns the shrunk sequence that broke the invariant, or nil if none did.
+func (r *runner) checkInvariant(t tester, bytecode []byte, ABI abi.ABI, invariant abi.Method, config FuzzConfig) ([]invariantCall, error) {
+	targets, err := r.invariantTargets(t, bytecode, ABI)
+	if err != nil {
+		return nil, err
+	}
+	gen := &valueGenerator{rng: rand.New(rand.NewSource(config.Seed)), addresses: []common.Address{senderAddress, contractAddress}}
+	var methods []invariantCall
+	for _, target := range targets {
+		gen.addresses = append(gen.addresses, target.address)
+		for _, method := range target.methods {
+			methods = append(methods, invariantCall{target: target.address, method: method})
+		}
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("no target methods")
+	}
+
+	// brokenAt returns the number of calls after which the invariant is first
+	// broken, or -1 if it holds for the whole sequence.
+	brokenAt := func(seq []invariantCall) (int, error) {
+		check := testCall{to: contractAddress, data: invariant.ID}
+		calls := []testCall{check}
+		for _, call := range seq {
+			tc, err := call.testCall()
+			if err != nil {
+				return 0, err
+			}
+			calls = append(calls, tc, check)
+		}
+		results, _, err := r.runCalls(bytecode, calls)
+		if err != nil {
+			return 0, err
+		}
+		for ii := 0; ii < len(results); ii += 2 {
+			if results[ii].failed() {
+				return ii / 2, nil
+			}
+		}
+		return -1, nil
+	}
+
+	for run := 0; run < config.InvariantRuns; run++ {
+		seq := make([]invariantCall, config.InvariantDepth)
+		for ii := range seq {
+			call := methods[gen.rng.Intn(len(methods))]
+			call.args = gen.args(call.method.Inputs)
+			seq[ii] = call
+		}
+		broken, err := brokenAt(seq)
+		if err != nil {
+			return nil, err
+		}
+		if broken < 0 {
+			continue
+		}
+		seq = shrinkSequence(seq[:broken], config.MaxShrinks, func(seq []invariantCall) bool {
+			broken, err := brokenAt(seq)
+			return err == nil && broken >= 0
+		})
+		return seq, nil
+	}
+	return nil, nil
+}
+
+// runInvariantTest runs an invariant test and reports the call sequence that
+// broke the invariant if one is found.
+func (r *runner) runInvariantTest(t tester, result *TestResult, bytecode []byte, ABI abi.ABI, invariant abi.Method) {
+	config := r.config.Fuzz.withDefaults()
+	seq, err := r.checkInvariant(t, bytecode, ABI, invariant, config)
+	if err != nil {
+		result.fatal(t, err)
+	}
+	result.Runs = config.InvariantRuns
+	if seq == nil {
+		t.Logf("Runs: %d, calls: %d", config.InvariantRuns, config.InvariantRuns*config.InvariantDepth)
+		return
+	}
+	lines := make([]string, len(seq))
+	calls := make([]testCall, 0, len(seq)+1)
+	for ii, call := range seq {
+		lines[ii] = fmt.Sprintf("  %d. %s", ii+1, call)
+		if tc, err := call.testCall(); err == nil {
+			calls = append(calls, tc)
+		}
+	}
+	r.traceFailure(t, result, bytecode, append(calls, testCall{to: contractAddress, data: invariant.ID}), ABI)
+	result.errorf(t, "Invariant broken after %d calls (seed %d):\n%s", len(seq), config.Seed, strings.Join(lines, "\n"))
+}
+
+// shrinkSequence removes calls from a failing sequence and then shrinks the
+// arguments of the remaining calls while fails returns true.
+func shrinkSequence(seq []invariantCall, maxAttempts int, fails func([]invariantCall) bool) []invariantCall {
+	attempts := 0
+	for ii := 0; ii < len(seq) && attempts < maxAttempts; {
+		attempts++
+		trial := append(append([]invariantCall{}, seq[:ii]...), seq[ii+1:]...)
+		if fails(trial) {
+			seq = trial
+		} else {
+			ii++
+		}
+	}
+	for ii := range seq {
+		if attempts >= maxAttempts {
+			break
+		}
+		budget := (maxAttempts - attempts) / (len(seq) - ii)
+		seq[ii].args = shrinkArgs(seq[ii].method.Inputs, seq[ii].args, budget, func(args []reflect.Value) bool {
+			attempts++
+			trial := append([]invariantCall{}, seq...)
+			trial[ii].args = args
+			return fails(trial)
+		})
+	}
+	return seq
+}
@@ -0,0 +1,253 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+const (
+	UnitTestKind      = "test"
+	FuzzTestKind      = "fuzz"
+	InvariantTestKind = "invariant"
+)
+
+// TestResult is the outcome of a test, as written to the structured test output.
+type TestResult struct {
+	Contract string       `json:"contract"`
+	Method   string       `json:"method"`
+	Kind     string       `json:"kind"`
+	Passed   bool         `json:"passed"`
+	Gas      uint64       `json:"gas"`               // Mean gas used for fuzz tests
+	Runs     int          `json:"runs,omitempty"`    // Runs of fuzz and invariant tests
+	Reason   string       `json:"reason,omitempty"`  // Decoded revert reason of the test call
+	Failure  string       `json:"failure,omitempty"` // Why the test failed
+	Logs     []DecodedLog `json:"logs,omitempty"`
+	Trace    string       `json:"trace,omitempty"` // Call trace of the failing transaction
+	Elapsed  float64      `json:"elapsed"`         // Seconds
+}
+
+func (r *TestResult) errorf(t tester, format string, args ...interface{}) {
+	t.Helper()
+	msg := fmt.Sprintf(format, args...)
+	if r.Failure == "" {
+		r.Failure = msg
+	}
+	t.Error(msg)
+}
+
+func (r *TestResult) fatal(t tester, err error) {
+	t.Helper()
+	if r.Failure == "" {
+		r.Failure = err.Error()
+	}
+	t.Fatal(err)
+}
+
+// DecodedLog is a log emitted by a test, decoded with the ABI of its emitter
+// if it is known.
+type DecodedLog struct {
+	Address common.Address    `json:"address"`
+	Event   string            `json:"event,omitempty"` // Event signature, empty if unknown
+	Args    map[string]string `json:"args,omitempty"`
+	Topics  []common.Hash     `json:"topics"`
+	Data    hexutil.Bytes     `json:"data"`
+}
+
+// formatArgs formats the decoded arguments of the log ordered by name.
+func (l DecodedLog) formatArgs() string {
+	names := make([]string, 0, len(l.Args))
+	for name := range l.Args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := make([]string, len(names))
+	for ii, name := range names {
+		args[ii] = name + ": " + l.Args[name]
+	}
+	return "(" + strings.Join(args, ", ") + ")"
+}
+
+func (l DecodedLog) String() string {
+	if l.Event == "" {
+		return fmt.Sprintf("%s topics=%v data=%s", l.Address, l.Topics, l.Data)
+	}
+	name, _, _ := strings.Cut(l.Event, "(")
+	return fmt.Sprintf("%s %s%s", l.Address, name, l.formatArgs())
+}
+
+// recordResult completes a test result once the test has finished and adds it
+// to the results of the run.
+func (r *runner) recordResult(t tester, result *TestResult, start time.Time) {
+	result.Passed = !t.Failed()
+	result.Elapsed = time.Since(start).Seconds()
+	r.resultsLock.Lock()
+	defer r.resultsLock.Unlock()
+	r.results = append(r.results, *result)
+}
+
+// sortedResults returns the results of the run ordered by contract and method.
+func (r *runner) sortedResults() []TestResult {
+	r.resultsLock.Lock()
+	defer r.resultsLock.Unlock()
+	results := append([]TestResult(nil), r.results...)
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Contract != results[j].Contract {
+			return results[i].Contract < results[j].Contract
+		}
+		return results[i].Method < results[j].Method
+	})
+	return results
+}
+
+// WriteJSONResults writes test results as JSON lines, one result per line.
+func WriteJSONResults(w io.Writer, results []TestResult) error {
+	enc := json.NewEncoder(w)
+	for _, result := range results {
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name     string          `xml:"name,attr"`
+	Tests    int             `xml:"tests,attr"`
+	Failures int             `xml:"failures,attr"`
+	Time     string          `xml:"time,attr"`
+	Cases    []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+func junitTime(seconds float64) string {
+	return fmt.Sprintf("%.3f", seconds)
+}
+
+func newJUnitTestCase(result TestResult) junitTestCase {
+	var out strings.Builder
+	fmt.Fprintf(&out, "gas: %d\n", result.Gas)
+	if result.Runs > 0 {
+		fmt.Fprintf(&out, "runs: %d\n", result.Runs)
+	}
+	if result.Reason != "" {
+		fmt.Fprintf(&out, "reason: %s\n", result.Reason)
+	}
+	for ii, log := range result.Logs {
+		fmt.Fprintf(&out, "logs[%d]: %s\n", ii, log)
+	}
+	testCase := junitTestCase{
+		Name:      result.Method,
+		Classname: result.Contract,
+		Time:      junitTime(result.Elapsed),
+		SystemOut: out.String(),
+	}
+	if !result.Passed {
+		message := result.Failure
+		if message == "" {
+			message = "test failed"
+		}
+		testCase.Failure = &junitFailure{Message: strings.SplitN(message, "\n", 2)[0], Text: message}
+	}
+	return testCase
+}
+
+// WriteJUnitResults writes test results as a JUnit XML report with a test suite
+// per contract.
+func WriteJUnitResults(w io.Writer, results []TestResult) error {
+	var (
+		report       junitTestSuites
+		suites       = make(map[string]int)
+		elapsed      float64
+		suiteElapsed []float64
+	)
+	for _, result := range results {
+		idx, ok := suites[result.Contract]
+		if !ok {
+			idx = len(report.Suites)
+			suites[result.Contract] = idx
+			report.Suites = append(report.Suites, junitTestSuite{Name: result.Contract})
+			suiteElapsed = append(suiteElapsed, 0)
+		}
+		suite := &report.Suites[idx]
+		suite.Cases = append(suite.Cases, newJUnitTestCase(result))
+		suite.Tests++
+		report.Tests++
+		if !result.Passed {
+			suite.Failures++
+			report.Failures++
+		}
+		elapsed += result.Elapsed
+		suiteElapsed[idx] += result.Elapsed
+	}
+	report.Time = junitTime(elapsed)
+	for ii := range report.Suites {
+		report.Suites[ii].Time = junitTime(suiteElapsed[ii])
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func writeResultsFile(path string, results []TestResult, write func(io.Writer, []TestResult) error) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(file, results); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,302 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/stretchr/testify/require"
+)
+
+// resultsTestPrecompile is a test contract implemented as a precompile.
+type resultsTestPrecompile struct {
+	abi abi.ABI
+}
+
+func newResultsTestPrecompile() *resultsTestPrecompile {
+	newMethod := func(name string) abi.Method {
+		return abi.NewMethod(name, name, abi.Function, "", false, false, nil, nil)
+	}
+	event := abi.NewEvent("Value", "Value", false, abi.Arguments{
+		{Name: "key", Type: newArguments("uint256")[0].Type, Indexed: true},
+		{Name: "value", Type: newArguments("uint256")[0].Type},
+	})
+	return &resultsTestPrecompile{abi: abi.ABI{
+		Methods: map[string]abi.Method{
+			"testLog":        newMethod("testLog"),
+			"testFailRevert": newMethod("testFailRevert"),
+			"testFailError":  newMethod("testFailError"),
+			"testFailCustom": newMethod("testFailCustom"),
+		},
+		Events: map[string]abi.Event{"Value": event},
+		Errors: map[string]abi.Error{"Insufficient": abi.NewError("Insufficient", newArguments("uint256,uint256"))},
+	}}
+}
+
+func (pc *resultsTestPrecompile) IsStatic(input []byte) bool { return false }
+
+func (pc *resultsTestPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	method, err := pc.abi.MethodById(input)
+	if err != nil {
+		return nil, nil // setUp
+	}
+	switch method.Name {
+	case "testLog":
+		data, _ := pc.abi.Events["Value"].Inputs.NonIndexed().Pack(big.NewInt(2))
+		env.Log([]common.Hash{pc.abi.Events["Value"].ID, common.BigToHash(big.NewInt(1))}, data)
+	case "testFailRevert":
+		return nil, errors.New("plain reason")
+	case "testFailError":
+		reason, _ := newArguments("string").Pack("abi reason")
+		return nil, errors.New(string(append(common.FromHex("0x08c379a0"), reason...)))
+	case "testFailCustom":
+		cheat(env, "warp(uint256)", big.NewInt(100))
+		abiErr := pc.abi.Errors["Insufficient"]
+		data, _ := abiErr.Inputs.Pack(big.NewInt(1), big.NewInt(2))
+		return nil, errors.New(string(append(abiErr.ID[:4], data...)))
+	}
+	return nil, nil
+}
+
+func TestResults(t *testing.T) {
+	var (
+		pc       = newResultsTestPrecompile()
+		registry = concrete.NewRegistry()
+	)
+	registry.AddPrecompiles(0, concrete.PrecompileMap{contractAddress: pc})
+	r := newRunner(registry, TestConfig{})
+	t.Run("ResultsTest", func(t *testing.T) {
+		r.runTestContract(goTester{t}, "ResultsTest", nil, pc.abi)
+	})
+
+	results := r.sortedResults()
+	require.Len(t, results, 4)
+	byMethod := make(map[string]TestResult)
+	for _, result := range results {
+		require.Equal(t, "ResultsTest", result.Contract)
+		require.Equal(t, UnitTestKind, result.Kind)
+		require.True(t, result.Passed)
+		require.NotZero(t, result.Gas)
+		byMethod[result.Method] = result
+	}
+
+	logResult := byMethod["testLog()"]
+	require.Len(t, logResult.Logs, 1)
+	require.Equal(t, "Value(uint256,uint256)", logResult.Logs[0].Event)
+	require.Equal(t, map[string]string{"key": "1", "value": "2"}, logResult.Logs[0].Args)
+
+	require.Equal(t, "plain reason", byMethod["testFailRevert()"].Reason)
+	require.Equal(t, "abi reason", byMethod["testFailError()"].Reason)
+	require.Equal(t, "Insufficient(1, 2)", byMethod["testFailCustom()"].Reason)
+
+	// Mark a test as failed to check how failures are reported
+	results[0].Passed = false
+	results[0].Failure = "Test failed: reason\nmore details"
+
+	t.Run("JSON", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteJSONResults(&buf, results))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 4)
+		var decoded TestResult
+		require.NoError(t, json.Unmarshal([]byte(lines[0]), &decoded))
+		require.Equal(t, results[0], decoded)
+	})
+	t.Run("JUnit", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteJUnitResults(&buf, results))
+		var report junitTestSuites
+		require.NoError(t, xml.Unmarshal(buf.Bytes(), &report))
+		require.Equal(t, 4, report.Tests)
+		require.Equal(t, 1, report.Failures)
+		require.Len(t, report.Suites, 1)
+		require.Equal(t, "ResultsTest", report.Suites[0].Name)
+		require.Len(t, report.Suites[0].Cases, 4)
+		failure := report.Suites[0].Cases[0].Failure
+		require.NotNil(t, failure)
+		require.Equal(t, "Test failed: reason", failure.Message)
+		require.Equal(t, results[0].Failure, failure.Text)
+		require.Nil(t, report.Suites[0].Cases[1].Failure)
+	})
+}
+
+func TestDecode(t *testing.T) {
+	var (
+		pc       = newResultsTestPrecompile()
+		registry = concrete.NewRegistry()
+	)
+	registry.AddPrecompiles(0, concrete.PrecompileMap{contractAddress: pc})
+	r := newRunner(registry, TestConfig{})
+
+	// An artifact declaring an error and an event the test contract doesn't
+	artifact := abi.ABI{
+		Events: map[string]abi.Event{"Other": abi.NewEvent("Other", "Other", false, abi.Arguments{
+			{Name: "who", Type: newArguments("address")[0].Type, Indexed: true},
+		})},
+		Errors: map[string]abi.Error{"Unauthorized": abi.NewError("Unauthorized", newArguments("address"))},
+	}
+	r.indexABI(artifact)
+	dec := r.newDecoder(pc.abi, nil)
+
+	t.Run("RevertReason", func(t *testing.T) {
+		unauthorized := artifact.Errors["Unauthorized"]
+		data, _ := unauthorized.Inputs.Pack(senderAddress)
+		panicData, _ := newArguments("uint256").Pack(big.NewInt(0x11))
+		for _, tc := range []struct {
+			output []byte
+			want   string
+		}{
+			{nil, ""},
+			{append(common.FromHex("0x4e487b71"), panicData...), "panic: arithmetic underflow or overflow (0x11)"},
+			{append(unauthorized.ID[:4], data...), "Unauthorized(" + senderAddress.Hex() + ")"},
+			{[]byte("plain reason"), "plain reason"},
+			{[]byte{0x01, 0x02}, "0x0102"},
+		} {
+			require.Equal(t, tc.want, dec.revertReason(tc.output))
+		}
+	})
+	t.Run("Log", func(t *testing.T) {
+		other := artifact.Events["Other"]
+		log := dec.log(common.Address{0x01}, []common.Hash{other.ID, common.BytesToHash(senderAddress.Bytes())}, nil)
+		require.Equal(t, "Other(address)", log.Event)
+		require.Equal(t, map[string]string{"who": senderAddress.Hex()}, log.Args)
+
+		unknown := dec.log(common.Address{0x01}, []common.Hash{{0x02}}, nil)
+		require.Empty(t, unknown.Event)
+	})
+	t.Run("Trace", func(t *testing.T) {
+		trace, err := r.traceCalls(nil, []testCall{{to: contractAddress, data: pc.abi.Methods["testFailCustom"].ID}}, pc.abi)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(trace), "\n")
+		require.Len(t, lines, 4)
+		require.Regexp(t, `^\[\d+\] 0xCC73570000000000000000000000000000000000::testFailCustom\(\)$`, lines[0])
+		require.Regexp(t, `^    \[\d+\] 0x7109709ECfa91a80626fF3989D68f67F5b1DD12D::warp\(100\)$`, lines[1])
+		require.Equal(t, "        ← ()", lines[2])
+		require.Equal(t, "    ← revert: Insufficient(1, 2)", lines[3])
+
+		trace, err = r.traceCalls(nil, []testCall{{to: contractAddress, data: pc.abi.Methods["testLog"].ID}}, pc.abi)
+		require.NoError(t, err)
+		require.Contains(t, trace, "    emit Value(key: 1, value: 2)\n")
+	})
+}
+
+// writeResultsArtifact writes the artifact of the ResultsTest contract to path.
+// The test contract is the precompile, so the artifact has no code.
+func writeResultsArtifact(t *testing.T, path string, pc *resultsTestPrecompile) {
+	var methods []string
+	for name := range pc.abi.Methods {
+		methods = append(methods, fmt.Sprintf(`{"type":"function","name":"%s","inputs":[],"outputs":[]}`, name))
+	}
+	data := fmt.Sprintf(`{"abi":[%s],"deployedBytecode":{"object":"0x"},"metadata":{"settings":{"compilationTarget":{"Results.t.sol":"ResultsTest"}}}}`, strings.Join(methods, ","))
+	require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+}
+
+func TestFilters(t *testing.T) {
+	var (
+		pc       = newResultsTestPrecompile()
+		registry = concrete.NewRegistry()
+		artifact = filepath.Join(t.TempDir(), "ResultsTest.json")
+	)
+	registry.AddPrecompiles(0, concrete.PrecompileMap{contractAddress: pc})
+	writeResultsArtifact(t, artifact, pc)
+
+	run := func(config TestConfig) []TestResult {
+		r := newRunner(registry, config)
+		require.NoError(t, r.compileFilters())
+		t.Run("Filter", func(t *testing.T) {
+			require.NoError(t, r.runTestPaths(goTester{t}, []string{artifact}))
+		})
+		return r.sortedResults()
+	}
+
+	results := run(TestConfig{MatchTest: "^testFail(Revert|Error)$"})
+	require.Len(t, results, 2)
+	require.Equal(t, "testFailError()", results[0].Method)
+	require.Equal(t, "testFailRevert()", results[1].Method)
+
+	require.Len(t, run(TestConfig{MatchContract: "Results"}), 4)
+	require.Empty(t, run(TestConfig{MatchContract: "Other"}))
+
+	err := newRunner(registry, TestConfig{MatchTest: "("}).compileFilters()
+	require.ErrorContains(t, err, "invalid test filter")
+}
+
+// concurrencyTestPrecompile records how many of its tests run at once.
+type concurrencyTestPrecompile struct {
+	abi      abi.ABI
+	running  atomic.Int32
+	maxCount atomic.Int32
+}
+
+func (pc *concurrencyTestPrecompile) IsStatic(input []byte) bool { return false }
+
+func (pc *concurrencyTestPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	if _, err := pc.abi.MethodById(input); err != nil {
+		return nil, nil // setUp
+	}
+	running := pc.running.Add(1)
+	defer pc.running.Add(-1)
+	for {
+		max := pc.maxCount.Load()
+		if running <= max || pc.maxCount.CompareAndSwap(max, running) {
+			break
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	return nil, nil
+}
+
+func TestParallel(t *testing.T) {
+	newMethod := func(name string) abi.Method {
+		return abi.NewMethod(name, name, abi.Function, "", false, false, nil, nil)
+	}
+	run := func(config TestConfig) *concurrencyTestPrecompile {
+		pc := &concurrencyTestPrecompile{abi: abi.ABI{Methods: map[string]abi.Method{
+			"testA": newMethod("testA"),
+			"testB": newMethod("testB"),
+			"testC": newMethod("testC"),
+		}}}
+		registry := concrete.NewRegistry()
+		registry.AddPrecompiles(0, concrete.PrecompileMap{contractAddress: pc})
+		r := newRunner(registry, config)
+		t.Run("ConcurrencyTest", func(t *testing.T) {
+			r.runTestContract(goTester{t}, "ConcurrencyTest", nil, pc.abi)
+		})
+		for _, result := range r.sortedResults() {
+			require.True(t, result.Passed, result.Method)
+		}
+		return pc
+	}
+
+	// Tests run one at a time by default
+	require.Equal(t, int32(1), run(TestConfig{}).maxCount.Load())
+	require.NotZero(t, run(TestConfig{Parallel: true}).maxCount.Load())
+}
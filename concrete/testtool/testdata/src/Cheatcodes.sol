@@ -0,0 +1,108 @@
+// SPDX-License-Identifier: LGPL-3.0-only
+pragma solidity ^0.8.0;
+
+/*
+This contract tests the cheatcode precompile at the HEVM address
+*/
+
+interface Vm {
+    function warp(uint256) external;
+    function roll(uint256) external;
+    function deal(address, uint256) external;
+    function store(address, bytes32, bytes32) external;
+    function load(address, bytes32) external view returns (bytes32);
+    function prank(address) external;
+    function expectRevert(bytes calldata) external;
+    function expectEmit(bool, bool, bool, bool) external;
+    function record() external;
+    function accesses(address) external returns (bytes32[] memory reads, bytes32[] memory writes);
+}
+
+contract Target {
+    event Ping(address indexed sender, uint256 value);
+
+    uint256 public value;
+
+    function sender() external view returns (address) {
+        return msg.sender;
+    }
+
+    function fail() external pure {
+        revert("boom");
+    }
+
+    function ping(uint256 v) external {
+        emit Ping(msg.sender, v);
+    }
+
+    function set(uint256 v) external {
+        value = v;
+    }
+}
+
+contract CheatcodesTest {
+    event Ping(address indexed sender, uint256 value);
+
+    Vm constant vm = Vm(0x7109709ECfa91a80626fF3989D68f67F5b1DD12D);
+    Target target;
+
+    function setUp() external {
+        target = new Target();
+    }
+
+    function testWarp() external {
+        vm.warp(100);
+        require(block.timestamp == 100, "timestamp not set");
+    }
+
+    function testRoll() external {
+        vm.roll(7);
+        require(block.number == 7, "block number not set");
+    }
+
+    function testDeal() external {
+        vm.deal(address(1), 5);
+        require(address(1).balance == 5, "balance not set");
+    }
+
+    function testStoreLoad() external {
+        vm.store(address(target), bytes32(0), bytes32(uint256(3)));
+        require(target.value() == 3, "storage not set");
+        require(vm.load(address(target), bytes32(0)) == bytes32(uint256(3)), "storage not loaded");
+    }
+
+    function testPrank() external {
+        vm.prank(address(0xbeef));
+        require(target.sender() == address(0xbeef), "sender not pranked");
+        require(target.sender() == address(this), "prank not reset");
+    }
+
+    function testExpectRevert() external {
+        vm.expectRevert(bytes("boom"));
+        target.fail();
+    }
+
+    function testFailExpectRevert() external {
+        vm.expectRevert(bytes("boom"));
+        target.set(1);
+    }
+
+    function testExpectEmit() external {
+        vm.expectEmit(true, false, false, true);
+        emit Ping(address(this), 1);
+        target.ping(1);
+    }
+
+    function testFailExpectEmit() external {
+        vm.expectEmit(true, false, false, true);
+        emit Ping(address(this), 1);
+        target.ping(2);
+    }
+
+    function testRecord() external {
+        vm.record();
+        target.set(1);
+        (, bytes32[] memory writes) = vm.accesses(address(target));
+        require(writes.length == 1 && writes[0] == bytes32(0), "write not recorded");
+    }
+}
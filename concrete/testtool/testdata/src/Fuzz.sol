@@ -0,0 +1,45 @@
+// SPDX-License-Identifier: LGPL-3.0-only
+pragma solidity ^0.8.0;
+
+/*
+This contract tests fuzz and invariant tests:
+- `^testFuzz` functions are run with random arguments
+- `^invariant` functions are checked after random calls to the contracts returned by `targetContracts`
+*/
+
+contract Counter {
+    uint256 public count;
+
+    function increment(uint8 x) external {
+        if (x < 10) {
+            count += x;
+        }
+    }
+}
+
+contract FuzzTest {
+    Counter counter;
+
+    function setUp() external {
+        counter = new Counter();
+    }
+
+    function targetContracts() external view returns (address[] memory targets) {
+        targets = new address[](1);
+        targets[0] = address(counter);
+    }
+
+    function testFuzzAdd(uint128 a, uint128 b) external pure {
+        uint256 sum = uint256(a) + uint256(b);
+        require(sum >= a && sum >= b, "overflow");
+    }
+
+    function testFailFuzzBelow(uint256 x) external pure {
+        require(x < 1000, "too large");
+        revert("always fails");
+    }
+
+    function invariantCountBelowLimit() external view {
+        require(counter.count() < type(uint256).max, "count overflow");
+    }
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// tester is the part of testing.T used to run the tests of test contracts, so
+// they can be run both by go test and by the concrete CLI.
+type tester interface {
+	Helper()
+	Name() string
+	Log(args ...interface{})
+	Logf(format string, args ...interface{})
+	Error(args ...interface{})
+	Errorf(format string, args ...interface{})
+	Fatal(args ...interface{})
+	Fatalf(format string, args ...interface{})
+	Failed() bool
+	Parallel()
+	Run(name string, f func(t tester)) bool
+}
+
+// goTester runs tests as subtests of a go test.
+type goTester struct {
+	*testing.T
+}
+
+func (t goTester) Run(name string, f func(t tester)) bool {
+	return t.T.Run(name, func(t *testing.T) {
+		f(goTester{t})
+	})
+}
+
+// cliTester runs tests outside of go test. Subtests run one at a time, and
+// their results are written in the format of go test once they finish.
+type cliTester struct {
+	parent  *cliTester
+	name    string
+	verbose bool      // Write the output of passing tests
+	w       io.Writer // Output of the root tester
+
+	mu     sync.Mutex
+	output strings.Builder
+	failed bool
+}
+
+func newCLITester(w io.Writer, verbose bool) *cliTester {
+	return &cliTester{w: w, verbose: verbose}
+}
+
+func (t *cliTester) Helper() {}
+
+func (t *cliTester) Name() string { return t.name }
+
+func (t *cliTester) Parallel() {}
+
+func (t *cliTester) write(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.parent == nil {
+		io.WriteString(t.w, s)
+	} else {
+		t.output.WriteString(s)
+	}
+}
+
+func (t *cliTester) Log(args ...interface{}) {
+	t.write(fmt.Sprintln(args...))
+}
+
+func (t *cliTester) Logf(format string, args ...interface{}) {
+	t.write(strings.TrimSuffix(fmt.Sprintf(format, args...), "\n") + "\n")
+}
+
+func (t *cliTester) fail() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.failed = true
+}
+
+func (t *cliTester) Failed() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.failed
+}
+
+func (t *cliTester) Error(args ...interface{}) {
+	t.Log(args...)
+	t.fail()
+}
+
+func (t *cliTester) Errorf(format string, args ...interface{}) {
+	t.Logf(format, args...)
+	t.fail()
+}
+
+// Fatal and Fatalf stop the test like in go test, so they must be called from
+// the goroutine running it.
+func (t *cliTester) Fatal(args ...interface{}) {
+	t.Error(args...)
+	runtime.Goexit()
+}
+
+func (t *cliTester) Fatalf(format string, args ...interface{}) {
+	t.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (t *cliTester) Run(name string, f func(t tester)) bool {
+	sub := &cliTester{parent: t, name: name, verbose: t.verbose}
+	if t.parent != nil {
+		sub.name = t.name + "/" + name
+	}
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f(sub)
+	}()
+	<-done
+
+	failed := sub.Failed()
+	if failed {
+		t.fail()
+	}
+	if failed || t.verbose {
+		status := "PASS"
+		if failed {
+			status = "FAIL"
+		}
+		t.write(fmt.Sprintf("--- %s: %s (%.2fs)\n%s", status, sub.name, time.Since(start).Seconds(), indent(sub.output.String(), "    ")))
+	}
+	return !failed
+}
+
+// indent prefixes the non-empty lines of s.
+func indent(s, prefix string) string {
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(s, "\n") {
+		if line != "" && line != "\n" {
+			sb.WriteString(prefix)
+		}
+		sb.WriteString(line)
+	}
+	return sb.String()
+}
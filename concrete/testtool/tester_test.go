@@ -0,0 +1,89 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCLITester(t *testing.T) {
+	var (
+		r    = require.New(t)
+		buf  bytes.Buffer
+		root = newCLITester(&buf, false)
+	)
+	r.False(root.Run("Fatal", func(t tester) {
+		t.Log("before")
+		t.Fatal("stop")
+		t.Log("after")
+	}))
+	r.True(root.Run("Pass", func(t tester) {
+		t.Run("Sub", func(t tester) {
+			t.Log("hidden")
+		})
+	}))
+	r.True(root.Failed())
+	r.Regexp(`^--- FAIL: Fatal \(\d+\.\d{2}s\)\n    before\n    stop\n$`, buf.String())
+
+	buf.Reset()
+	root = newCLITester(&buf, true)
+	root.Run("Pass", func(t tester) {
+		t.Run("Sub", func(t tester) {
+			t.Logf("shown\n")
+		})
+	})
+	r.False(root.Failed())
+	r.Regexp(`^--- PASS: Pass \(\d+\.\d{2}s\)\n    --- PASS: Pass/Sub \(\d+\.\d{2}s\)\n        shown\n$`, buf.String())
+}
+
+func TestRun(t *testing.T) {
+	var (
+		r        = require.New(t)
+		pc       = newResultsTestPrecompile()
+		registry = concrete.NewRegistry()
+		dir      = t.TempDir()
+		config   = TestConfig{TestDir: filepath.Join(dir, "test"), OutDir: filepath.Join(dir, "out")}
+	)
+	registry.AddPrecompiles(0, concrete.PrecompileMap{contractAddress: pc})
+	r.NoError(os.MkdirAll(config.TestDir, 0755))
+	r.NoError(os.MkdirAll(filepath.Join(config.OutDir, "Results.t.sol"), 0755))
+	r.NoError(os.WriteFile(filepath.Join(config.TestDir, "Results.t.sol"), nil, 0644))
+	writeResultsArtifact(t, filepath.Join(config.OutDir, "Results.t.sol", "ResultsTest.json"), pc)
+
+	var buf bytes.Buffer
+	results, err := Run(&buf, registry, config, false)
+	r.NoError(err)
+	r.Len(results, 4)
+	for _, result := range results {
+		r.True(result.Passed)
+	}
+	r.Empty(buf.String(), "passed tests should only be written if verbose")
+
+	buf.Reset()
+	_, err = Run(&buf, registry, config, true)
+	r.NoError(err)
+	r.Contains(buf.String(), "--- PASS: ResultsTest/testLog (")
+
+	config.Contract = "Results.t.sol"
+	_, err = Run(&buf, registry, config, false)
+	r.ErrorContains(err, "invalid contract")
+}
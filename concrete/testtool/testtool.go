@@ -17,22 +17,26 @@ package testtool
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"math/big"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete"
-	"github.com/ethereum/go-ethereum/consensus/ethash"
-	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/ethereum/go-ethereum/params"
 	"golang.org/x/exp/slog"
 )
 
@@ -42,74 +46,206 @@ var (
 	PrintLogs = true
 )
 
-func runTestMethod(t *testing.T, concreteRegistry concrete.PrecompileRegistry, bytecode []byte, method abi.Method, shouldFail bool) {
-	var (
-		key, _          = crypto.HexToECDSA("d17bd946feb884d463d58fb702b94dd0457ca349338da1d732a57856cf777ccd") // 0xCcca11AbAC28D9b6FceD3a9CA73C434f6b33B215
-		senderAddress   = crypto.PubkeyToAddress(key.PublicKey)
-		contractAddress = common.HexToAddress("cc73570000000000000000000000000000000000")
-		gspec           = &core.Genesis{
-			GasLimit: 2e9,
-			Config:   params.TestChainConfig,
-			Alloc: types.GenesisAlloc{
-				senderAddress:   {Balance: big.NewInt(1e18)},
-				contractAddress: {Balance: common.Big0, Code: bytecode},
-			},
+var (
+	senderKey, _    = crypto.HexToECDSA("d17bd946feb884d463d58fb702b94dd0457ca349338da1d732a57856cf777ccd")
+	senderAddress   = crypto.PubkeyToAddress(senderKey.PublicKey) // 0xCcca11AbAC28D9b6FceD3a9CA73C434f6b33B215
+	contractAddress = common.HexToAddress("cc73570000000000000000000000000000000000")
+	txGasLimit      = uint64(1e7)
+	setupId         = crypto.Keccak256([]byte("setUp()"))[:4]
+)
+
+var errSetupFailed = errors.New("setup failed")
+
+// testCall is a transaction made by the test runner after setUp.
+type testCall struct {
+	to   common.Address
+	data []byte
+}
+
+// callResult is the outcome of a testCall.
+type callResult struct {
+	receipt *types.Receipt
+	output  []byte
+}
+
+func (r callResult) failed() bool {
+	return r.receipt.Status != types.ReceiptStatusSuccessful
+}
+
+// runner runs the tests of test contracts.
+type runner struct {
+	concreteRegistry concrete.PrecompileRegistry
+	config           TestConfig
+	// ABIs of the contracts built with the tests, by runtime code hash
+	artifacts map[common.Hash]abi.ABI
+	// Events and errors of all artifacts, by ID
+	events map[common.Hash][]abi.Event
+	errors map[[4]byte][]abi.Error
+	gas    *gasRecorder
+	// Filters on the names of the tests and test contracts to run, if not nil
+	matchTest     *regexp.Regexp
+	matchContract *regexp.Regexp
+
+	// State after setUp of every test contract, by code hash
+	setups     map[common.Hash]*setupState
+	setupsLock sync.Mutex
+	fork       *forkChain // Chain tests run on, a new chain if nil
+
+	results     []TestResult
+	resultsLock sync.Mutex
+}
+
+func newRunner(concreteRegistry concrete.PrecompileRegistry, config TestConfig) *runner {
+	return &runner{
+		concreteRegistry: concreteRegistry,
+		config:           config,
+		artifacts:        make(map[common.Hash]abi.ABI),
+		events:           make(map[common.Hash][]abi.Event),
+		errors:           make(map[[4]byte][]abi.Error),
+		gas:              newGasRecorder(nil),
+		setups:           make(map[common.Hash]*setupState),
+	}
+}
+
+// compileFilters compiles the test and contract name filters of the config.
+func (r *runner) compileFilters() error {
+	var err error
+	if r.config.MatchTest != "" {
+		if r.matchTest, err = regexp.Compile(r.config.MatchTest); err != nil {
+			return fmt.Errorf("invalid test filter: %w", err)
 		}
-		signer   = types.LatestSigner(gspec.Config)
-		gasLimit = uint64(1e7)
-		setupId  = crypto.Keccak256([]byte("setUp()"))[:4]
-	)
-
-	_, _, receipts := core.GenerateChainWithGenesisWithConcrete(gspec, ethash.NewFaker(), 1, concreteRegistry, func(ii int, block *core.BlockGen) {
-		for _, id := range [][]byte{setupId, method.ID} {
-			tx := types.NewTransaction(block.TxNonce(senderAddress), contractAddress, common.Big0, gasLimit, block.BaseFee(), id)
-			signed, err := types.SignTx(tx, signer, key)
-			if err != nil {
-				t.Fatal(err)
-			}
-			block.AddTx(signed)
+	}
+	if r.config.MatchContract != "" {
+		if r.matchContract, err = regexp.Compile(r.config.MatchContract); err != nil {
+			return fmt.Errorf("invalid contract filter: %w", err)
 		}
-	})
+	}
+	return nil
+}
+
+// runCalls makes the given calls, each in its own transaction, from the state
+// of the test contract after setUp. It returns the results of the calls and
+// the state after the last one.
+func (r *runner) runCalls(bytecode []byte, calls []testCall) ([]callResult, *state.StateDB, error) {
+	return r.runCallsWithTracer(bytecode, calls, nil)
+}
 
-	if len(receipts[0]) != 2 {
-		t.Fatalf("expected 2 receipts, got %d", len(receipts[0]))
+// runCallsWithTracer is like runCalls, but traces the calls with the given
+// tracer instead of accounting their gas.
+func (r *runner) runCallsWithTracer(bytecode []byte, calls []testCall, tracer vm.EVMLogger) ([]callResult, *state.StateDB, error) {
+	setup, err := r.setUp(bytecode)
+	if err != nil {
+		return nil, nil, err
+	}
+	statedb, cheatcodes := setup.copy()
+	gasTracer := newGasTracer(r.concreteRegistry.PrecompiledAddressesSet(setup.header.Number.Uint64()))
+	if tracer == nil {
+		tracer = gasTracer
 	}
-	setupReceipt := receipts[0][0]
-	testReceipt := receipts[0][1]
 
-	if setupReceipt.Status != types.ReceiptStatusSuccessful {
-		t.Fatal("setup failed")
+	usedGas := setup.usedGas
+	results := make([]callResult, len(calls))
+	for ii, call := range calls {
+		receipt, output, err := setup.applyCall(r.concreteRegistry, statedb, cheatcodes, call, ii+1, &usedGas, tracer)
+		if err != nil {
+			return nil, nil, err
+		}
+		results[ii] = callResult{receipt: receipt, output: output}
 	}
-	if (testReceipt.Status == types.ReceiptStatusSuccessful) == shouldFail {
-		t.Fail()
+	if tracer == gasTracer {
+		r.gas.recordPrecompiles(gasTracer)
+	}
+	return results, statedb, nil
+}
+
+func (r *runner) runTestMethod(t tester, result *TestResult, bytecode []byte, ABI abi.ABI, method abi.Method, shouldFail bool) {
+	results, statedb, err := r.runCalls(bytecode, []testCall{{to: contractAddress, data: method.ID}})
+	if err != nil {
+		result.fatal(t, err)
+	}
+	testReceipt := results[0].receipt
+	dec := r.newDecoder(ABI, statedb)
+	result.Gas = testReceipt.GasUsed
+	if results[0].failed() {
+		result.Reason = dec.revertReason(results[0].output)
+	}
+	result.Logs = dec.logs(testReceipt.Logs)
+
+	if results[0].failed() != shouldFail {
+		r.traceFailure(t, result, bytecode, []testCall{{to: contractAddress, data: method.ID}}, ABI)
+		if shouldFail {
+			result.errorf(t, "Expected test to fail")
+		} else if result.Reason != "" {
+			result.errorf(t, "Test failed: %s", result.Reason)
+		} else {
+			result.errorf(t, "Test failed")
+		}
 	}
 
 	t.Logf("Gas used: %d", testReceipt.GasUsed)
+	if !t.Failed() {
+		entry := gasSnapshotEntry{Test: result.Contract + ":" + method.Sig, Gas: testReceipt.GasUsed}
+		if err := r.gas.recordTest(entry, r.config.GasTolerance); err != nil {
+			result.errorf(t, "%v", err)
+		}
+	}
 
-	if PrintLogs && len(testReceipt.Logs) > 0 {
-		for ii, log := range testReceipt.Logs {
+	if PrintLogs && len(result.Logs) > 0 {
+		for ii, log := range result.Logs {
 			logStr := fmt.Sprintf("\nLogs[%d]\nAddress : %s\n", ii, log.Address)
-			if len(log.Topics) > 0 {
-				logStr += fmt.Sprintf("Topics  : %s\n", log.Topics[0].String())
-				for _, topic := range log.Topics[1:] {
-					logStr += fmt.Sprintf("         : %s\n", topic.String())
+			if log.Event != "" {
+				logStr += fmt.Sprintf("Event   : %s\n", log.Event)
+				logStr += fmt.Sprintf("Args    : %s\n", log.formatArgs())
+			} else {
+				if len(log.Topics) > 0 {
+					logStr += fmt.Sprintf("Topics  : %s\n", log.Topics[0].String())
+					for _, topic := range log.Topics[1:] {
+						logStr += fmt.Sprintf("         : %s\n", topic.String())
+					}
 				}
+				logStr += fmt.Sprintf("Data    : %s\n", log.Data)
 			}
-			logStr += fmt.Sprintf("Data    : 0x%x\n", log.Data)
 			t.Log(logStr)
 		}
 	}
 }
 
-func runTestContract(t *testing.T, concreteRegistry concrete.PrecompileRegistry, bytecode []byte, ABI abi.ABI) {
+// runTestContract runs the tests of a contract, each from a copy of the state
+// after setUp. With config.Parallel set, the tests finish once t's test function
+// returns.
+func (r *runner) runTestContract(t tester, contractName string, bytecode []byte, ABI abi.ABI) {
 	for _, method := range ABI.Methods {
-		if !strings.HasPrefix(method.Name, "test") {
+		method := method
+		if r.matchTest != nil && !r.matchTest.MatchString(method.Name) {
 			continue
 		}
-		t.Run(method.Name, func(t *testing.T) {
-			shouldFail := strings.HasPrefix(method.Name, "testFail")
-			runTestMethod(t, concreteRegistry, bytecode, method, shouldFail)
-		})
+		switch {
+		case strings.HasPrefix(method.Name, "test"):
+			t.Run(method.Name, func(t tester) {
+				if r.config.Parallel {
+					t.Parallel()
+				}
+				shouldFail := strings.HasPrefix(method.Name, "testFail")
+				if len(method.Inputs) > 0 {
+					result := &TestResult{Contract: contractName, Method: method.Sig, Kind: FuzzTestKind}
+					defer r.recordResult(t, result, time.Now())
+					r.runFuzzTest(t, result, bytecode, ABI, method, shouldFail)
+				} else {
+					result := &TestResult{Contract: contractName, Method: method.Sig, Kind: UnitTestKind}
+					defer r.recordResult(t, result, time.Now())
+					r.runTestMethod(t, result, bytecode, ABI, method, shouldFail)
+				}
+			})
+		case strings.HasPrefix(method.Name, "invariant") && len(method.Inputs) == 0:
+			t.Run(method.Name, func(t tester) {
+				if r.config.Parallel {
+					t.Parallel()
+				}
+				result := &TestResult{Contract: contractName, Method: method.Sig, Kind: InvariantTestKind}
+				defer r.recordResult(t, result, time.Now())
+				r.runInvariantTest(t, result, bytecode, ABI, method)
+			})
+		}
 	}
 }
 
@@ -195,16 +331,39 @@ func getTestPaths(testDir, outDir string) ([]string, error) {
 	return paths, nil
 }
 
-func runTestPaths(t *testing.T, concreteRegistry concrete.PrecompileRegistry, contractJsonPaths []string) {
+func (r *runner) runTestPaths(t tester, contractJsonPaths []string) error {
 	for _, path := range contractJsonPaths {
 		bytecode, ABI, testPath, contractName, err := extractTestDataFromPath(path)
 		if err != nil {
-			t.Fatalf("Error extracting test data from %s: %s\n", path, err)
+			return fmt.Errorf("error extracting test data from %s: %w", path, err)
+		}
+		if r.matchContract != nil && !r.matchContract.MatchString(contractName) {
 			continue
 		}
-		t.Logf("\nRunning tests for %s:%s\n", testPath, contractName)
-		runTestContract(t, concreteRegistry, bytecode, ABI)
+		t.Run(contractName, func(t tester) {
+			t.Logf("\nRunning tests for %s:%s\n", testPath, contractName)
+			r.runTestContract(t, contractName, bytecode, ABI)
+		})
 	}
+	return nil
+}
+
+// loadArtifacts indexes the ABIs of all the contracts built in outDir by the
+// hash of their runtime code.
+func (r *runner) loadArtifacts(outDir string) error {
+	return filepath.WalkDir(outDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || filepath.Ext(path) != ".json" {
+			return err
+		}
+		bytecode, ABI, _, _, err := extractTestDataFromPath(path)
+		if err != nil || len(bytecode) == 0 {
+			// Not a contract artifact
+			return nil
+		}
+		r.artifacts[crypto.Keccak256Hash(bytecode)] = ABI
+		r.indexABI(ABI)
+		return nil
+	})
 }
 
 func setGethVerbosity(lvl slog.Level) func() {
@@ -216,18 +375,48 @@ func setGethVerbosity(lvl slog.Level) func() {
 }
 
 type TestConfig struct {
-	Contract string
-	TestDir  string
-	OutDir   string
+	Contract     string
+	TestDir      string
+	OutDir       string
+	Fuzz         FuzzConfig
+	Fork         ForkConfig
+	GasReport    bool    // Log the gas used by tests and concrete precompiles
+	GasSnapshot  string  // Path of the gas snapshot file, no snapshot is taken if empty
+	GasTolerance float64 // Fraction of the snapshot gas a test can exceed it by before failing
+	JSONOutput   string  // Path of a file to write the test results to as JSON lines
+	JUnitOutput  string  // Path of a file to write the test results to as JUnit XML
+	// Parallel runs the tests of a contract in parallel under go test. The tests
+	// share the precompiles of the registry, which must be safe for concurrent use.
+	Parallel bool
+	// Regular expressions the names of the tests and test contracts to run must
+	// match, all are run if empty
+	MatchTest     string
+	MatchContract string
 }
 
+// RunTestContract runs the tests of a contract one at a time as subtests of t.
 func RunTestContract(t *testing.T, concreteRegistry concrete.PrecompileRegistry, bytecode []byte, ABI abi.ABI) {
-	resetGethLogger := setGethVerbosity(log.LevelWarn)
-	defer resetGethLogger()
-	runTestContract(t, concreteRegistry, bytecode, ABI)
+	t.Cleanup(setGethVerbosity(log.LevelWarn))
+	newRunner(concreteRegistry, TestConfig{}).runTestContract(goTester{t}, t.Name(), bytecode, ABI)
 }
 
+// Test runs the tests of the test contracts built in config.OutDir as subtests
+// of t.
 func Test(t *testing.T, concreteRegistry concrete.PrecompileRegistry, config TestConfig) {
+	if _, err := runTests(goTester{t}, concreteRegistry, config); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// Run runs the tests of the test contracts built in config.OutDir outside of go
+// test and returns their results. It writes the outcome of each test contract
+// and failed test to w in the format of go test, and that of passed tests too if
+// verbose is set. Tests failing do not make Run return an error.
+func Run(w io.Writer, concreteRegistry concrete.PrecompileRegistry, config TestConfig, verbose bool) ([]TestResult, error) {
+	return runTests(newCLITester(w, verbose), concreteRegistry, config)
+}
+
+func runTests(t tester, concreteRegistry concrete.PrecompileRegistry, config TestConfig) ([]TestResult, error) {
 	resetGethLogger := setGethVerbosity(log.LevelWarn)
 	defer resetGethLogger()
 
@@ -237,7 +426,7 @@ func Test(t *testing.T, concreteRegistry concrete.PrecompileRegistry, config Tes
 	if config.Contract != "" {
 		parts := strings.SplitN(config.Contract, ":", 2)
 		if len(parts) != 2 {
-			t.Fatalf("Invalid contract: %s. Must follow format Path:Contract\n", config.Contract)
+			return nil, fmt.Errorf("invalid contract: %s, must follow format Path:Contract", config.Contract)
 		}
 		_, fileName := filepath.Split(parts[0])
 		contractName := parts[1]
@@ -247,10 +436,58 @@ func Test(t *testing.T, concreteRegistry concrete.PrecompileRegistry, config Tes
 		var err error
 		testPaths, err = getTestPaths(config.TestDir, config.OutDir)
 		if err != nil {
-			t.Fatalf("Error getting test paths: %s\n", err)
+			return nil, fmt.Errorf("error getting test paths: %w", err)
 		}
 	}
 
 	// Run tests
-	runTestPaths(t, concreteRegistry, testPaths)
+	r := newRunner(concreteRegistry, config)
+	if err := r.compileFilters(); err != nil {
+		return nil, err
+	}
+	if config.OutDir != "" {
+		if err := r.loadArtifacts(config.OutDir); err != nil {
+			return nil, fmt.Errorf("error loading artifacts: %w", err)
+		}
+	}
+	if config.Fork.Datadir != "" {
+		fork, err := openFork(config.Fork)
+		if err != nil {
+			return nil, fmt.Errorf("error opening fork: %w", err)
+		}
+		defer fork.Close()
+		r.fork = fork
+	}
+	if config.GasSnapshot != "" {
+		stored, err := readGasSnapshot(config.GasSnapshot)
+		if err != nil {
+			return nil, fmt.Errorf("error reading gas snapshot: %w", err)
+		}
+		r.gas = newGasRecorder(stored)
+	}
+	if err := r.runTestPaths(t, testPaths); err != nil {
+		return nil, err
+	}
+	results := r.sortedResults()
+
+	if config.GasReport {
+		t.Log(r.gas.report())
+	}
+	if config.JSONOutput != "" {
+		if err := writeResultsFile(config.JSONOutput, results, WriteJSONResults); err != nil {
+			return results, fmt.Errorf("error writing JSON results: %w", err)
+		}
+	}
+	if config.JUnitOutput != "" {
+		if err := writeResultsFile(config.JUnitOutput, results, WriteJUnitResults); err != nil {
+			return results, fmt.Errorf("error writing JUnit results: %w", err)
+		}
+	}
+	// Keep the stored snapshot if gas regressed so the regression is not lost
+	if config.GasSnapshot != "" && r.gas.regressions == 0 {
+		if err := r.gas.snapshot().write(config.GasSnapshot); err != nil {
+			return results, fmt.Errorf("error writing gas snapshot: %w", err)
+		}
+	}
+	return results, nil
 }
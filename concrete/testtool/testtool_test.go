@@ -50,3 +50,28 @@ func TestRunTestContract(t *testing.T) {
 	concreteRegistry := concrete.NewRegistry()
 	Test(t, concreteRegistry, config)
 }
+
+func TestRunCheatcodesContract(t *testing.T) {
+	if err := forgeBuild(t); err != nil {
+		t.Fatal(err)
+	}
+	config := TestConfig{
+		Contract: filepath.Join("testdata", "src", "Cheatcodes.sol:CheatcodesTest"),
+		OutDir:   filepath.Join("testdata", "out"),
+	}
+	concreteRegistry := concrete.NewRegistry()
+	Test(t, concreteRegistry, config)
+}
+
+func TestRunFuzzContract(t *testing.T) {
+	if err := forgeBuild(t); err != nil {
+		t.Fatal(err)
+	}
+	config := TestConfig{
+		Contract: filepath.Join("testdata", "src", "Fuzz.sol:FuzzTest"),
+		OutDir:   filepath.Join("testdata", "out"),
+		Fuzz:     FuzzConfig{Runs: 32, InvariantRuns: 4},
+	}
+	concreteRegistry := concrete.NewRegistry()
+	Test(t, concreteRegistry, config)
+}
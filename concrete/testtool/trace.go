@@ -0,0 +1,195 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package testtool
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
+)
+
+// traceFrame is a call frame as output by the call tracer.
+type traceFrame struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to"`
+	GasUsed hexutil.Uint64  `json:"gasUsed"`
+	Input   hexutil.Bytes   `json:"input"`
+	Output  hexutil.Bytes   `json:"output"`
+	Error   string          `json:"error"`
+	Calls   []traceFrame    `json:"calls"`
+	Logs    []traceLog      `json:"logs"`
+}
+
+func (f traceFrame) hasLogs() bool {
+	if len(f.Logs) > 0 {
+		return true
+	}
+	for _, call := range f.Calls {
+		if call.hasLogs() {
+			return true
+		}
+	}
+	return false
+}
+
+type traceLog struct {
+	Address  common.Address `json:"address"`
+	Topics   []common.Hash  `json:"topics"`
+	Data     hexutil.Bytes  `json:"data"`
+	Position hexutil.Uint   `json:"position"`
+}
+
+// nestedTracer passes the events of a transaction to a tracer, reporting calls
+// started while another is still running as entered call frames. The EVM runs
+// concrete precompiles at the depth of their caller, so calls they make from the
+// top level of a transaction start a new trace instead of nesting.
+type nestedTracer struct {
+	tracers.Tracer
+	depth int
+}
+
+func (t *nestedTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.depth++
+	if t.depth == 1 {
+		t.Tracer.CaptureStart(env, from, to, create, input, gas, value)
+		return
+	}
+	typ := vm.CALL
+	if create {
+		typ = vm.CREATE
+	}
+	t.Tracer.CaptureEnter(typ, from, to, input, gas, value)
+}
+
+func (t *nestedTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.depth--
+	if t.depth == 0 {
+		t.Tracer.CaptureEnd(output, gasUsed, err)
+		return
+	}
+	t.Tracer.CaptureExit(output, gasUsed, err)
+}
+
+// traceCalls runs the calls again with the call tracer and returns the trace
+// of the last one.
+func (r *runner) traceCalls(bytecode []byte, calls []testCall, testABI abi.ABI) (string, error) {
+	tracer, err := tracers.DefaultDirectory.New("callTracer", &tracers.Context{}, json.RawMessage(`{"withLog": true}`))
+	if err != nil {
+		return "", err
+	}
+	results, statedb, err := r.runCallsWithTracer(bytecode, calls, &nestedTracer{Tracer: tracer})
+	if err != nil {
+		return "", err
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		return "", err
+	}
+	var frame traceFrame
+	if err := json.Unmarshal(res, &frame); err != nil {
+		return "", err
+	}
+	// Logs emitted by concrete precompiles are not seen by the call tracer, so
+	// show the logs of the receipt instead if it found none
+	if !frame.hasLogs() {
+		for _, log := range results[len(results)-1].receipt.Logs {
+			frame.Logs = append(frame.Logs, traceLog{Address: log.Address, Topics: log.Topics, Data: log.Data, Position: hexutil.Uint(len(frame.Calls))})
+		}
+	}
+	var sb strings.Builder
+	r.newDecoder(testABI, statedb).formatFrame(&sb, frame, "")
+	return sb.String(), nil
+}
+
+// traceFailure adds the call trace of the last of the given calls to the
+// result of a failed test.
+func (r *runner) traceFailure(t tester, result *TestResult, bytecode []byte, calls []testCall, testABI abi.ABI) {
+	trace, err := r.traceCalls(bytecode, calls, testABI)
+	if err != nil {
+		t.Logf("Error tracing failing call: %s", err)
+		return
+	}
+	result.Trace = trace
+	t.Logf("Trace:\n%s", trace)
+}
+
+func (d *decoder) formatFrame(sb *strings.Builder, frame traceFrame, indent string) {
+	var to common.Address
+	if frame.To != nil {
+		to = *frame.To
+	}
+	call, method := d.call(to, frame.Input)
+	if frame.Type == "CREATE" || frame.Type == "CREATE2" {
+		call = "new"
+	}
+	fmt.Fprintf(sb, "%s[%d] %s::%s", indent, frame.GasUsed, to.Hex(), call)
+	if frame.Type != "CALL" {
+		fmt.Fprintf(sb, " [%s]", strings.ToLower(frame.Type))
+	}
+	sb.WriteString("\n")
+
+	inner := indent + "    "
+	logs := frame.Logs
+	for ii, child := range frame.Calls {
+		for len(logs) > 0 && int(logs[0].Position) <= ii {
+			d.formatLog(sb, logs[0], inner)
+			logs = logs[1:]
+		}
+		d.formatFrame(sb, child, inner)
+	}
+	for _, log := range logs {
+		d.formatLog(sb, log, inner)
+	}
+
+	switch {
+	case frame.Error == vm.ErrExecutionReverted.Error():
+		fmt.Fprintf(sb, "%s← revert: %s\n", inner, d.revertReason(frame.Output))
+	case frame.Error != "":
+		fmt.Fprintf(sb, "%s← %s\n", inner, frame.Error)
+	case len(frame.Output) == 0:
+		fmt.Fprintf(sb, "%s← ()\n", inner)
+	default:
+		fmt.Fprintf(sb, "%s← %s\n", inner, formatOutput(method, frame.Output))
+	}
+}
+
+func (d *decoder) formatLog(sb *strings.Builder, log traceLog, indent string) {
+	decoded := d.log(log.Address, log.Topics, log.Data)
+	if decoded.Event == "" {
+		fmt.Fprintf(sb, "%semit topics=%v data=%s\n", indent, log.Topics, log.Data)
+		return
+	}
+	name, _, _ := strings.Cut(decoded.Event, "(")
+	fmt.Fprintf(sb, "%semit %s%s\n", indent, name, decoded.formatArgs())
+}
+
+func formatOutput(method *abi.Method, output []byte) string {
+	if method != nil {
+		if values, err := method.Outputs.Unpack(output); err == nil {
+			return formatInterfaces(values)
+		}
+	}
+	return hexutil.Encode(output)
+}
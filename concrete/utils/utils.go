@@ -32,6 +32,17 @@ func BytesToUint64(data []byte) uint64 {
 	return binary.BigEndian.Uint64(data)
 }
 
+func BoolToBytes(value bool) []byte {
+	if value {
+		return []byte{0x01}
+	}
+	return []byte{0x00}
+}
+
+func BytesToBool(data []byte) bool {
+	return len(data) > 0 && data[0] == 0x01
+}
+
 const (
 	nil_error    = byte(0x00)
 	notNil_error = byte(0x01)
@@ -57,6 +57,14 @@ func TestUint64BytesConversion(t *testing.T) {
 	})
 }
 
+func TestBoolBytesConversion(t *testing.T) {
+	require.Equal(t, []byte{0x01}, BoolToBytes(true))
+	require.Equal(t, []byte{0x00}, BoolToBytes(false))
+	require.True(t, BytesToBool([]byte{0x01}))
+	require.False(t, BytesToBool([]byte{0x00}))
+	require.False(t, BytesToBool([]byte{}))
+}
+
 func TestErrorCodec(t *testing.T) {
 	var errorEncodeDecodeTestCases = []struct {
 		err    error
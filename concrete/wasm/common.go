@@ -15,6 +15,12 @@
 
 package wasm
 
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/concrete/api"
+)
+
 const (
 	// Host functions
 	Environment_WasmFuncName = "concrete_Environment"
@@ -23,4 +29,13 @@ const (
 	// Finalise_WasmFuncName = "concrete_Finalise"
 	// Commit_WasmFuncName = "concrete_Commit"
 	Run_WasmFuncName = "concrete_Run"
+	// Optional WASM functions
+	AbiVersion_WasmFuncName = "concrete_AbiVersion"
 )
+
+func checkAbiVersion(version api.AbiVersion) error {
+	if !api.IsAbiVersionSupported(version) {
+		return fmt.Errorf("%w: %d", api.ErrUnsupportedAbiVersion, version)
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/holiman/uint256"
+)
+
+var (
+	ErrDivergence     = errors.New("backends diverged")
+	ErrTooFewBackends = errors.New("at least two backends are required")
+)
+
+// Backend is a named precompile implementation taking part in a differential
+// run, e.g. the native Go precompile or the same precompile compiled to WASM
+// and executed with wazero or wasmer.
+type Backend struct {
+	Name       string
+	Precompile concrete.Precompile
+}
+
+type DifferentialConfig struct {
+	Address common.Address
+	Caller  common.Address
+	Gas     uint64
+	Static  bool
+	// Setup is called on the fresh state of every backend before each run so
+	// all backends start from an identical state.
+	Setup func(statedb api.StateDB)
+}
+
+// ExecutionResult is the observable outcome of running a precompile.
+type ExecutionResult struct {
+	Backend  string
+	IsStatic bool
+	Output   []byte
+	Err      error
+	GasLeft  uint64
+	Logs     []*types.Log
+	Storage  map[common.Address]map[common.Hash]common.Hash
+}
+
+// DifferentialRunner executes the same input on several precompile backends
+// and reports any difference in return data, gas, logs or storage writes.
+type DifferentialRunner struct {
+	config   DifferentialConfig
+	backends []Backend
+}
+
+func NewDifferentialRunner(config DifferentialConfig, backends ...Backend) *DifferentialRunner {
+	return &DifferentialRunner{config: config, backends: backends}
+}
+
+// Run executes the input on every backend and returns the results in backend
+// order.
+func (d *DifferentialRunner) Run(input []byte) []ExecutionResult {
+	results := make([]ExecutionResult, len(d.backends))
+	for ii, backend := range d.backends {
+		results[ii] = d.run(backend, input)
+	}
+	return results
+}
+
+// Check executes the input on every backend and returns an error wrapping
+// ErrDivergence if any backend disagrees with the first one. It is meant to be
+// called from Go fuzz targets.
+func (d *DifferentialRunner) Check(input []byte) error {
+	if len(d.backends) < 2 {
+		return ErrTooFewBackends
+	}
+	results := d.Run(input)
+	for _, res := range results[1:] {
+		if err := compareResults(results[0], res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DifferentialRunner) run(backend Backend, input []byte) (res ExecutionResult) {
+	res.Backend = backend.Name
+
+	statedb := newRecordingStateDB(api.NewMockStateDB())
+	if d.config.Setup != nil {
+		d.config.Setup(statedb.StateDB)
+	}
+	contract := api.NewContract(d.config.Caller, d.config.Caller, d.config.Address, uint256.NewInt(0))
+	env, _, _, _ := api.NewMockEnvironment(
+		api.WithConfig(api.EnvConfig{IsStatic: d.config.Static, IsTrusted: true}),
+		api.WithMeterGas(true),
+		api.WithStateDB(statedb),
+		api.WithContract(contract),
+	)
+
+	defer func() {
+		if r := recover(); r != nil {
+			res.Err = fmt.Errorf("panic: %v", r)
+		}
+		res.Logs = statedb.logs
+		res.Storage = statedb.storage
+	}()
+
+	res.IsStatic = backend.Precompile.IsStatic(input)
+	res.Output, res.GasLeft, res.Err = concrete.RunPrecompile(backend.Precompile, env, input, d.config.Gas, uint256.NewInt(0))
+	return res
+}
+
+func divergence(a, b ExecutionResult, field string, va, vb interface{}) error {
+	return fmt.Errorf("%w: %s: %s=%v, %s=%v", ErrDivergence, field, a.Backend, va, b.Backend, vb)
+}
+
+func compareResults(a, b ExecutionResult) error {
+	if a.IsStatic != b.IsStatic {
+		return divergence(a, b, "isStatic", a.IsStatic, b.IsStatic)
+	}
+	if !bytes.Equal(a.Output, b.Output) {
+		return divergence(a, b, "output", fmt.Sprintf("0x%x", a.Output), fmt.Sprintf("0x%x", b.Output))
+	}
+	if errString(a.Err) != errString(b.Err) {
+		return divergence(a, b, "error", a.Err, b.Err)
+	}
+	if a.GasLeft != b.GasLeft {
+		return divergence(a, b, "gasLeft", a.GasLeft, b.GasLeft)
+	}
+	if len(a.Logs) != len(b.Logs) {
+		return divergence(a, b, "log count", len(a.Logs), len(b.Logs))
+	}
+	for ii := range a.Logs {
+		la, lb := a.Logs[ii], b.Logs[ii]
+		if la.Address != lb.Address || !equalTopics(la.Topics, lb.Topics) || !bytes.Equal(la.Data, lb.Data) {
+			return divergence(a, b, fmt.Sprintf("log %d", ii), la, lb)
+		}
+	}
+	if err := compareStorage(a, b); err != nil {
+		return err
+	}
+	return nil
+}
+
+func compareStorage(a, b ExecutionResult) error {
+	for addr, slots := range a.Storage {
+		for key, va := range slots {
+			vb := b.Storage[addr][key]
+			if va != vb {
+				return divergence(a, b, fmt.Sprintf("storage %s[%s]", addr.Hex(), key.Hex()), va.Hex(), vb.Hex())
+			}
+		}
+	}
+	for addr, slots := range b.Storage {
+		for key, vb := range slots {
+			if _, ok := a.Storage[addr][key]; !ok {
+				return divergence(a, b, fmt.Sprintf("storage %s[%s]", addr.Hex(), key.Hex()), "unset", vb.Hex())
+			}
+		}
+	}
+	return nil
+}
+
+func equalTopics(a, b []common.Hash) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// recordingStateDB records the storage writes and logs made through it.
+type recordingStateDB struct {
+	api.StateDB
+	storage map[common.Address]map[common.Hash]common.Hash
+	logs    []*types.Log
+}
+
+func newRecordingStateDB(statedb api.StateDB) *recordingStateDB {
+	return &recordingStateDB{
+		StateDB: statedb,
+		storage: make(map[common.Address]map[common.Hash]common.Hash),
+	}
+}
+
+func (s *recordingStateDB) SetState(addr common.Address, key common.Hash, value common.Hash) {
+	if _, ok := s.storage[addr]; !ok {
+		s.storage[addr] = make(map[common.Hash]common.Hash)
+	}
+	s.storage[addr][key] = value
+	s.StateDB.SetState(addr, key, value)
+}
+
+func (s *recordingStateDB) AddLog(log *types.Log) {
+	s.logs = append(s.logs, log)
+	s.StateDB.AddLog(log)
+}
+
+var _ api.StateDB = (*recordingStateDB)(nil)
@@ -0,0 +1,65 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/lib"
+	"github.com/stretchr/testify/require"
+)
+
+type storePrecompile struct {
+	lib.BlankPrecompile
+	value common.Hash
+}
+
+func (p *storePrecompile) IsStatic(input []byte) bool {
+	return false
+}
+
+func (p *storePrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	env.StorageStore(common.BytesToHash(input), p.value)
+	return input, nil
+}
+
+func TestDifferentialRunner(t *testing.T) {
+	r := require.New(t)
+	config := DifferentialConfig{Address: common.Address{0x80}, Gas: 1e6}
+
+	runner := NewDifferentialRunner(config,
+		Backend{Name: "A", Precompile: &storePrecompile{value: common.Hash{0x01}}},
+		Backend{Name: "B", Precompile: &storePrecompile{value: common.Hash{0x01}}},
+	)
+	r.NoError(runner.Check([]byte{0x01}))
+
+	results := runner.Run([]byte{0x01})
+	r.Len(results, 2)
+	r.Equal(common.Hash{0x01}, results[0].Storage[config.Address][common.BytesToHash([]byte{0x01})])
+
+	runner = NewDifferentialRunner(config,
+		Backend{Name: "A", Precompile: &storePrecompile{value: common.Hash{0x01}}},
+		Backend{Name: "B", Precompile: &storePrecompile{value: common.Hash{0x02}}},
+	)
+	r.ErrorIs(runner.Check([]byte{0x01}), ErrDivergence)
+
+	runner = NewDifferentialRunner(config)
+	r.ErrorIs(runner.Check([]byte{0x01}), ErrTooFewBackends)
+	runner = NewDifferentialRunner(config, Backend{Name: "A", Precompile: &storePrecompile{}})
+	r.ErrorIs(runner.Check([]byte{0x01}), ErrTooFewBackends)
+}
@@ -0,0 +1,159 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+//go:build !mips && !mipsle && !mips64 && !mips64le
+
+// This file will ignored when building for mips to prevent compatibility
+// issues.
+
+package host
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/concrete/wasm/wasi"
+	"github.com/wasmerio/wasmer-go/wasmer"
+)
+
+// wasmerLinearMemory adapts the wasmer memory to wasi.Memory.
+type wasmerLinearMemory []byte
+
+func (m wasmerLinearMemory) inRange(offset, size uint32) bool {
+	return uint64(offset)+uint64(size) <= uint64(len(m))
+}
+
+func (m wasmerLinearMemory) Size() uint32 {
+	return uint32(len(m))
+}
+
+func (m wasmerLinearMemory) Read(offset, byteCount uint32) ([]byte, bool) {
+	if !m.inRange(offset, byteCount) {
+		return nil, false
+	}
+	out := make([]byte, byteCount)
+	copy(out, m[offset:])
+	return out, true
+}
+
+func (m wasmerLinearMemory) Write(offset uint32, data []byte) bool {
+	if !m.inRange(offset, uint32(len(data))) {
+		return false
+	}
+	copy(m[offset:], data)
+	return true
+}
+
+func (m wasmerLinearMemory) ReadUint32Le(offset uint32) (uint32, bool) {
+	if !m.inRange(offset, 4) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(m[offset:]), true
+}
+
+func (m wasmerLinearMemory) WriteUint32Le(offset, value uint32) bool {
+	if !m.inRange(offset, 4) {
+		return false
+	}
+	binary.LittleEndian.PutUint32(m[offset:], value)
+	return true
+}
+
+func (m wasmerLinearMemory) WriteUint64Le(offset uint32, value uint64) bool {
+	if !m.inRange(offset, 8) {
+		return false
+	}
+	binary.LittleEndian.PutUint64(m[offset:], value)
+	return true
+}
+
+var _ wasi.Memory = (wasmerLinearMemory)(nil)
+
+func toWasmerValueTypes(types []wasi.ValueType) []*wasmer.ValueType {
+	kinds := make([]wasmer.ValueKind, len(types))
+	for ii, t := range types {
+		if t == wasi.I64 {
+			kinds[ii] = wasmer.I64
+		} else {
+			kinds[ii] = wasmer.I32
+		}
+	}
+	return wasmer.NewValueTypes(kinds...)
+}
+
+func fromWasmerValueTypes(types []*wasmer.ValueType) []wasi.ValueType {
+	out := make([]wasi.ValueType, len(types))
+	for ii, t := range types {
+		if t.Kind() == wasmer.I64 {
+			out[ii] = wasi.I64
+		} else {
+			out[ii] = wasi.I32
+		}
+	}
+	return out
+}
+
+// NewWasmerWasiImports returns the deterministic WASI shim functions resolving
+// the WASI imports of the given module. The environment must be initialized
+// with the module instance before the guest is called.
+func NewWasmerWasiImports(store *wasmer.Store, module *wasmer.Module, shim *wasi.Shim, env *WasmerEnvironment) (map[string]wasmer.IntoExtern, error) {
+	imports := make(map[string]wasmer.IntoExtern)
+	for _, imp := range module.Imports() {
+		if imp.Module() != wasi.ModuleName || imp.Type().Kind() != wasmer.FUNCTION {
+			continue
+		}
+		funcType := imp.Type().IntoFunctionType()
+		fn, err := wasi.Resolve(imp.Name(), fromWasmerValueTypes(funcType.Params()), fromWasmerValueTypes(funcType.Results()), shim.Config().Strict)
+		if err != nil {
+			return nil, err
+		}
+		imports[imp.Name()] = wasmer.NewFunctionWithEnvironment(
+			store,
+			wasmer.NewFunctionType(toWasmerValueTypes(fn.Params), toWasmerValueTypes(fn.Results)),
+			env,
+			newWasmerWasiFunc(shim, fn),
+		)
+	}
+	return imports, nil
+}
+
+func newWasmerWasiFunc(shim *wasi.Shim, fn wasi.Function) WasmerHostFunc {
+	return func(wasmerEnv interface{}, values []wasmer.Value) ([]wasmer.Value, error) {
+		mem, err := wasmerEnv.(*WasmerEnvironment).instance.Exports.GetMemory("memory")
+		if err != nil {
+			return nil, err
+		}
+		args := make([]uint64, len(values))
+		for ii, v := range values {
+			if v.Kind() == wasmer.I64 {
+				args[ii] = uint64(v.I64())
+			} else {
+				args[ii] = uint64(uint32(v.I32()))
+			}
+		}
+		ret, err := fn.Call(shim, wasmerLinearMemory(mem.Data()), args)
+		if err != nil {
+			return nil, err
+		}
+		results := make([]wasmer.Value, len(fn.Results))
+		for ii, t := range fn.Results {
+			if t == wasi.I64 {
+				results[ii] = wasmer.NewI64(int64(ret[ii]))
+			} else {
+				results[ii] = wasmer.NewI32(int32(uint32(ret[ii])))
+			}
+		}
+		return results, nil
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package host
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/concrete/wasm/wasi"
+	"github.com/tetratelabs/wazero"
+	wz_api "github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/sys"
+)
+
+func toWazeroValueTypes(types []wasi.ValueType) []wz_api.ValueType {
+	out := make([]wz_api.ValueType, len(types))
+	for ii, t := range types {
+		if t == wasi.I64 {
+			out[ii] = wz_api.ValueTypeI64
+		} else {
+			out[ii] = wz_api.ValueTypeI32
+		}
+	}
+	return out
+}
+
+func fromWazeroValueTypes(types []wz_api.ValueType) []wasi.ValueType {
+	out := make([]wasi.ValueType, len(types))
+	for ii, t := range types {
+		if t == wz_api.ValueTypeI64 {
+			out[ii] = wasi.I64
+		} else {
+			out[ii] = wasi.I32
+		}
+	}
+	return out
+}
+
+// InstantiateWazeroWasi instantiates the deterministic WASI shim in the runtime,
+// resolving the WASI functions imported by the given compiled module.
+func InstantiateWazeroWasi(ctx context.Context, r wazero.Runtime, compiled wazero.CompiledModule, shim *wasi.Shim) error {
+	builder := r.NewHostModuleBuilder(wasi.ModuleName)
+	for _, def := range compiled.ImportedFunctions() {
+		moduleName, name, _ := def.Import()
+		if moduleName != wasi.ModuleName {
+			continue
+		}
+		fn, err := wasi.Resolve(name, fromWazeroValueTypes(def.ParamTypes()), fromWazeroValueTypes(def.ResultTypes()), shim.Config().Strict)
+		if err != nil {
+			return err
+		}
+		builder.NewFunctionBuilder().
+			WithGoModuleFunction(newWazeroWasiFunc(shim, fn), toWazeroValueTypes(fn.Params), toWazeroValueTypes(fn.Results)).
+			Export(name)
+	}
+	_, err := builder.Instantiate(ctx)
+	return err
+}
+
+func newWazeroWasiFunc(shim *wasi.Shim, fn wasi.Function) wz_api.GoModuleFunc {
+	return func(ctx context.Context, module wz_api.Module, stack []uint64) {
+		args := make([]uint64, len(fn.Params))
+		copy(args, stack)
+		ret, err := fn.Call(shim, module.Memory(), args)
+		if exitErr, ok := err.(*wasi.ExitError); ok {
+			// Mirror wazero's proc_exit so exiting from a start function is
+			// handled the same way
+			_ = module.CloseWithExitCode(ctx, exitErr.Code)
+			panic(sys.NewExitError(exitErr.Code))
+		} else if err != nil {
+			panic(err)
+		}
+		copy(stack, ret)
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/concrete/wasm/host"
+	"github.com/ethereum/go-ethereum/concrete/wasm/wasi"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/holiman/uint256"
+	"github.com/tetratelabs/wazero"
+	wz_api "github.com/tetratelabs/wazero/api"
+)
+
+const (
+	EnvModuleName  = "env"
+	WasiModuleName = wasi.ModuleName
+	MemoryName     = "memory"
+)
+
+var (
+	ErrMissingExport    = errors.New("missing export")
+	ErrMissingMemory    = errors.New("memory not exported")
+	ErrInvalidSignature = errors.New("invalid function signature")
+	ErrInvalidImport    = errors.New("invalid import")
+)
+
+type funcSignature struct {
+	params  []wz_api.ValueType
+	results []wz_api.ValueType
+}
+
+func (s funcSignature) matches(def wz_api.FunctionDefinition) bool {
+	return equalValueTypes(s.params, def.ParamTypes()) && equalValueTypes(s.results, def.ResultTypes())
+}
+
+func equalValueTypes(a, b []wz_api.ValueType) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+var (
+	i64 = wz_api.ValueTypeI64
+	// Functions the host expects the guest to export
+	requiredExports = map[string]funcSignature{
+		Run_WasmFuncName:         {params: []wz_api.ValueType{i64}, results: []wz_api.ValueType{i64}},
+		IsStatic_WasmFuncName:    {params: []wz_api.ValueType{i64}, results: []wz_api.ValueType{i64}},
+		host.Malloc_WasmFuncName: {params: []wz_api.ValueType{i64}, results: []wz_api.ValueType{i64}},
+		host.Free_WasmFuncName:   {params: []wz_api.ValueType{i64}, results: nil},
+		host.Prune_WasmFuncName:  {params: nil, results: nil},
+	}
+	// Functions the guest may optionally export
+	optionalExports = map[string]funcSignature{
+		AbiVersion_WasmFuncName: {params: nil, results: []wz_api.ValueType{i64}},
+	}
+	// Functions the host provides to the guest
+	environmentSignature = funcSignature{params: []wz_api.ValueType{i64}, results: []wz_api.ValueType{i64}}
+)
+
+// ModuleImport is a function imported by a WASM module.
+type ModuleImport struct {
+	Module string
+	Name   string
+}
+
+func (i ModuleImport) String() string {
+	return i.Module + "." + i.Name
+}
+
+// ModuleInfo summarizes a compiled WASM precompile module.
+type ModuleInfo struct {
+	Size     int
+	CodeHash common.Hash
+	Exports  []string
+	Imports  []ModuleImport
+	// ExportsAbiVersion is false for legacy modules that are assumed to use
+	// api.AbiVersion1.
+	ExportsAbiVersion bool
+	MemoryMin         uint32 // In pages
+	MemoryMax         uint32 // In pages, only meaningful if HasMemoryMax is true
+	HasMemoryMax      bool
+	// UnsupportedWasi lists the WASI imports outside the deterministic shim.
+	// They fail with ENOSYS, or are rejected in strict mode.
+	UnsupportedWasi []ModuleImport
+
+	errs []error
+}
+
+// Validate returns an error describing every incompatibility found between the
+// module and the host, or nil if the module can be loaded as a precompile.
+func (info *ModuleInfo) Validate() error {
+	return errors.Join(info.errs...)
+}
+
+// ValidateStrict is like Validate, but also rejects imports outside the
+// allowlist, as the host does in strict mode.
+func (info *ModuleInfo) ValidateStrict() error {
+	errs := info.errs
+	for _, imp := range info.UnsupportedWasi {
+		errs = append(errs, fmt.Errorf("%w: %s", wasi.ErrImportNotAllowed, imp))
+	}
+	return errors.Join(errs...)
+}
+
+// checkImport returns an error if the import is outside the allowlist.
+func checkImport(imp ModuleImport) error {
+	if imp.Module == EnvModuleName && imp.Name == Environment_WasmFuncName {
+		return nil
+	}
+	if imp.Module == WasiModuleName && wasi.IsAllowed(imp.Name) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", wasi.ErrImportNotAllowed, imp)
+}
+
+// InspectModule compiles a WASM module without instantiating it and checks
+// its exports, imports and memory against what the host expects.
+func InspectModule(code []byte) (*ModuleInfo, error) {
+	ctx := context.Background()
+	r := wazero.NewRuntimeWithConfig(ctx, wazero.NewRuntimeConfigInterpreter())
+	defer r.Close(ctx)
+
+	compiled, err := r.CompileModule(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	defer compiled.Close(ctx)
+
+	info := &ModuleInfo{
+		Size:     len(code),
+		CodeHash: crypto.Keccak256Hash(code),
+	}
+
+	exports := compiled.ExportedFunctions()
+	for name := range exports {
+		info.Exports = append(info.Exports, name)
+	}
+	sort.Strings(info.Exports)
+
+	requiredNames := make([]string, 0, len(requiredExports))
+	for name := range requiredExports {
+		requiredNames = append(requiredNames, name)
+	}
+	sort.Strings(requiredNames)
+	for _, name := range requiredNames {
+		def, ok := exports[name]
+		if !ok {
+			info.errs = append(info.errs, fmt.Errorf("%w: %s", ErrMissingExport, name))
+			continue
+		}
+		if !requiredExports[name].matches(def) {
+			info.errs = append(info.errs, fmt.Errorf("%w: %s", ErrInvalidSignature, name))
+		}
+	}
+
+	if def, ok := exports[AbiVersion_WasmFuncName]; ok {
+		info.ExportsAbiVersion = true
+		if !optionalExports[AbiVersion_WasmFuncName].matches(def) {
+			info.errs = append(info.errs, fmt.Errorf("%w: %s", ErrInvalidSignature, AbiVersion_WasmFuncName))
+		}
+	}
+
+	for _, def := range compiled.ImportedFunctions() {
+		moduleName, name, _ := def.Import()
+		imp := ModuleImport{Module: moduleName, Name: name}
+		info.Imports = append(info.Imports, imp)
+		switch {
+		case moduleName == EnvModuleName && name == Environment_WasmFuncName:
+			if !environmentSignature.matches(def) {
+				info.errs = append(info.errs, fmt.Errorf("%w: %s", ErrInvalidSignature, imp))
+			}
+		case moduleName == WasiModuleName:
+			if !wasi.IsAllowed(name) {
+				info.UnsupportedWasi = append(info.UnsupportedWasi, imp)
+			}
+		default:
+			info.errs = append(info.errs, fmt.Errorf("%w: %s", ErrInvalidImport, imp))
+		}
+	}
+
+	if mem, ok := compiled.ExportedMemories()[MemoryName]; ok {
+		info.MemoryMin = mem.Min()
+		info.MemoryMax, info.HasMemoryMax = mem.Max()
+	} else {
+		info.errs = append(info.errs, ErrMissingMemory)
+	}
+
+	return info, nil
+}
+
+// ValidateModule returns an error if the module cannot be loaded as a precompile.
+func ValidateModule(code []byte) error {
+	info, err := InspectModule(code)
+	if err != nil {
+		return err
+	}
+	return info.Validate()
+}
+
+// SmokeResult holds the outcome of running a precompile against a mock
+// environment.
+type SmokeResult struct {
+	AbiVersion api.AbiVersion
+	IsStatic   bool
+	Output     []byte
+	GasUsed    uint64
+	Err        error
+}
+
+// SmokeTest loads the module with wazero and calls IsStatic and Run with the
+// given input against a mock environment.
+func SmokeTest(code []byte, input []byte, gas uint64) (res SmokeResult, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("precompile panicked: %v", r)
+		}
+	}()
+	pc := newWazeroPrecompile(context.Background(), code, wazero.NewRuntimeConfigInterpreter())
+	defer pc.runtime.Close(context.Background())
+	res.AbiVersion = pc.AbiVersion()
+	env, _, _, _ := api.NewMockEnvironment(
+		api.WithTrusted(true),
+		api.WithMeterGas(true),
+		api.WithStatic(false),
+	)
+	res.IsStatic = pc.IsStatic(input)
+	output, gasLeft, runErr := concrete.RunPrecompile(pc, env, input, gas, uint256.NewInt(0))
+	res.Output = output
+	res.GasUsed = gas - gasLeft
+	res.Err = runErr
+	return res, nil
+}
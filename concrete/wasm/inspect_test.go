@@ -0,0 +1,62 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInspectModule(t *testing.T) {
+	r := require.New(t)
+
+	info, err := InspectModule(blankCode)
+	r.NoError(err)
+	r.NoError(info.Validate())
+	r.Equal(len(blankCode), info.Size)
+	r.Equal(crypto.Keccak256Hash(blankCode), info.CodeHash)
+	r.Contains(info.Exports, Run_WasmFuncName)
+	r.Contains(info.Exports, IsStatic_WasmFuncName)
+
+	_, err = InspectModule(blankCode[:len(blankCode)/2])
+	r.Error(err)
+}
+
+func TestSmokeTest(t *testing.T) {
+	r := require.New(t)
+	res, err := SmokeTest(blankCode, []byte{}, 1e6)
+	r.NoError(err)
+	r.NoError(res.Err)
+	r.True(res.IsStatic)
+}
+
+func TestLoadWazeroPrecompile(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	pc, err := LoadWazeroPrecompile(ctx, blankCode)
+	r.NoError(err)
+	r.True(pc.IsStatic(nil))
+
+	// The precompile cannot run once the context is done
+	cancel()
+	r.Panics(func() { pc.IsStatic(nil) })
+
+	_, err = LoadWazeroPrecompile(context.Background(), blankCode[:len(blankCode)/2])
+	r.Error(err)
+}
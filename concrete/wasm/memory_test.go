@@ -23,6 +23,7 @@ import (
 	"github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/concrete/wasm/host"
 	"github.com/ethereum/go-ethereum/concrete/wasm/memory"
+	"github.com/ethereum/go-ethereum/concrete/wasm/wasi"
 	"github.com/stretchr/testify/require"
 	"github.com/tetratelabs/wazero"
 	"github.com/wasmerio/wasmer-go/wasmer"
@@ -113,7 +114,7 @@ func TestMockMemoryPutGetValues(t *testing.T) {
 func newWazeroMemory() (memory.Memory, memory.Allocator) {
 	envCall := host.NewWazeroEnvironmentCaller(func() api.Environment { return nil })
 	config := wazero.NewRuntimeConfigInterpreter()
-	mod, _, err := newWazeroModule(envCall, blankCode, config)
+	mod, _, err := newWazeroModule(context.Background(), envCall, wasi.NewShim(wasi.DefaultConfig, nil), blankCode, config)
 	if err != nil {
 		panic(err)
 	}
@@ -130,7 +131,7 @@ func newWasmerMemory() (memory.Memory, memory.Allocator) {
 	} else {
 		config = wasmer.NewConfig().UseCraneliftCompiler()
 	}
-	_, instance, err := newWasmerModule(envCall, blankCode, config)
+	_, instance, err := newWasmerModule(envCall, wasi.NewShim(wasi.DefaultConfig, nil), blankCode, config)
 	if err != nil {
 		panic(err)
 	}
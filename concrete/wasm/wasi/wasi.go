@@ -0,0 +1,320 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package wasi implements a deterministic subset of wasi_snapshot_preview1 for
+// WASM precompiles. Guests get a fixed clock, seeded (or no) randomness, no
+// filesystem, and stdout/stderr routed to the environment's Debug.
+package wasi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const ModuleName = "wasi_snapshot_preview1"
+
+// WASI errno values.
+const (
+	ErrnoSuccess uint32 = 0
+	ErrnoBadf    uint32 = 8
+	ErrnoFault   uint32 = 21
+	ErrnoInval   uint32 = 28
+	ErrnoNosys   uint32 = 52
+)
+
+var ErrImportNotAllowed = errors.New("import not allowed")
+
+// ExitError is returned when the guest calls proc_exit.
+type ExitError struct {
+	Code uint32
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("wasi: exit code %d", e.Code)
+}
+
+type ValueType byte
+
+const (
+	I32 ValueType = iota
+	I64
+)
+
+// Memory is the guest linear memory. It is satisfied by wazero's api.Memory.
+type Memory interface {
+	Size() uint32
+	Read(offset, byteCount uint32) ([]byte, bool)
+	Write(offset uint32, data []byte) bool
+	ReadUint32Le(offset uint32) (uint32, bool)
+	WriteUint32Le(offset, value uint32) bool
+	WriteUint64Le(offset uint32, value uint64) bool
+}
+
+// Function is a WASI function implemented by the shim. Arguments and results
+// are passed as raw uint64 values, as in the WASM stack.
+type Function struct {
+	Params  []ValueType
+	Results []ValueType
+	Call    func(s *Shim, mem Memory, args []uint64) ([]uint64, error)
+}
+
+type Config struct {
+	// Walltime is returned by clock_time_get for every clock, in nanoseconds.
+	Walltime uint64
+	// RandSeed seeds the bytes returned by random_get. The stream is reset
+	// before every call into the guest.
+	RandSeed uint64
+	// DisallowRandom makes random_get fail with ENOSYS.
+	DisallowRandom bool
+	// Strict rejects modules importing WASI functions outside the shim.
+	Strict bool
+}
+
+var DefaultConfig = Config{}
+
+// Shim holds the state of the WASI functions of a single module instance.
+type Shim struct {
+	config      Config
+	debug       func(msg string)
+	randCounter uint64
+}
+
+// NewShim creates a shim writing guest stdout and stderr to debug, which may
+// be nil to discard the output.
+func NewShim(config Config, debug func(msg string)) *Shim {
+	return &Shim{config: config, debug: debug}
+}
+
+func (s *Shim) Config() Config {
+	return s.config
+}
+
+// Reset restores the shim to its initial state so consecutive calls into the
+// guest observe the same values.
+func (s *Shim) Reset() {
+	s.randCounter = 0
+}
+
+func (s *Shim) randomBytes(size int) []byte {
+	var (
+		out   = make([]byte, 0, size+32)
+		input = make([]byte, 16)
+	)
+	binary.BigEndian.PutUint64(input[:8], s.config.RandSeed)
+	for len(out) < size {
+		binary.BigEndian.PutUint64(input[8:], s.randCounter)
+		out = append(out, crypto.Keccak256(input)...)
+		s.randCounter++
+	}
+	return out[:size]
+}
+
+// Functions are the WASI functions provided to guests, keyed by name.
+var Functions = map[string]Function{
+	"fd_write": {
+		Params:  []ValueType{I32, I32, I32, I32},
+		Results: []ValueType{I32},
+		Call:    fdWrite,
+	},
+	"fd_read":             badf([]ValueType{I32, I32, I32, I32}),
+	"fd_close":            badf([]ValueType{I32}),
+	"fd_seek":             badf([]ValueType{I32, I64, I32, I32}),
+	"fd_fdstat_get":       badf([]ValueType{I32, I32}),
+	"fd_prestat_get":      badf([]ValueType{I32, I32}),
+	"fd_prestat_dir_name": badf([]ValueType{I32, I32, I32}),
+	"random_get": {
+		Params:  []ValueType{I32, I32},
+		Results: []ValueType{I32},
+		Call:    randomGet,
+	},
+	"clock_time_get": {
+		Params:  []ValueType{I32, I64, I32},
+		Results: []ValueType{I32},
+		Call:    clockTimeGet,
+	},
+	"clock_res_get": {
+		Params:  []ValueType{I32, I32},
+		Results: []ValueType{I32},
+		Call:    clockResGet,
+	},
+	"args_get":          empty([]ValueType{I32, I32}),
+	"args_sizes_get":    emptySizes(),
+	"environ_get":       empty([]ValueType{I32, I32}),
+	"environ_sizes_get": emptySizes(),
+	"sched_yield": {
+		Results: []ValueType{I32},
+		Call: func(s *Shim, mem Memory, args []uint64) ([]uint64, error) {
+			return []uint64{uint64(ErrnoSuccess)}, nil
+		},
+	},
+	"proc_exit": {
+		Params: []ValueType{I32},
+		Call: func(s *Shim, mem Memory, args []uint64) ([]uint64, error) {
+			return nil, &ExitError{Code: uint32(args[0])}
+		},
+	},
+}
+
+// Names returns the sorted names of the WASI functions provided to guests.
+func Names() []string {
+	names := make([]string, 0, len(Functions))
+	for name := range Functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsAllowed returns whether the shim implements the given WASI function.
+func IsAllowed(name string) bool {
+	_, ok := Functions[name]
+	return ok
+}
+
+// Resolve returns the implementation of the WASI import with the given name
+// and signature. Imports outside the shim are rejected in strict mode and fail
+// with ENOSYS otherwise.
+func Resolve(name string, params, results []ValueType, strict bool) (Function, error) {
+	if fn, ok := Functions[name]; ok {
+		return fn, nil
+	}
+	if strict {
+		return Function{}, fmt.Errorf("%w: %s.%s", ErrImportNotAllowed, ModuleName, name)
+	}
+	return Unsupported(params, results), nil
+}
+
+// Unsupported returns a function with the given signature that fails with
+// ENOSYS. It is used for WASI imports outside the shim in non-strict mode.
+func Unsupported(params, results []ValueType) Function {
+	return Function{
+		Params:  params,
+		Results: results,
+		Call: func(s *Shim, mem Memory, args []uint64) ([]uint64, error) {
+			if len(results) == 0 {
+				return nil, nil
+			}
+			ret := make([]uint64, len(results))
+			ret[0] = uint64(ErrnoNosys)
+			return ret, nil
+		},
+	}
+}
+
+func badf(params []ValueType) Function {
+	return Function{
+		Params:  params,
+		Results: []ValueType{I32},
+		Call: func(s *Shim, mem Memory, args []uint64) ([]uint64, error) {
+			return []uint64{uint64(ErrnoBadf)}, nil
+		},
+	}
+}
+
+func empty(params []ValueType) Function {
+	return Function{
+		Params:  params,
+		Results: []ValueType{I32},
+		Call: func(s *Shim, mem Memory, args []uint64) ([]uint64, error) {
+			return []uint64{uint64(ErrnoSuccess)}, nil
+		},
+	}
+}
+
+func emptySizes() Function {
+	return Function{
+		Params:  []ValueType{I32, I32},
+		Results: []ValueType{I32},
+		Call: func(s *Shim, mem Memory, args []uint64) ([]uint64, error) {
+			if !mem.WriteUint32Le(uint32(args[0]), 0) || !mem.WriteUint32Le(uint32(args[1]), 0) {
+				return []uint64{uint64(ErrnoFault)}, nil
+			}
+			return []uint64{uint64(ErrnoSuccess)}, nil
+		},
+	}
+}
+
+func fdWrite(s *Shim, mem Memory, args []uint64) ([]uint64, error) {
+	var (
+		fd       = uint32(args[0])
+		iovs     = uint32(args[1])
+		iovsLen  = uint32(args[2])
+		nwritten = uint32(args[3])
+	)
+	if fd != 1 && fd != 2 {
+		return []uint64{uint64(ErrnoBadf)}, nil
+	}
+	var data []byte
+	for ii := uint32(0); ii < iovsLen; ii++ {
+		offset, ok := mem.ReadUint32Le(iovs + ii*8)
+		if !ok {
+			return []uint64{uint64(ErrnoFault)}, nil
+		}
+		size, ok := mem.ReadUint32Le(iovs + ii*8 + 4)
+		if !ok {
+			return []uint64{uint64(ErrnoFault)}, nil
+		}
+		buf, ok := mem.Read(offset, size)
+		if !ok {
+			return []uint64{uint64(ErrnoFault)}, nil
+		}
+		data = append(data, buf...)
+	}
+	if s.debug != nil && len(data) > 0 {
+		s.debug(string(data))
+	}
+	if !mem.WriteUint32Le(nwritten, uint32(len(data))) {
+		return []uint64{uint64(ErrnoFault)}, nil
+	}
+	return []uint64{uint64(ErrnoSuccess)}, nil
+}
+
+func randomGet(s *Shim, mem Memory, args []uint64) ([]uint64, error) {
+	if s.config.DisallowRandom {
+		return []uint64{uint64(ErrnoNosys)}, nil
+	}
+	var (
+		buf    = uint32(args[0])
+		bufLen = uint32(args[1])
+	)
+	// bufLen is guest controlled, check it before generating the bytes
+	if uint64(buf)+uint64(bufLen) > uint64(mem.Size()) {
+		return []uint64{uint64(ErrnoFault)}, nil
+	}
+	if !mem.Write(buf, s.randomBytes(int(bufLen))) {
+		return []uint64{uint64(ErrnoFault)}, nil
+	}
+	return []uint64{uint64(ErrnoSuccess)}, nil
+}
+
+func clockTimeGet(s *Shim, mem Memory, args []uint64) ([]uint64, error) {
+	result := uint32(args[2])
+	if !mem.WriteUint64Le(result, s.config.Walltime) {
+		return []uint64{uint64(ErrnoFault)}, nil
+	}
+	return []uint64{uint64(ErrnoSuccess)}, nil
+}
+
+func clockResGet(s *Shim, mem Memory, args []uint64) ([]uint64, error) {
+	result := uint32(args[1])
+	if !mem.WriteUint64Le(result, 1) {
+		return []uint64{uint64(ErrnoFault)}, nil
+	}
+	return []uint64{uint64(ErrnoSuccess)}, nil
+}
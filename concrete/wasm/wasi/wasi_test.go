@@ -0,0 +1,153 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package wasi
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockMemory []byte
+
+func (m mockMemory) Size() uint32 {
+	return uint32(len(m))
+}
+
+func (m mockMemory) Read(offset, byteCount uint32) ([]byte, bool) {
+	if int(offset+byteCount) > len(m) {
+		return nil, false
+	}
+	return append([]byte{}, m[offset:offset+byteCount]...), true
+}
+
+func (m mockMemory) Write(offset uint32, data []byte) bool {
+	if int(offset)+len(data) > len(m) {
+		return false
+	}
+	copy(m[offset:], data)
+	return true
+}
+
+func (m mockMemory) ReadUint32Le(offset uint32) (uint32, bool) {
+	if int(offset+4) > len(m) {
+		return 0, false
+	}
+	return binary.LittleEndian.Uint32(m[offset:]), true
+}
+
+func (m mockMemory) WriteUint32Le(offset, value uint32) bool {
+	if int(offset+4) > len(m) {
+		return false
+	}
+	binary.LittleEndian.PutUint32(m[offset:], value)
+	return true
+}
+
+func (m mockMemory) WriteUint64Le(offset uint32, value uint64) bool {
+	if int(offset+8) > len(m) {
+		return false
+	}
+	binary.LittleEndian.PutUint64(m[offset:], value)
+	return true
+}
+
+func call(t *testing.T, shim *Shim, mem Memory, name string, args ...uint64) []uint64 {
+	ret, err := Functions[name].Call(shim, mem, args)
+	require.NoError(t, err)
+	return ret
+}
+
+func TestFdWrite(t *testing.T) {
+	r := require.New(t)
+	var out string
+	shim := NewShim(DefaultConfig, func(msg string) { out += msg })
+	mem := make(mockMemory, 64)
+
+	// iovec at 0 pointing to "hello" at 32
+	copy(mem[32:], "hello")
+	mem.WriteUint32Le(0, 32)
+	mem.WriteUint32Le(4, 5)
+
+	r.Equal([]uint64{uint64(ErrnoSuccess)}, call(t, shim, mem, "fd_write", 1, 0, 1, 16))
+	r.Equal("hello", out)
+	n, _ := mem.ReadUint32Le(16)
+	r.Equal(uint32(5), n)
+
+	r.Equal([]uint64{uint64(ErrnoBadf)}, call(t, shim, mem, "fd_write", 3, 0, 1, 16))
+}
+
+func TestRandomGet(t *testing.T) {
+	r := require.New(t)
+	shim := NewShim(Config{RandSeed: 1}, nil)
+	mem := make(mockMemory, 64)
+
+	r.Equal([]uint64{uint64(ErrnoSuccess)}, call(t, shim, mem, "random_get", 0, 40))
+	first, _ := mem.Read(0, 40)
+	call(t, shim, mem, "random_get", 0, 40)
+	second, _ := mem.Read(0, 40)
+	r.NotEqual(first, second)
+
+	shim.Reset()
+	call(t, shim, mem, "random_get", 0, 40)
+	reset, _ := mem.Read(0, 40)
+	r.Equal(first, reset)
+
+	other := NewShim(Config{RandSeed: 2}, nil)
+	call(t, other, mem, "random_get", 0, 40)
+	seeded, _ := mem.Read(0, 40)
+	r.NotEqual(first, seeded)
+
+	// Out of bounds buffers fail without advancing the stream
+	shim.Reset()
+	r.Equal([]uint64{uint64(ErrnoFault)}, call(t, shim, mem, "random_get", 32, 40))
+	r.Equal([]uint64{uint64(ErrnoFault)}, call(t, shim, mem, "random_get", 0, math.MaxUint32))
+	r.Zero(shim.randCounter)
+
+	disallowed := NewShim(Config{DisallowRandom: true}, nil)
+	r.Equal([]uint64{uint64(ErrnoNosys)}, call(t, disallowed, mem, "random_get", 0, 40))
+}
+
+func TestClockTimeGet(t *testing.T) {
+	r := require.New(t)
+	shim := NewShim(Config{Walltime: 42}, nil)
+	mem := make(mockMemory, 16)
+	r.Equal([]uint64{uint64(ErrnoSuccess)}, call(t, shim, mem, "clock_time_get", 0, 1, 8))
+	r.Equal(uint64(42), binary.LittleEndian.Uint64(mem[8:]))
+}
+
+func TestProcExit(t *testing.T) {
+	_, err := Functions["proc_exit"].Call(NewShim(DefaultConfig, nil), make(mockMemory, 0), []uint64{3})
+	require.Equal(t, &ExitError{Code: 3}, err)
+}
+
+func TestResolve(t *testing.T) {
+	r := require.New(t)
+
+	_, err := Resolve("fd_write", nil, nil, true)
+	r.NoError(err)
+
+	_, err = Resolve("path_open", []ValueType{I32}, []ValueType{I32}, true)
+	r.ErrorIs(err, ErrImportNotAllowed)
+
+	fn, err := Resolve("path_open", []ValueType{I32}, []ValueType{I32}, false)
+	r.NoError(err)
+	ret, err := fn.Call(NewShim(DefaultConfig, nil), make(mockMemory, 0), []uint64{0})
+	r.NoError(err)
+	r.Equal([]uint64{uint64(ErrnoNosys)}, ret)
+}
@@ -23,10 +23,13 @@ package wasm
 import (
 	"sync"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete"
 	"github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/concrete/wasm/host"
 	"github.com/ethereum/go-ethereum/concrete/wasm/memory"
+	"github.com/ethereum/go-ethereum/concrete/wasm/wasi"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/wasmerio/wasmer-go/wasmer"
 )
 
@@ -39,7 +42,11 @@ func NewWasmerPrecompileWithConfig(code []byte, config *wasmer.Config) concrete.
 	return newWasmerPrecompile(code, config)
 }
 
-func newWasmerModule(envCall host.WasmerHostFunc, code []byte, engineConfig *wasmer.Config) (*wasmer.Module, *wasmer.Instance, error) {
+func NewWasmerPrecompileWithWasiConfig(code []byte, config *wasmer.Config, wasiConfig wasi.Config) concrete.Precompile {
+	return newWasmerPrecompileWithWasiConfig(code, config, wasiConfig)
+}
+
+func newWasmerModule(envCall host.WasmerHostFunc, shim *wasi.Shim, code []byte, engineConfig *wasmer.Config) (*wasmer.Module, *wasmer.Instance, error) {
 	engine := wasmer.NewEngineWithConfig(engineConfig)
 	store := wasmer.NewStore(engine)
 	module, err := wasmer.NewModule(store, code)
@@ -48,19 +55,25 @@ func newWasmerModule(envCall host.WasmerHostFunc, code []byte, engineConfig *was
 		return nil, nil, err
 	}
 
-	wasiEnv, err := wasmer.NewWasiStateBuilder("wasi-program").Finalize()
-	if err != nil {
-		return nil, nil, err
+	if shim.Config().Strict {
+		for _, imp := range module.Imports() {
+			if err := checkImport(ModuleImport{Module: imp.Module(), Name: imp.Name()}); err != nil {
+				return nil, nil, err
+			}
+		}
 	}
-	importObject, err := wasiEnv.GenerateImportObject(store, module)
+
+	wasmerEnv := host.NewWasmerEnvironment()
+
+	wasiImports, err := host.NewWasmerWasiImports(store, module, shim, wasmerEnv)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	wasmerEnv := host.NewWasmerEnvironment()
+	importObject := wasmer.NewImportObject()
+	importObject.Register(wasi.ModuleName, wasiImports)
 
 	importObject.Register(
-		"env",
+		EnvModuleName,
 		map[string]wasmer.IntoExtern{
 			Environment_WasmFuncName: wasmer.NewFunctionWithEnvironment(
 				store,
@@ -90,6 +103,9 @@ type wasmerPrecompile struct {
 	memory      memory.Memory
 	allocator   memory.Allocator
 	environment *api.Env
+	wasi        *wasi.Shim
+	abiVersion  api.AbiVersion
+	codeHash    common.Hash
 	expIsStatic wasmer.NativeFunction
 	// expFinalise wasmer.NativeFunction
 	// expCommit wasmer.NativeFunction
@@ -97,10 +113,19 @@ type wasmerPrecompile struct {
 }
 
 func newWasmerPrecompile(code []byte, engineConfig *wasmer.Config) *wasmerPrecompile {
-	pc := &wasmerPrecompile{}
+	return newWasmerPrecompileWithWasiConfig(code, engineConfig, wasi.DefaultConfig)
+}
+
+func newWasmerPrecompileWithWasiConfig(code []byte, engineConfig *wasmer.Config, wasiConfig wasi.Config) *wasmerPrecompile {
+	pc := &wasmerPrecompile{codeHash: crypto.Keccak256Hash(code)}
+	pc.wasi = wasi.NewShim(wasiConfig, func(msg string) {
+		if pc.environment != nil {
+			pc.environment.Debug(msg)
+		}
+	})
 
 	envCall := host.NewWasmerEnvironmentCaller(func() api.Environment { return pc.environment })
-	module, instance, err := newWasmerModule(envCall, code, engineConfig)
+	module, instance, err := newWasmerModule(envCall, pc.wasi, code, engineConfig)
 	if err != nil {
 		panic(err)
 	}
@@ -109,6 +134,14 @@ func newWasmerPrecompile(code []byte, engineConfig *wasmer.Config) *wasmerPrecom
 	pc.module = module
 	pc.memory, pc.allocator = host.NewWasmerMemory(instance)
 
+	pc.abiVersion = api.AbiVersion1
+	if expAbiVersion, err := instance.Exports.GetFunction(AbiVersion_WasmFuncName); err == nil {
+		pc.abiVersion = api.AbiVersion(pc.call__Uint64(expAbiVersion))
+	}
+	if err := checkAbiVersion(pc.abiVersion); err != nil {
+		panic(err)
+	}
+
 	pc.expIsStatic, err = instance.Exports.GetFunction(IsStatic_WasmFuncName)
 	if err != nil {
 		panic(err)
@@ -174,9 +207,13 @@ func (p *wasmerPrecompile) before(env api.Environment) {
 		if !envImpl.Config().IsTrusted {
 			panic(api.ErrEnvNotTrusted)
 		}
+		if err := envImpl.SetAbiVersion(p.abiVersion); err != nil {
+			panic(err)
+		}
 	}
 	p.mutex.Lock()
 	p.environment = envImpl
+	p.wasi.Reset()
 }
 
 func (p *wasmerPrecompile) after(env api.Environment) {
@@ -185,6 +222,16 @@ func (p *wasmerPrecompile) after(env api.Environment) {
 	p.mutex.Unlock()
 }
 
+// AbiVersion returns the ABI version the module was built against.
+func (p *wasmerPrecompile) AbiVersion() api.AbiVersion {
+	return p.abiVersion
+}
+
+// CodeHash returns the keccak256 hash of the WASM code of the precompile.
+func (p *wasmerPrecompile) CodeHash() common.Hash {
+	return p.codeHash
+}
+
 func (p *wasmerPrecompile) IsStatic(input []byte) bool {
 	p.before(nil)
 	defer p.after(nil)
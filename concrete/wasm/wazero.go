@@ -17,70 +17,147 @@ package wasm
 
 import (
 	"context"
+	"fmt"
 	"sync"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete"
 	"github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/concrete/wasm/host"
 	"github.com/ethereum/go-ethereum/concrete/wasm/memory"
+	"github.com/ethereum/go-ethereum/concrete/wasm/wasi"
+	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/tetratelabs/wazero"
 	wz_api "github.com/tetratelabs/wazero/api"
-	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
 )
 
 // Note: For trusted use only. Precompiles can trigger a panic in the host.
 
 func NewWazeroPrecompile(code []byte) concrete.Precompile {
 	config := wazero.NewRuntimeConfigCompiler()
-	return newWazeroPrecompile(code, config)
+	return newWazeroPrecompile(context.Background(), code, config)
 }
 
 func NewWazeroPrecompileWithConfig(code []byte, config wazero.RuntimeConfig) concrete.Precompile {
-	return newWazeroPrecompile(code, config)
+	return newWazeroPrecompile(context.Background(), code, config)
 }
 
-func newWazeroModule(envCall host.WazeroHostFunc, code []byte, runtimeConfig wazero.RuntimeConfig) (wz_api.Module, wazero.Runtime, error) {
-	ctx := context.Background()
+func NewWazeroPrecompileWithWasiConfig(code []byte, config wazero.RuntimeConfig, wasiConfig wasi.Config) concrete.Precompile {
+	return newWazeroPrecompileWithWasiConfig(context.Background(), code, config, wasiConfig)
+}
+
+// LoadWazeroPrecompile validates the module and loads it with wazero. Unlike
+// NewWazeroPrecompile, it returns an error instead of panicking if the code
+// cannot be loaded, so it can be used as a concrete.PrecompileLoader.
+// The module is compiled and run under ctx: cancelling ctx aborts any running
+// call, and the runtime is closed once ctx is done.
+func LoadWazeroPrecompile(ctx context.Context, code []byte) (pc concrete.Precompile, err error) {
+	if err := ValidateModule(code); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			pc, err = nil, fmt.Errorf("could not load precompile: %v", r)
+		}
+	}()
+	config := wazero.NewRuntimeConfigCompiler().WithCloseOnContextDone(true)
+	wpc := newWazeroPrecompile(ctx, code, config)
+	context.AfterFunc(ctx, func() {
+		// Wait for the running call, which the closed context aborts
+		wpc.mutex.Lock()
+		defer wpc.mutex.Unlock()
+		wpc.runtime.Close(context.Background())
+	})
+	return wpc, nil
+}
+
+func newWazeroModule(ctx context.Context, envCall host.WazeroHostFunc, shim *wasi.Shim, code []byte, runtimeConfig wazero.RuntimeConfig) (wz_api.Module, wazero.Runtime, error) {
 	r := wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
-	_, err := r.NewHostModuleBuilder("env").
+	_, err := r.NewHostModuleBuilder(EnvModuleName).
 		NewFunctionBuilder().WithFunc(envCall).Export(Environment_WasmFuncName).
 		Instantiate(ctx)
 	if err != nil {
+		r.Close(ctx)
+		return nil, nil, err
+	}
+	compiled, err := r.CompileModule(ctx, code)
+	if err != nil {
+		r.Close(ctx)
+		return nil, nil, err
+	}
+	if shim.Config().Strict {
+		for _, def := range compiled.ImportedFunctions() {
+			moduleName, name, _ := def.Import()
+			if err := checkImport(ModuleImport{Module: moduleName, Name: name}); err != nil {
+				r.Close(ctx)
+				return nil, nil, err
+			}
+		}
+	}
+	if err := host.InstantiateWazeroWasi(ctx, r, compiled, shim); err != nil {
+		r.Close(ctx)
 		return nil, nil, err
 	}
-	wasi_snapshot_preview1.MustInstantiate(ctx, r)
-	mod, err := r.Instantiate(ctx, code)
+	mod, err := r.InstantiateModule(ctx, compiled, wazero.NewModuleConfig())
 	if err != nil {
+		r.Close(ctx)
 		return nil, nil, err
 	}
 	return mod, r, nil
 }
 
 type wazeroPrecompile struct {
+	ctx         context.Context
 	runtime     wazero.Runtime
 	module      wz_api.Module
 	mutex       sync.Mutex
 	memory      memory.Memory
 	allocator   memory.Allocator
 	environment *api.Env
+	wasi        *wasi.Shim
+	abiVersion  api.AbiVersion
+	codeHash    common.Hash
 	expIsStatic wz_api.Function
 	// expFinalise wz_api.Function
 	// expCommit   wz_api.Function
 	expRun wz_api.Function
 }
 
-func newWazeroPrecompile(code []byte, runtimeConfig wazero.RuntimeConfig) *wazeroPrecompile {
-	pc := &wazeroPrecompile{}
+func newWazeroPrecompile(ctx context.Context, code []byte, runtimeConfig wazero.RuntimeConfig) *wazeroPrecompile {
+	return newWazeroPrecompileWithWasiConfig(ctx, code, runtimeConfig, wasi.DefaultConfig)
+}
+
+func newWazeroPrecompileWithWasiConfig(ctx context.Context, code []byte, runtimeConfig wazero.RuntimeConfig, wasiConfig wasi.Config) *wazeroPrecompile {
+	pc := &wazeroPrecompile{ctx: ctx, codeHash: crypto.Keccak256Hash(code)}
+	pc.wasi = wasi.NewShim(wasiConfig, func(msg string) {
+		if pc.environment != nil {
+			pc.environment.Debug(msg)
+		}
+	})
 
 	envCall := host.NewWazeroEnvironmentCaller(func() api.Environment { return pc.environment })
-	mod, r, err := newWazeroModule(envCall, code, runtimeConfig)
+	mod, r, err := newWazeroModule(ctx, envCall, pc.wasi, code, runtimeConfig)
 	if err != nil {
 		panic(err)
 	}
 
 	pc.runtime = r
 	pc.module = mod
-	pc.memory, pc.allocator = host.NewWazeroMemory(context.Background(), mod)
+	defer func() {
+		if err := recover(); err != nil {
+			r.Close(ctx)
+			panic(err)
+		}
+	}()
+	pc.memory, pc.allocator = host.NewWazeroMemory(ctx, mod)
+
+	pc.abiVersion = api.AbiVersion1
+	if expAbiVersion := mod.ExportedFunction(AbiVersion_WasmFuncName); expAbiVersion != nil {
+		pc.abiVersion = api.AbiVersion(pc.call__Uint64(expAbiVersion))
+	}
+	if err := checkAbiVersion(pc.abiVersion); err != nil {
+		panic(err)
+	}
 
 	pc.expIsStatic = mod.ExportedFunction(IsStatic_WasmFuncName)
 	if pc.expIsStatic == nil {
@@ -103,8 +180,7 @@ func newWazeroPrecompile(code []byte, runtimeConfig wazero.RuntimeConfig) *wazer
 }
 
 func (p *wazeroPrecompile) call__Uint64(expFunc wz_api.Function) uint64 {
-	ctx := context.Background()
-	_ret, err := expFunc.Call(ctx)
+	_ret, err := expFunc.Call(p.ctx)
 	if err != nil {
 		panic(err)
 	}
@@ -120,10 +196,9 @@ func (p *wazeroPrecompile) call__Err(expFunc wz_api.Function) error {
 }
 
 func (p *wazeroPrecompile) call_Bytes_Uint64(expFunc wz_api.Function, input []byte) (ret uint64) {
-	ctx := context.Background()
 	pointer := memory.PutValue(p.memory, input)
 	defer p.allocator.Free(pointer)
-	_ret, err := expFunc.Call(ctx, pointer.Uint64())
+	_ret, err := expFunc.Call(p.ctx, pointer.Uint64())
 	if err != nil {
 		panic(err)
 	}
@@ -147,9 +222,13 @@ func (p *wazeroPrecompile) before(env api.Environment) {
 		if !envImpl.Config().IsTrusted {
 			panic(api.ErrEnvNotTrusted)
 		}
+		if err := envImpl.SetAbiVersion(p.abiVersion); err != nil {
+			panic(err)
+		}
 	}
 	p.mutex.Lock()
 	p.environment = envImpl
+	p.wasi.Reset()
 }
 
 func (p *wazeroPrecompile) after(env api.Environment) {
@@ -158,6 +237,16 @@ func (p *wazeroPrecompile) after(env api.Environment) {
 	p.mutex.Unlock()
 }
 
+// AbiVersion returns the ABI version the module was built against.
+func (p *wazeroPrecompile) AbiVersion() api.AbiVersion {
+	return p.abiVersion
+}
+
+// CodeHash returns the keccak256 hash of the WASM code of the precompile.
+func (p *wazeroPrecompile) CodeHash() common.Hash {
+	return p.codeHash
+}
+
 func (p *wazeroPrecompile) IsStatic(input []byte) bool {
 	p.before(nil)
 	defer p.after(nil)
@@ -345,6 +345,7 @@ func GenerateChainWithConcrete(config *params.ChainConfig, parent *types.Block,
 		if config.DAOForkSupport && config.DAOForkBlock != nil && config.DAOForkBlock.Cmp(b.header.Number) == 0 {
 			misc.ApplyDAOHardFork(statedb)
 		}
+		concrete.InstallPlaceholderCode(concreteRegistry, statedb, b.header.Number.Uint64())
 		// Execute any user modifications to the block
 		if gen != nil {
 			gen(i, b)
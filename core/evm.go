@@ -67,19 +67,20 @@ func NewEVMBlockContext(header *types.Header, chain ChainContext, author *common
 		random = &header.MixDigest
 	}
 	return vm.BlockContext{
-		CanTransfer:         CanTransfer,
-		Transfer:            Transfer,
-		GetHash:             GetHashFn(header, chain),
-		Coinbase:            beneficiary,
-		BlockNumber:         new(big.Int).Set(header.Number),
-		Time:                header.Time,
-		Difficulty:          new(big.Int).Set(header.Difficulty),
-		BaseFee:             baseFee,
-		BlobBaseFee:         blobBaseFee,
-		GasLimit:            header.GasLimit,
-		Random:              random,
-		L1CostFunc:          types.NewL1CostFunc(config, statedb),
-		ConcretePrecompiles: chain.Concrete().Precompiles(header.Number.Uint64()),
+		CanTransfer:          CanTransfer,
+		Transfer:             Transfer,
+		GetHash:              GetHashFn(header, chain),
+		Coinbase:             beneficiary,
+		BlockNumber:          new(big.Int).Set(header.Number),
+		Time:                 header.Time,
+		Difficulty:           new(big.Int).Set(header.Difficulty),
+		BaseFee:              baseFee,
+		BlobBaseFee:          blobBaseFee,
+		GasLimit:             header.GasLimit,
+		Random:               random,
+		L1CostFunc:           types.NewL1CostFunc(config, statedb),
+		ConcretePrecompiles:  chain.Concrete().Precompiles(header.Number.Uint64()),
+		ConcreteCapabilities: concrete.RegistryCapabilities(chain.Concrete(), header.Number.Uint64()),
 	}
 }
 
@@ -89,6 +90,9 @@ func NewEVMTxContext(msg *Message) vm.TxContext {
 		Origin:     msg.From,
 		GasPrice:   new(big.Int).Set(msg.GasPrice),
 		BlobHashes: msg.BlobHashes,
+
+		IsDepositTx:    msg.IsDepositTx,
+		RollupCostData: msg.RollupCostData,
 	}
 	if msg.BlobGasFeeCap != nil {
 		ctx.BlobFeeCap = new(big.Int).Set(msg.BlobGasFeeCap)
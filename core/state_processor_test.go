@@ -17,12 +17,14 @@
 package core
 
 import (
+	"bytes"
 	"crypto/ecdsa"
 	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/concrete"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
@@ -422,3 +424,105 @@ func GenerateBadBlock(parent *types.Block, engine consensus.Engine, txs types.Tr
 	}
 	return types.NewBlock(header, txs, nil, receipts, trie.NewStackTrie(nil))
 }
+
+type blankPrecompile struct{}
+
+func (blankPrecompile) IsStatic(input []byte) bool { return true }
+
+func (blankPrecompile) Run(env concrete.Environment, input []byte) ([]byte, error) {
+	return nil, nil
+}
+
+// TestConcretePlaceholderCode tests that placeholder code is installed at
+// concrete precompile addresses on activation, both when generating and when
+// importing blocks.
+func TestConcretePlaceholderCode(t *testing.T) {
+	var (
+		addr1 = common.HexToAddress("0xc1")
+		addr2 = common.HexToAddress("0xc2")
+		gspec = &Genesis{Config: params.TestChainConfig}
+	)
+	registry := concrete.NewRegistry()
+	registry.AddPrecompiles(0, concrete.PrecompileMap{addr1: blankPrecompile{}})
+	registry.AddPrecompiles(2, concrete.PrecompileMap{addr1: blankPrecompile{}, addr2: blankPrecompile{}})
+	registry.SetPlaceholderCode(concrete.DefaultPlaceholderCode)
+
+	_, blocks, _ := GenerateChainWithGenesisWithConcrete(gspec, ethash.NewFaker(), 3, registry, func(i int, b *BlockGen) {})
+
+	blockchain, _ := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+	defer blockchain.Stop()
+	blockchain.SetConcrete(registry)
+	if _, err := blockchain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for i, want := range []struct{ code1, code2 []byte }{
+		{concrete.DefaultPlaceholderCode, nil},
+		{concrete.DefaultPlaceholderCode, concrete.DefaultPlaceholderCode},
+	} {
+		statedb, err := blockchain.StateAt(blocks[i].Root())
+		if err != nil {
+			t.Fatalf("failed to get state: %v", err)
+		}
+		if code := statedb.GetCode(addr1); !bytes.Equal(code, want.code1) {
+			t.Errorf("block %d: code mismatch at %x: have %x, want %x", i+1, addr1, code, want.code1)
+		}
+		if code := statedb.GetCode(addr2); !bytes.Equal(code, want.code2) {
+			t.Errorf("block %d: code mismatch at %x: have %x, want %x", i+1, addr2, code, want.code2)
+		}
+	}
+}
+
+// TestConcretePlaceholderCodeReplay tests that placeholder code only changes the
+// state of the blocks at which precompiles are activated, and that a chain only
+// replays to the same state roots with the setting it was built with.
+func TestConcretePlaceholderCodeReplay(t *testing.T) {
+	var (
+		addr  = common.HexToAddress("0xc1")
+		gspec = &Genesis{Config: params.TestChainConfig}
+	)
+	newRegistry := func(code []byte) *concrete.GenericPrecompileRegistry {
+		registry := concrete.NewRegistry()
+		registry.AddPrecompiles(2, concrete.PrecompileMap{addr: blankPrecompile{}})
+		registry.SetPlaceholderCode(code)
+		return registry
+	}
+	enabled, disabled := newRegistry(concrete.DefaultPlaceholderCode), newRegistry(nil)
+
+	_, withCode, _ := GenerateChainWithGenesisWithConcrete(gspec, ethash.NewFaker(), 3, enabled, nil)
+	db, withoutCode, _ := GenerateChainWithGenesisWithConcrete(gspec, ethash.NewFaker(), 3, disabled, nil)
+	if withCode[0].Root() != withoutCode[0].Root() {
+		t.Errorf("root mismatch before activation: %x != %x", withCode[0].Root(), withoutCode[0].Root())
+	}
+	if withCode[1].Root() == withoutCode[1].Root() {
+		t.Errorf("placeholder code did not change the root at activation")
+	}
+
+	// Blocks after the activation do not install code, so extending the chain
+	// built without placeholder code gives the same root with either setting
+	extended, _ := GenerateChainWithConcrete(gspec.Config, withoutCode[2], ethash.NewFaker(), db, 1, enabled, nil)
+	reference, _ := GenerateChainWithConcrete(gspec.Config, withoutCode[2], ethash.NewFaker(), db, 1, disabled, nil)
+	if extended[0].Root() != reference[0].Root() {
+		t.Errorf("root mismatch after activation: %x != %x", extended[0].Root(), reference[0].Root())
+	}
+
+	for i, test := range []struct {
+		blocks   []*types.Block
+		registry *concrete.GenericPrecompileRegistry
+		fail     bool
+	}{
+		{withCode, enabled, false},
+		{withCode, disabled, true},
+		{withoutCode, disabled, false},
+		{withoutCode, enabled, true},
+	} {
+		blockchain, _ := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil, nil)
+		blockchain.SetConcrete(test.registry)
+		_, err := blockchain.InsertChain(test.blocks)
+		if test.fail && err == nil {
+			t.Errorf("test %d: replayed chain with a different placeholder setting", i)
+		} else if !test.fail && err != nil {
+			t.Errorf("test %d: failed to replay chain: %v", i, err)
+		}
+		blockchain.Stop()
+	}
+}
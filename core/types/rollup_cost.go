@@ -58,9 +58,13 @@ var (
 	// L1BlockAddr is the address of the L1Block contract which stores the L1 gas attributes.
 	L1BlockAddr = common.HexToAddress("0x4200000000000000000000000000000000000015")
 
-	L1BaseFeeSlot = common.BigToHash(big.NewInt(1))
-	OverheadSlot  = common.BigToHash(big.NewInt(5))
-	ScalarSlot    = common.BigToHash(big.NewInt(6))
+	// L1BlockNumberSlot stores the 8-byte L1 block number in bytes [24:32] of the slot,
+	// packed with the L1 block timestamp.
+	L1BlockNumberSlot = common.BigToHash(big.NewInt(0))
+	L1BaseFeeSlot     = common.BigToHash(big.NewInt(1))
+	L1BlockHashSlot   = common.BigToHash(big.NewInt(2))
+	OverheadSlot      = common.BigToHash(big.NewInt(5))
+	ScalarSlot        = common.BigToHash(big.NewInt(6))
 
 	// L2BlobBaseFeeSlot was added with the Ecotone upgrade and stores the blobBaseFee L1 gas
 	// attribute.
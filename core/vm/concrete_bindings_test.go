@@ -1,11 +1,14 @@
 package vm
 
 import (
+	"errors"
 	"math/big"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/concrete"
+	cc_api "github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -43,6 +46,7 @@ func newTestBlockContext() BlockContext {
 		BaseFee:     big.NewInt(5678),
 		Coinbase:    common.HexToAddress("0x0854167430392BBc2D15Dd1Cc17e761897AF31C9"),
 		Random:      &randomHash,
+		BlobBaseFee: big.NewInt(9),
 	}
 
 	return blockCtx
@@ -51,7 +55,7 @@ func newTestBlockContext() BlockContext {
 func TestConcreteBlockContext(t *testing.T) {
 	r := require.New(t)
 	blockCtx := newTestBlockContext()
-	ccBlockCtx := concreteBlockContext{ctx: &blockCtx}
+	ccBlockCtx := concreteBlockContext{ctx: &blockCtx, chainID: big.NewInt(901)}
 
 	var (
 		block0Hash = blockCtx.GetHash(0)
@@ -83,6 +87,12 @@ func TestConcreteBlockContext(t *testing.T) {
 	t.Run("Random", func(t *testing.T) {
 		r.Equal(*blockCtx.Random, ccBlockCtx.Random())
 	})
+	t.Run("BlobBaseFee", func(t *testing.T) {
+		r.Equal(uint256.MustFromBig(blockCtx.BlobBaseFee), ccBlockCtx.BlobBaseFee())
+	})
+	t.Run("ChainID", func(t *testing.T) {
+		r.Equal(uint256.NewInt(901), ccBlockCtx.ChainID())
+	})
 }
 
 func TestEVMCallStatic(t *testing.T) {
@@ -212,3 +222,96 @@ func TestEVMCreate(t *testing.T) {
 		r.Less(remainingGas, gas, "Gas used should be less than the provided gas")
 	})
 }
+
+func TestConcreteEnvironmentRollup(t *testing.T) {
+	var (
+		r          = require.New(t)
+		statedb, _ = state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		callerAddr = common.BytesToAddress([]byte("caller"))
+		selfAddr   = common.BytesToAddress([]byte("self"))
+	)
+	contract := NewContract(AccountRef(callerAddr), AccountRef(selfAddr), new(uint256.Int), 10_000_000)
+
+	evm := NewEVM(BlockContext{}, TxContext{GasPrice: big.NewInt(0)}, statedb, params.TestChainConfig, Config{})
+	env := evm.newConcreteEnvironment(contract, false)
+	r.False(env.Contract().IsDeposit)
+	r.Equal(new(uint256.Int), env.Contract().L1Fee())
+
+	var costCalls int
+	blockCtx := BlockContext{
+		L1CostFunc: func(rcd types.RollupCostData, blockTime uint64) *big.Int {
+			costCalls++
+			return big.NewInt(42)
+		},
+	}
+	txCtx := TxContext{GasPrice: big.NewInt(0), IsDepositTx: true}
+	evm = NewEVM(blockCtx, txCtx, statedb, params.TestChainConfig, Config{})
+	env = evm.newConcreteEnvironment(contract, false)
+	r.True(env.Contract().IsDeposit)
+	r.Zero(costCalls, "L1 fee should only be computed on demand")
+	r.Equal(uint256.NewInt(42), env.Contract().L1Fee())
+	r.Equal(1, costCalls)
+}
+
+// mintPrecompile mints balance to its caller and reverts if given any input.
+type mintPrecompile struct{}
+
+func (pc *mintPrecompile) IsStatic(input []byte) bool { return false }
+
+func (pc *mintPrecompile) AbiVersion() cc_api.AbiVersion { return cc_api.AbiVersion2 }
+
+func (pc *mintPrecompile) Run(env cc_api.Environment, input []byte) ([]byte, error) {
+	env.MintBalance(env.GetCaller(), uint256.NewInt(100))
+	if len(input) > 0 {
+		return nil, errors.New("revert")
+	}
+	return nil, nil
+}
+
+func TestConcreteSystemOperations(t *testing.T) {
+	var (
+		r          = require.New(t)
+		statedb, _ = state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		callerAddr = common.BytesToAddress([]byte("caller"))
+		pcAddr     = common.BytesToAddress([]byte("mint"))
+		gas        = uint64(100_000)
+	)
+	blockCtx := newTestBlockContext()
+	blockCtx.ConcretePrecompiles = concrete.PrecompileMap{pcAddr: &mintPrecompile{}}
+	txCtx := TxContext{GasPrice: big.NewInt(0)}
+
+	// Without the capability the call fails
+	evm := NewEVM(blockCtx, txCtx, statedb, params.TestChainConfig, Config{})
+	_, _, err := evm.Call(AccountRef(callerAddr), pcAddr, nil, gas, new(uint256.Int))
+	r.Error(err)
+	r.True(statedb.GetBalance(callerAddr).IsZero())
+
+	blockCtx.ConcreteCapabilities = concrete.CapabilityMap{pcAddr: cc_api.CapabilityBalance}
+	evm = NewEVM(blockCtx, txCtx, statedb, params.TestChainConfig, Config{})
+	_, _, err = evm.Call(AccountRef(callerAddr), pcAddr, nil, gas, new(uint256.Int))
+	r.NoError(err)
+	r.Equal(uint256.NewInt(100), statedb.GetBalance(callerAddr))
+
+	// Minting is undone when the call reverts
+	_, _, err = evm.Call(AccountRef(callerAddr), pcAddr, []byte{0x01}, gas, new(uint256.Int))
+	r.ErrorIs(err, ErrExecutionReverted)
+	r.Equal(uint256.NewInt(100), statedb.GetBalance(callerAddr))
+}
+
+func TestConcreteEnvironmentMode(t *testing.T) {
+	var (
+		r          = require.New(t)
+		statedb, _ = state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		callerAddr = common.BytesToAddress([]byte("caller"))
+		selfAddr   = common.BytesToAddress([]byte("self"))
+		txCtx      = TxContext{GasPrice: big.NewInt(0)}
+	)
+	contract := NewContract(AccountRef(callerAddr), AccountRef(selfAddr), new(uint256.Int), 10_000_000)
+
+	// Execution defaults to the consensus mode
+	evm := NewEVM(BlockContext{}, txCtx, statedb, params.TestChainConfig, Config{})
+	r.Equal(cc_api.ExecutionModeConsensus, evm.newConcreteEnvironment(contract, false).Config().Mode)
+
+	evm = NewEVM(BlockContext{}, txCtx, statedb, params.TestChainConfig, Config{ConcreteMode: cc_api.ExecutionModeTrace})
+	r.Equal(cc_api.ExecutionModeTrace, evm.newConcreteEnvironment(contract, false).Config().Mode)
+}
@@ -74,22 +74,51 @@ func (evm *EVM) concretePrecompile(addr common.Address) (concrete.Precompile, bo
 
 func (evm *EVM) newConcreteEnvironment(contract *Contract, static bool) *cc_api.Env {
 	env := cc_api.NewEnvironment(
-		cc_api.EnvConfig{IsStatic: static, IsTrusted: true},
+		cc_api.EnvConfig{
+			IsStatic:     static,
+			IsTrusted:    true,
+			Capabilities: evm.Context.ConcreteCapabilities[contract.Address()],
+			Mode:         evm.Config.ConcreteMode,
+		},
 		true,
 		evm.StateDB,
-		concreteBlockContext{&evm.Context},
+		concreteBlockContext{&evm.Context, evm.chainConfig.ChainID},
 		&concreteEVM{evm, contract},
 		&cc_api.Contract{
-			Origin:   evm.TxContext.Origin,
-			Caller:   contract.Caller(),
-			Address:  contract.Address(),
-			GasPrice: uint256.MustFromBig(evm.TxContext.GasPrice),
+			Origin:     evm.TxContext.Origin,
+			Caller:     contract.Caller(),
+			Address:    contract.Address(),
+			GasPrice:   uint256.MustFromBig(evm.TxContext.GasPrice),
+			BlobHashes: evm.TxContext.BlobHashes,
+			IsDeposit:  evm.TxContext.IsDepositTx,
+			L1Fee:      evm.txL1Fee,
 			// input, gas, value are set in RunPrecompile
 		},
 	)
+	if tracer, ok := evm.Config.Tracer.(cc_api.GasTracer); ok {
+		env.SetGasTracer(tracer)
+	}
+	if evm.Config.ConcreteDebugTracer != nil {
+		env.SetDebugTracer(evm.Config.ConcreteDebugTracer)
+	} else if tracer, ok := evm.Config.Tracer.(cc_api.DebugTracer); ok {
+		env.SetDebugTracer(tracer)
+	}
 	return env
 }
 
+// txL1Fee returns the L1 data fee of the current transaction, or zero if none
+// is charged.
+func (evm *EVM) txL1Fee() *uint256.Int {
+	if evm.Context.L1CostFunc == nil {
+		return new(uint256.Int)
+	}
+	fee := evm.Context.L1CostFunc(evm.TxContext.RollupCostData, evm.Context.Time)
+	if fee == nil {
+		return new(uint256.Int)
+	}
+	return uint256.MustFromBig(fee)
+}
+
 func concreteErrToEVMErr(err error) error {
 	switch err {
 	case cc_api.ErrWriteProtection:
@@ -129,7 +158,8 @@ type BlockContext struct {
 	Random      *common.Hash   // Provides information for PREVRANDAO
 
 	// Concrete precompiles
-	ConcretePrecompiles concrete.PrecompileMap
+	ConcretePrecompiles  concrete.PrecompileMap
+	ConcreteCapabilities concrete.CapabilityMap // Capabilities granted to concrete precompiles
 }
 
 // TxContext provides the EVM with information about a transaction.
@@ -140,6 +170,10 @@ type TxContext struct {
 	GasPrice   *big.Int       // Provides information for GASPRICE (and is used to zero the basefee if NoBaseFee is set)
 	BlobHashes []common.Hash  // Provides information for BLOBHASH
 	BlobFeeCap *big.Int       // Is used to zero the blobbasefee if NoBaseFee is set
+
+	// OP-Stack additions
+	IsDepositTx    bool                 // Provides deposit status to concrete precompiles
+	RollupCostData types.RollupCostData // Provides the L1 data fee to concrete precompiles
 }
 
 // EVM is the Ethereum Virtual Machine base object and provides
@@ -232,6 +266,10 @@ func (evm *EVM) Interpreter() *EVMInterpreter {
 // the necessary steps to create accounts and reverses the state in case of an
 // execution error or failed value transfer.
 func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas uint64, value *uint256.Int) (ret []byte, leftOverGas uint64, err error) {
+	if hooks := evm.Config.CallHooks; hooks != nil {
+		caller = evm.enterCallHooks(hooks, caller, addr, input)
+		defer func() { ret, err = hooks.CallExit(evm, addr, ret, err) }()
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -429,6 +467,10 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 // Opcodes that attempt to perform such modifications will result in exceptions
 // instead of performing the modifications.
 func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte, gas uint64) (ret []byte, leftOverGas uint64, err error) {
+	if hooks := evm.Config.CallHooks; hooks != nil {
+		caller = evm.enterCallHooks(hooks, caller, addr, input)
+		defer func() { ret, err = hooks.CallExit(evm, addr, ret, err) }()
+	}
 	// Fail if we're trying to execute above the call depth limit
 	if evm.depth > int(params.CallCreateDepth) {
 		return nil, gas, ErrDepth
@@ -611,6 +653,24 @@ func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
 
+// CallHooks lets testing tools observe and alter message calls, e.g. to
+// implement cheatcodes. Hooks are only invoked for CALL and STATICCALL.
+type CallHooks interface {
+	// CallEnter is called before a call is executed and returns the address the
+	// callee will observe as its caller.
+	CallEnter(evm *EVM, caller common.Address, addr common.Address, input []byte) common.Address
+	// CallExit is called after a call is executed and any state changes are
+	// reverted, and returns the result the caller will observe.
+	CallExit(evm *EVM, addr common.Address, ret []byte, err error) ([]byte, error)
+}
+
+func (evm *EVM) enterCallHooks(hooks CallHooks, caller ContractRef, addr common.Address, input []byte) ContractRef {
+	if newCaller := hooks.CallEnter(evm, caller.Address(), addr, input); newCaller != caller.Address() {
+		return AccountRef(newCaller)
+	}
+	return caller
+}
+
 // TODO: if this is called multiple times it should be done once during construction
 func (evm *EVM) ConcretePrecompiledAddressesSet() map[common.Address]struct{} {
 	pcs := evm.Context.ConcretePrecompiles
@@ -622,7 +682,8 @@ func (evm *EVM) ConcretePrecompiledAddressesSet() map[common.Address]struct{} {
 }
 
 type concreteBlockContext struct {
-	ctx *BlockContext
+	ctx     *BlockContext
+	chainID *big.Int
 }
 
 func (b concreteBlockContext) GetHash(block uint64) common.Hash {
@@ -657,6 +718,20 @@ func (b concreteBlockContext) Random() common.Hash {
 	return *b.ctx.Random
 }
 
+func (b concreteBlockContext) BlobBaseFee() *uint256.Int {
+	if b.ctx.BlobBaseFee == nil {
+		return new(uint256.Int)
+	}
+	return uint256.MustFromBig(b.ctx.BlobBaseFee)
+}
+
+func (b concreteBlockContext) ChainID() *uint256.Int {
+	if b.chainID == nil {
+		return new(uint256.Int)
+	}
+	return uint256.MustFromBig(b.chainID)
+}
+
 var _ cc_api.BlockContext = (*concreteBlockContext)(nil)
 
 type extCaller interface {
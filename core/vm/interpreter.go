@@ -19,6 +19,7 @@ package vm
 import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
+	cc_api "github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -29,11 +30,14 @@ type PrecompileOverrides func(params.Rules, PrecompiledContract, common.Address)
 
 // Config are the configuration options for the Interpreter
 type Config struct {
-	Tracer                      EVMLogger           // Opcode logger
-	NoBaseFee                   bool                // Forces the EIP-1559 baseFee to 0 (needed for 0 price calls)
-	EnablePreimageRecording     bool                // Enables recording of SHA3/keccak preimages
-	ExtraEips                   []int               // Additional EIPS that are to be enabled
-	OptimismPrecompileOverrides PrecompileOverrides // Precompile overrides for Optimism
+	Tracer                      EVMLogger            // Opcode logger
+	NoBaseFee                   bool                 // Forces the EIP-1559 baseFee to 0 (needed for 0 price calls)
+	EnablePreimageRecording     bool                 // Enables recording of SHA3/keccak preimages
+	ExtraEips                   []int                // Additional EIPS that are to be enabled
+	OptimismPrecompileOverrides PrecompileOverrides  // Precompile overrides for Optimism
+	CallHooks                   CallHooks            // Call hooks for testing tools, must be nil in production
+	ConcreteDebugTracer         cc_api.DebugTracer   // Receives debug messages of concrete precompiles, e.g. for RPC calls
+	ConcreteMode                cc_api.ExecutionMode // Context concrete precompiles are executed in
 }
 
 // ScopeContext contains the things that are per-call, such as stack and memory,
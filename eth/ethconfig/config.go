@@ -151,6 +151,10 @@ type Config struct {
 	// RPCEVMTimeout is the global timeout for eth-call.
 	RPCEVMTimeout time.Duration
 
+	// RPCPrecompileOverrides allows eth-call variants to override concrete
+	// precompiles with code, e.g. WASM modules.
+	RPCPrecompileOverrides bool
+
 	// RPCTxFeeCap is the global transaction fee(price * gaslimit) cap for
 	// send-transaction variants. The unit is ether.
 	RPCTxFeeCap float64
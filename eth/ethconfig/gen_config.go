@@ -53,6 +53,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		DocRoot                                 string `toml:"-"`
 		RPCGasCap                               uint64
 		RPCEVMTimeout                           time.Duration
+		RPCPrecompileOverrides                  bool
 		RPCTxFeeCap                             float64
 		OverrideCancun                          *uint64 `toml:",omitempty"`
 		OverrideVerkle                          *uint64 `toml:",omitempty"`
@@ -105,6 +106,7 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.DocRoot = c.DocRoot
 	enc.RPCGasCap = c.RPCGasCap
 	enc.RPCEVMTimeout = c.RPCEVMTimeout
+	enc.RPCPrecompileOverrides = c.RPCPrecompileOverrides
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
 	enc.OverrideCancun = c.OverrideCancun
 	enc.OverrideVerkle = c.OverrideVerkle
@@ -161,6 +163,7 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		DocRoot                                 *string `toml:"-"`
 		RPCGasCap                               *uint64
 		RPCEVMTimeout                           *time.Duration
+		RPCPrecompileOverrides                  *bool
 		RPCTxFeeCap                             *float64
 		OverrideCancun                          *uint64 `toml:",omitempty"`
 		OverrideVerkle                          *uint64 `toml:",omitempty"`
@@ -288,6 +291,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.RPCEVMTimeout != nil {
 		c.RPCEVMTimeout = *dec.RPCEVMTimeout
 	}
+	if dec.RPCPrecompileOverrides != nil {
+		c.RPCPrecompileOverrides = *dec.RPCPrecompileOverrides
+	}
 	if dec.RPCTxFeeCap != nil {
 		c.RPCTxFeeCap = *dec.RPCTxFeeCap
 	}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/concrete"
+	cc_api "github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -44,7 +45,8 @@ type Options struct {
 	Header *types.Header       // Header defining the block context to execute in
 	State  *state.StateDB      // Pre-state on top of which to estimate the gas
 
-	ConcretePrecompiles concrete.PrecompileMap // Concrete precompiled contracts to execute
+	ConcretePrecompiles  concrete.PrecompileMap // Concrete precompiled contracts to execute
+	ConcreteCapabilities concrete.CapabilityMap // Capabilities of the concrete precompiles, set with them
 
 	ErrorRatio float64 // Allowed overestimation ratio for faster estimation termination
 }
@@ -212,9 +214,14 @@ func run(ctx context.Context, call *core.Message, opts *Options) (*core.Executio
 	var (
 		msgContext = core.NewEVMTxContext(call)
 		evmContext = core.NewEVMBlockContext(opts.Header, opts.Chain, nil, opts.Config, opts.State)
-
+	)
+	if opts.ConcretePrecompiles != nil {
+		evmContext.ConcretePrecompiles = opts.ConcretePrecompiles
+		evmContext.ConcreteCapabilities = opts.ConcreteCapabilities
+	}
+	var (
 		dirtyState = opts.State.Copy()
-		evm        = vm.NewEVM(evmContext, msgContext, dirtyState, opts.Config, vm.Config{NoBaseFee: true})
+		evm        = vm.NewEVM(evmContext, msgContext, dirtyState, opts.Config, vm.Config{NoBaseFee: true, ConcreteMode: cc_api.ExecutionModeEstimate})
 	)
 	// Monitor the outer context and interrupt the EVM upon cancellation. To avoid
 	// a dangling goroutine until the outer estimation finishes, create an internal
@@ -122,6 +122,7 @@ type testBackend struct {
 	engine      consensus.Engine
 	chaindb     ethdb.Database
 	chain       *core.BlockChain
+	concrete    concrete.PrecompileRegistry
 
 	refHook func() // Hook is invoked when the requested state is referenced
 	relHook func() // Hook is invoked when the requested state is released
@@ -133,6 +134,12 @@ type testBackend struct {
 // testBackend creates a new test backend. OBS: After test is done, teardown must be
 // invoked in order to release associated resources.
 func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) *testBackend {
+	return newTestBackendWithConcrete(t, n, gspec, concrete.NewRegistry(), generator)
+}
+
+// newTestBackendWithConcrete creates a new test backend whose chain runs the
+// concrete precompiles of the given registry.
+func newTestBackendWithConcrete(t *testing.T, n int, gspec *core.Genesis, registry concrete.PrecompileRegistry, generator func(i int, b *core.BlockGen)) *testBackend {
 	mock := new(mockHistoricalBackend)
 	historicalAddr := newMockHistoricalBackend(t, mock)
 
@@ -147,9 +154,10 @@ func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i i
 		chaindb:        rawdb.NewMemoryDatabase(),
 		historical:     historicalClient,
 		mockHistorical: mock,
+		concrete:       registry,
 	}
 	// Generate blocks for testing
-	_, blocks, _ := core.GenerateChainWithGenesis(gspec, backend.engine, n, generator)
+	_, blocks, _ := core.GenerateChainWithGenesisWithConcrete(gspec, backend.engine, n, registry, generator)
 
 	// Import the canonical chain
 	cacheConfig := &core.CacheConfig{
@@ -163,6 +171,7 @@ func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i i
 	if err != nil {
 		t.Fatalf("failed to create tester chain: %v", err)
 	}
+	chain.SetConcrete(registry)
 	if n, err := chain.InsertChain(blocks); err != nil {
 		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
 	}
@@ -201,6 +210,10 @@ func (b *testBackend) RPCGasCap() uint64 {
 	return 25000000
 }
 
+func (b *testBackend) RPCPrecompileOverrides() bool {
+	return true
+}
+
 func (b *testBackend) ChainConfig() *params.ChainConfig {
 	return b.chainConfig
 }
@@ -271,7 +284,7 @@ func (b *testBackend) HistoricalRPCService() *rpc.Client {
 func (b *testBackend) SetConcrete(concrete.PrecompileRegistry) {}
 
 func (b *testBackend) Concrete() concrete.PrecompileRegistry {
-	return &concrete.GenericPrecompileRegistry{}
+	return b.concrete
 }
 
 func TestTraceCall(t *testing.T) {
@@ -1184,3 +1197,89 @@ func TestTraceChain(t *testing.T) {
 		}
 	}
 }
+
+type blankPrecompile struct{}
+
+func (blankPrecompile) IsStatic(input []byte) bool { return true }
+
+func (blankPrecompile) Run(env concrete.Environment, input []byte) ([]byte, error) {
+	return nil, nil
+}
+
+// TestTraceConcretePlaceholderCode tests that blocks are traced on the state the
+// chain imported them on, with placeholder code installed at the concrete
+// precompiles activated by the block.
+func TestTraceConcretePlaceholderCode(t *testing.T) {
+	t.Parallel()
+
+	var (
+		accounts   = newAccounts(1)
+		pcAddr     = common.HexToAddress("0xc1")
+		sizer      = common.HexToAddress("0xc2")
+		activation = uint64(2)
+		genBlocks  = 3
+		signer     = types.HomesteadSigner{}
+	)
+	genesis := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			// PUSH20 pcAddr, EXTCODESIZE, STOP
+			sizer: {Code: append(append([]byte{byte(vm.PUSH20)}, pcAddr.Bytes()...), byte(vm.EXTCODESIZE), byte(vm.STOP))},
+		},
+	}
+	registry := concrete.NewRegistry()
+	registry.AddPrecompiles(activation, concrete.PrecompileMap{pcAddr: blankPrecompile{}})
+	registry.SetPlaceholderCode(concrete.DefaultPlaceholderCode)
+	backend := newTestBackendWithConcrete(t, genBlocks, genesis, registry, func(i int, b *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(uint64(i), sizer, new(big.Int), 100000, b.BaseFee(), nil), signer, accounts[0].key)
+		b.AddTx(tx)
+	})
+	defer backend.chain.Stop()
+	api := NewAPI(backend)
+
+	// The code size is on the stack when the sizer stops
+	codeSize := func(result interface{}) string {
+		var res logger.ExecutionResult
+		if err := json.Unmarshal(result.(json.RawMessage), &res); err != nil {
+			t.Fatalf("failed to decode trace: %v", err)
+		}
+		return (*res.StructLogs[len(res.StructLogs)-1].Stack)[0]
+	}
+	for number := uint64(1); number <= uint64(genBlocks); number++ {
+		want := "0x0"
+		if number >= activation {
+			want = "0x1"
+		}
+		block := backend.chain.GetBlockByNumber(number)
+		roots, err := api.IntermediateRoots(context.Background(), block.Hash(), nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to get intermediate roots: %v", number, err)
+		}
+		// The block reward is paid after the last transaction
+		statedb, _ := backend.chain.StateAt(block.Root())
+		statedb.SubBalance(block.Coinbase(), ethash.ConstantinopleBlockReward)
+		if want := statedb.IntermediateRoot(true); roots[len(roots)-1] != want {
+			t.Errorf("block %d: root mismatch, have %x, want %x", number, roots[len(roots)-1], want)
+		}
+		traces, err := api.TraceBlockByNumber(context.Background(), rpc.BlockNumber(number), nil)
+		if err != nil {
+			t.Fatalf("block %d: failed to trace block: %v", number, err)
+		}
+		if have := codeSize(traces[0].Result); have != want {
+			t.Errorf("block %d: code size mismatch when tracing block, have %s, want %s", number, have, want)
+		}
+	}
+
+	from, _ := api.blockByNumber(context.Background(), 0)
+	to, _ := api.blockByNumber(context.Background(), rpc.BlockNumber(genBlocks))
+	for result := range api.traceChain(from, to, nil, nil) {
+		want := "0x0"
+		if uint64(result.Block) >= activation {
+			want = "0x1"
+		}
+		if have := codeSize(result.Traces[0].Result); have != want {
+			t.Errorf("block %d: code size mismatch when tracing chain, have %s, want %s", result.Block, have, want)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/concrete"
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// debugPrecompile emits its input as a debug message and reverts.
+type debugPrecompile struct{}
+
+func (pc *debugPrecompile) IsStatic(input []byte) bool { return true }
+
+func (pc *debugPrecompile) Run(env api.Environment, input []byte) ([]byte, error) {
+	env.Debugf(string(input), "caller", env.GetCaller())
+	return nil, errors.New("revert")
+}
+
+func TestConcreteDebugTracer(t *testing.T) {
+	var (
+		to      = common.HexToAddress("0x00000000000000000000000000000000deadbeef")
+		origin  = common.HexToAddress("0x00000000000000000000000000000000feed")
+		context = vm.BlockContext{
+			CanTransfer:         core.CanTransfer,
+			Transfer:            core.Transfer,
+			BlockNumber:         new(big.Int).SetUint64(8000000),
+			Difficulty:          big.NewInt(0x30000),
+			GasLimit:            uint64(6000000),
+			ConcretePrecompiles: concrete.PrecompileMap{to: &debugPrecompile{}},
+		}
+	)
+	tracer, err := tracers.DefaultDirectory.New("concreteDebugTracer", new(tracers.Context), nil)
+	if err != nil {
+		t.Fatalf("failed to create tracer: %v", err)
+	}
+	state := tests.MakePreState(rawdb.NewMemoryDatabase(), types.GenesisAlloc{}, false, rawdb.HashScheme)
+	defer state.Close()
+
+	evm := vm.NewEVM(context, vm.TxContext{Origin: origin, GasPrice: big.NewInt(0)}, state.StateDB, params.MainnetChainConfig, vm.Config{Tracer: tracer})
+	msg := &core.Message{
+		To:        &to,
+		From:      origin,
+		Value:     big.NewInt(0),
+		GasLimit:  80000,
+		GasPrice:  big.NewInt(0),
+		GasFeeCap: big.NewInt(0),
+		GasTipCap: big.NewInt(0),
+		Data:      []byte("hello"),
+	}
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(msg.GasLimit))
+	if _, err := st.TransitionDb(); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	// Messages of reverted calls are kept
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	want := `[{"address":"0x00000000000000000000000000000000deadbeef","message":"hello caller=0x000000000000000000000000000000000000FEeD"}]`
+	if string(res) != want {
+		t.Errorf("trace mismatch\n have: %v\n want: %v\n", string(res), want)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2023 The concrete-geth Authors
+//
+// The concrete-geth library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The concrete library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the concrete library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/concrete/api"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("concreteDebugTracer", newConcreteDebugTracer, false)
+}
+
+// concreteDebugTracer collects the debug messages emitted by concrete
+// precompiles, in the order they were emitted, including those of reverted
+// calls.
+type concreteDebugTracer struct {
+	noopTracer
+	api.DebugCollector
+	reason error // Textual reason for the interruption
+}
+
+// newConcreteDebugTracer returns a new concrete debug tracer.
+func newConcreteDebugTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &concreteDebugTracer{}, nil
+}
+
+// GetResult returns the debug messages as a json array.
+func (t *concreteDebugTracer) GetResult() (json.RawMessage, error) {
+	messages := t.Messages
+	if messages == nil {
+		messages = []api.DebugMessage{}
+	}
+	res, err := json.Marshal(messages)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *concreteDebugTracer) Stop(err error) {
+	t.reason = err
+}
+
+var _ api.DebugTracer = (*concreteDebugTracer)(nil)
@@ -19,6 +19,7 @@ package ethapi
 import (
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math/big"
@@ -34,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/concrete"
+	cc_api "github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
 	"github.com/ethereum/go-ethereum/core"
@@ -59,6 +61,8 @@ const estimateGasErrorRatio = 0.015
 
 var errBlobTxNotSupported = errors.New("signing blob transactions not supported")
 
+var errPrecompileOverridesDisabled = errors.New("precompile code overrides are disabled, enable them with --rpc.precompileoverrides")
+
 // EthereumAPI provides an API to access Ethereum related information.
 type EthereumAPI struct {
 	b Backend
@@ -1036,12 +1040,53 @@ func (s *BlockChainAPI) GetBlockReceipts(ctx context.Context, blockNrOrHash rpc.
 // set, message execution will only use the data in the given state. Otherwise
 // if statDiff is set, all diff will be applied first and then execute the call
 // message.
+// Precompile replaces or removes the concrete precompile at the account and
+// movePrecompileToAddress moves it to another address. Precompiles are moved
+// before being replaced, so a precompile can be moved and a new one loaded in
+// its place.
 type OverrideAccount struct {
-	Nonce     *hexutil.Uint64              `json:"nonce"`
-	Code      *hexutil.Bytes               `json:"code"`
-	Balance   **hexutil.Big                `json:"balance"`
-	State     *map[common.Hash]common.Hash `json:"state"`
-	StateDiff *map[common.Hash]common.Hash `json:"stateDiff"`
+	Nonce            *hexutil.Uint64              `json:"nonce"`
+	Code             *hexutil.Bytes               `json:"code"`
+	Balance          **hexutil.Big                `json:"balance"`
+	State            *map[common.Hash]common.Hash `json:"state"`
+	StateDiff        *map[common.Hash]common.Hash `json:"stateDiff"`
+	Precompile       *PrecompileOverride          `json:"precompile"`
+	MovePrecompileTo *common.Address              `json:"movePrecompileToAddress"`
+}
+
+// PrecompileOverride is either the code of a precompile to load in place of
+// the concrete precompile at an address, e.g. a WASM module, or "remove".
+type PrecompileOverride struct {
+	Remove bool
+	Code   []byte
+}
+
+const precompileOverrideRemove = "remove"
+
+// MarshalJSON implements json.Marshaler.
+func (o PrecompileOverride) MarshalJSON() ([]byte, error) {
+	if o.Remove {
+		return json.Marshal(precompileOverrideRemove)
+	}
+	return json.Marshal(hexutil.Bytes(o.Code))
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *PrecompileOverride) UnmarshalJSON(input []byte) error {
+	var str string
+	if err := json.Unmarshal(input, &str); err == nil && str == precompileOverrideRemove {
+		*o = PrecompileOverride{Remove: true}
+		return nil
+	}
+	var code hexutil.Bytes
+	if err := code.UnmarshalJSON(input); err != nil {
+		return fmt.Errorf("precompile override must be %q or hex encoded code: %w", precompileOverrideRemove, err)
+	}
+	if len(code) == 0 {
+		return errors.New("precompile override code is empty")
+	}
+	*o = PrecompileOverride{Code: code}
+	return nil
 }
 
 // StateOverride is the collection of overridden accounts.
@@ -1087,6 +1132,92 @@ func (diff *StateOverride) Apply(state *state.StateDB) error {
 	return nil
 }
 
+// ApplyPrecompiles returns the given concrete precompiles and their capabilities
+// with the precompile overrides applied. The given maps are not modified. Moved
+// precompiles keep their capabilities, while removed and loaded ones have none.
+// Code is loaded into a precompile by the registry, which must implement
+// concrete.LoaderRegistry, and only if allowCode is set. Loaded precompiles run
+// under ctx, which should be cancelled when the call returns to release them.
+func (diff *StateOverride) ApplyPrecompiles(ctx context.Context, registry concrete.PrecompileRegistry, precompiles concrete.PrecompileMap, capabilities concrete.CapabilityMap, allowCode bool) (concrete.PrecompileMap, concrete.CapabilityMap, error) {
+	if diff == nil {
+		return precompiles, capabilities, nil
+	}
+	var overridden bool
+	for _, account := range *diff {
+		if account.Precompile != nil || account.MovePrecompileTo != nil {
+			overridden = true
+			break
+		}
+	}
+	if !overridden {
+		return precompiles, capabilities, nil
+	}
+	result := make(concrete.PrecompileMap, len(precompiles))
+	for addr, pc := range precompiles {
+		result[addr] = pc
+	}
+	resultCapabilities := make(concrete.CapabilityMap, len(capabilities))
+	for addr, capability := range capabilities {
+		resultCapabilities[addr] = capability
+	}
+	// Remove all moved precompiles before adding them back, so that the
+	// result does not depend on the order of the overrides.
+	moved := make(map[common.Address]concrete.Precompile)
+	movedCapabilities := make(concrete.CapabilityMap)
+	for addr, account := range *diff {
+		if account.MovePrecompileTo == nil {
+			continue
+		}
+		pc, ok := precompiles[addr]
+		if !ok {
+			return nil, nil, fmt.Errorf("account %s is not a concrete precompile", addr.Hex())
+		}
+		dest := *account.MovePrecompileTo
+		if _, ok := moved[dest]; ok {
+			return nil, nil, fmt.Errorf("multiple precompiles moved to %s", dest.Hex())
+		}
+		moved[dest] = pc
+		if capability, ok := capabilities[addr]; ok {
+			movedCapabilities[dest] = capability
+		}
+		delete(result, addr)
+		delete(resultCapabilities, addr)
+	}
+	for dest, pc := range moved {
+		if _, ok := result[dest]; ok {
+			return nil, nil, fmt.Errorf("cannot move precompile to %s: address is already a concrete precompile", dest.Hex())
+		}
+		result[dest] = pc
+		if capability, ok := movedCapabilities[dest]; ok {
+			resultCapabilities[dest] = capability
+		}
+	}
+	for addr, account := range *diff {
+		if account.Precompile == nil {
+			continue
+		}
+		// Neither removed nor loaded precompiles have capabilities
+		delete(resultCapabilities, addr)
+		if account.Precompile.Remove {
+			delete(result, addr)
+			continue
+		}
+		if !allowCode {
+			return nil, nil, fmt.Errorf("account %s: %w", addr.Hex(), errPrecompileOverridesDisabled)
+		}
+		loader, ok := registry.(concrete.LoaderRegistry)
+		if !ok {
+			return nil, nil, fmt.Errorf("account %s: %w", addr.Hex(), concrete.ErrNoLoader)
+		}
+		pc, err := loader.LoadPrecompile(ctx, account.Precompile.Code)
+		if err != nil {
+			return nil, nil, fmt.Errorf("account %s: %w", addr.Hex(), err)
+		}
+		result[addr] = pc
+	}
+	return result, resultCapabilities, nil
+}
+
 // BlockOverrides is a set of header fields to override.
 type BlockOverrides struct {
 	Number      *hexutil.Big
@@ -1167,6 +1298,14 @@ func (context *ChainContext) Concrete() concrete.PrecompileRegistry {
 	return context.b.Concrete()
 }
 
+type concreteDebugKey struct{}
+
+// WithConcreteDebug returns a copy of ctx making calls executed with it report
+// the debug messages of concrete precompiles to the given tracer.
+func WithConcreteDebug(ctx context.Context, tracer cc_api.DebugTracer) context.Context {
+	return context.WithValue(ctx, concreteDebugKey{}, tracer)
+}
+
 func doCall(ctx context.Context, b Backend, args TransactionArgs, state *state.StateDB, header *types.Header, overrides *StateOverride, blockOverrides *BlockOverrides, timeout time.Duration, globalGasCap uint64) (*core.ExecutionResult, error) {
 	if err := overrides.Apply(state); err != nil {
 		return nil, err
@@ -1188,11 +1327,21 @@ func doCall(ctx context.Context, b Backend, args TransactionArgs, state *state.S
 	if blockOverrides != nil {
 		blockOverrides.Apply(&blockCtx)
 	}
+	precompiles, capabilities, err := overrides.ApplyPrecompiles(ctx, b.Concrete(), blockCtx.ConcretePrecompiles, blockCtx.ConcreteCapabilities, b.RPCPrecompileOverrides())
+	if err != nil {
+		return nil, err
+	}
+	blockCtx.ConcretePrecompiles = precompiles
+	blockCtx.ConcreteCapabilities = capabilities
 	msg, err := args.ToMessage(globalGasCap, blockCtx.BaseFee)
 	if err != nil {
 		return nil, err
 	}
-	evm := b.GetEVM(ctx, msg, state, header, &vm.Config{NoBaseFee: true}, &blockCtx)
+	vmConfig := &vm.Config{NoBaseFee: true, ConcreteMode: cc_api.ExecutionModeCall}
+	if tracer, ok := ctx.Value(concreteDebugKey{}).(cc_api.DebugTracer); ok {
+		vmConfig.ConcreteDebugTracer = tracer
+	}
+	evm := b.GetEVM(ctx, msg, state, header, vmConfig, &blockCtx)
 
 	// Wait for the context to be done and cancel the evm. Even if the
 	// EVM has finished, cancelling may be done (repeatedly)
@@ -1283,14 +1432,28 @@ func DoEstimateGas(ctx context.Context, b Backend, args TransactionArgs, blockNr
 	if err = overrides.Apply(state); err != nil {
 		return 0, err
 	}
+	// Release the precompiles loaded by the overrides once the estimation
+	// has completed.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		number       = header.Number.Uint64()
+		precompiles  = b.Concrete().Precompiles(number)
+		capabilities = concrete.RegistryCapabilities(b.Concrete(), number)
+	)
+	precompiles, capabilities, err = overrides.ApplyPrecompiles(ctx, b.Concrete(), precompiles, capabilities, b.RPCPrecompileOverrides())
+	if err != nil {
+		return 0, err
+	}
 	// Construct the gas estimator option from the user input
 	opts := &gasestimator.Options{
-		Config:              b.ChainConfig(),
-		Chain:               NewChainContext(ctx, b),
-		Header:              header,
-		State:               state,
-		ErrorRatio:          estimateGasErrorRatio,
-		ConcretePrecompiles: b.Concrete().Precompiles(header.Nonce.Uint64()),
+		Config:               b.ChainConfig(),
+		Chain:                NewChainContext(ctx, b),
+		Header:               header,
+		State:                state,
+		ErrorRatio:           estimateGasErrorRatio,
+		ConcretePrecompiles:  precompiles,
+		ConcreteCapabilities: capabilities,
 	}
 	// Run the gas estimation andwrap any revertals into a custom return
 	call, err := args.ToMessage(gasCap, header.BaseFee)
@@ -1712,7 +1875,7 @@ func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrH
 
 		// Apply the transaction with the access list tracer
 		tracer := logger.NewAccessListTracer(accessList, args.from(), to, precompiles)
-		config := vm.Config{Tracer: tracer, NoBaseFee: true}
+		config := vm.Config{Tracer: tracer, NoBaseFee: true, ConcreteMode: cc_api.ExecutionModeCall}
 		vmenv := b.GetEVM(ctx, msg, statedb, header, &config, nil)
 		res, err := core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(msg.GasLimit))
 		if err != nil {
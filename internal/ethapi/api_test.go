@@ -41,6 +41,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/concrete"
+	cc_api "github.com/ethereum/go-ethereum/concrete/api"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/consensus/beacon"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
@@ -636,6 +637,7 @@ func (b testBackend) AccountManager() *accounts.Manager { return b.accman }
 func (b testBackend) ExtRPCEnabled() bool               { return false }
 func (b testBackend) RPCGasCap() uint64                 { return 10000000 }
 func (b testBackend) RPCEVMTimeout() time.Duration      { return time.Second }
+func (b testBackend) RPCPrecompileOverrides() bool      { return true }
 func (b testBackend) RPCTxFeeCap() float64              { return 0 }
 func (b testBackend) UnprotectedAllowed() bool          { return false }
 func (b testBackend) SetHead(number uint64)             {}
@@ -742,9 +744,11 @@ func (b testBackend) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) even
 func (b testBackend) SubscribeChainSideEvent(ch chan<- core.ChainSideEvent) event.Subscription {
 	panic("implement me")
 }
-func (b testBackend) SetConcrete(concrete.PrecompileRegistry) {}
+func (b testBackend) SetConcrete(registry concrete.PrecompileRegistry) {
+	b.chain.SetConcrete(registry)
+}
 func (b testBackend) Concrete() concrete.PrecompileRegistry {
-	return &concrete.GenericPrecompileRegistry{}
+	return b.chain.Concrete()
 }
 func (b testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
 	panic("implement me")
@@ -950,6 +954,107 @@ func TestEstimateGas(t *testing.T) {
 	}
 }
 
+type gasPrecompile struct{ gas uint64 }
+
+func (pc *gasPrecompile) IsStatic(input []byte) bool { return true }
+
+func (pc *gasPrecompile) Run(env concrete.Environment, input []byte) ([]byte, error) {
+	env.UseGas(pc.gas)
+	return nil, nil
+}
+
+func TestEstimateGasPrecompileOverride(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(1)
+		genesis  = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		pcAddr = common.HexToAddress("0xc1")
+	)
+	backend := newTestBackend(t, 1, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		b.SetPoS()
+	})
+	registry := concrete.NewRegistry()
+	registry.SetLoader(func(ctx context.Context, code []byte) (concrete.Precompile, error) {
+		return &gasPrecompile{gas: new(big.Int).SetBytes(code).Uint64()}, nil
+	})
+	backend.SetConcrete(registry)
+	api := NewBlockChainAPI(backend)
+
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	call := TransactionArgs{From: &accounts[0].addr, To: &pcAddr}
+	estimate, err := api.EstimateGas(context.Background(), call, &latest, nil)
+	if err != nil {
+		t.Fatalf("failed to estimate gas: %v", err)
+	}
+	if uint64(estimate) != params.TxGas {
+		t.Errorf("gas mismatch without override: have %d, want %d", estimate, params.TxGas)
+	}
+	overrides := StateOverride{pcAddr: {Precompile: &PrecompileOverride{Code: big.NewInt(50000).Bytes()}}}
+	estimate, err = api.EstimateGas(context.Background(), call, &latest, &overrides)
+	if err != nil {
+		t.Fatalf("failed to estimate gas: %v", err)
+	}
+	if uint64(estimate) < params.TxGas+50000 {
+		t.Errorf("gas mismatch with override: have %d, want at least %d", estimate, params.TxGas+50000)
+	}
+}
+
+// modePrecompile reverts unless executed in the mode given as input.
+type modePrecompile struct{}
+
+func (pc *modePrecompile) IsStatic(input []byte) bool { return true }
+
+func (pc *modePrecompile) AbiVersion() cc_api.AbiVersion { return cc_api.AbiVersion2 }
+
+func (pc *modePrecompile) Run(env concrete.Environment, input []byte) ([]byte, error) {
+	mode := env.GetExecutionMode()
+	if len(input) != 1 || cc_api.ExecutionMode(input[0]) != mode {
+		return nil, fmt.Errorf("unexpected mode %s", mode)
+	}
+	return []byte{byte(mode)}, nil
+}
+
+func TestConcreteExecutionMode(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(1)
+		genesis  = &core.Genesis{
+			Config: params.MergedTestChainConfig,
+			Alloc: types.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+			},
+		}
+		pcAddr = common.HexToAddress("0xc1")
+	)
+	backend := newTestBackend(t, 1, genesis, beacon.New(ethash.NewFaker()), func(i int, b *core.BlockGen) {
+		b.SetPoS()
+	})
+	registry := concrete.NewRegistry()
+	registry.AddPrecompile(0, pcAddr, &modePrecompile{})
+	backend.SetConcrete(registry)
+	api := NewBlockChainAPI(backend)
+
+	latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+	newCall := func(mode cc_api.ExecutionMode) TransactionArgs {
+		input := hexutil.Bytes{byte(mode)}
+		return TransactionArgs{From: &accounts[0].addr, To: &pcAddr, Input: &input}
+	}
+	if _, err := api.Call(context.Background(), newCall(cc_api.ExecutionModeCall), &latest, nil, nil); err != nil {
+		t.Errorf("call failed: %v", err)
+	}
+	if _, err := api.EstimateGas(context.Background(), newCall(cc_api.ExecutionModeEstimate), &latest, nil); err != nil {
+		t.Errorf("estimate failed: %v", err)
+	}
+	if _, err := api.EstimateGas(context.Background(), newCall(cc_api.ExecutionModeCall), &latest, nil); err == nil {
+		t.Errorf("estimate did not fail in call mode")
+	}
+}
+
 func TestCall(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
@@ -1120,6 +1225,20 @@ func TestCall(t *testing.T) {
 			},
 			want: "0x0122000000000000000000000000000000000000000000000000000000000000",
 		},
+		// Precompile code overrides require a registry that can load code
+		{
+			blockNumber: rpc.LatestBlockNumber,
+			call: TransactionArgs{
+				From: &accounts[1].addr,
+				To:   &randomAccounts[2].addr,
+			},
+			overrides: StateOverride{
+				randomAccounts[2].addr: {
+					Precompile: &PrecompileOverride{Code: []byte{0x00}},
+				},
+			},
+			expectErr: concrete.ErrNoLoader,
+		},
 	}
 	for i, tc := range testSuite {
 		result, err := api.Call(context.Background(), tc.call, &rpc.BlockNumberOrHash{BlockNumber: &tc.blockNumber}, &tc.overrides, &tc.blockOverrides)
@@ -1146,6 +1265,169 @@ func TestCall(t *testing.T) {
 	}
 }
 
+type testPrecompile struct{ output []byte }
+
+func (pc *testPrecompile) IsStatic(input []byte) bool { return true }
+
+func (pc *testPrecompile) Run(env concrete.Environment, input []byte) ([]byte, error) {
+	return pc.output, nil
+}
+
+func TestApplyPrecompileOverrides(t *testing.T) {
+	t.Parallel()
+	var (
+		addr1 = common.HexToAddress("0xc1")
+		addr2 = common.HexToAddress("0xc2")
+		addr3 = common.HexToAddress("0xc3")
+		pc1   = &testPrecompile{output: []byte{1}}
+		pc2   = &testPrecompile{output: []byte{2}}
+	)
+	registry := concrete.NewRegistry()
+	registry.AddPrecompiles(0, concrete.PrecompileMap{addr1: pc1, addr2: pc2})
+	registry.SetLoader(func(ctx context.Context, code []byte) (concrete.Precompile, error) {
+		return &testPrecompile{output: code}, nil
+	})
+	if err := registry.SetCapabilities(0, addr1, cc_api.CapabilityBalance); err != nil {
+		t.Fatal(err)
+	}
+	precompiles := registry.Precompiles(0)
+	capabilities := concrete.CapabilityMap{addr1: cc_api.CapabilityBalance}
+
+	var testSuite = []struct {
+		overrides    StateOverride
+		want         map[common.Address][]byte
+		capabilities concrete.CapabilityMap
+		expectErr    bool
+	}{
+		// No precompile overrides
+		{
+			overrides:    StateOverride{addr1: {Code: hex2Bytes("fe")}},
+			want:         map[common.Address][]byte{addr1: {1}, addr2: {2}},
+			capabilities: concrete.CapabilityMap{addr1: cc_api.CapabilityBalance},
+		},
+		// Remove a precompile
+		{
+			overrides:    StateOverride{addr1: {Precompile: &PrecompileOverride{Remove: true}}},
+			want:         map[common.Address][]byte{addr2: {2}},
+			capabilities: concrete.CapabilityMap{},
+		},
+		// Replace a precompile and add a new one
+		{
+			overrides: StateOverride{
+				addr1: {Precompile: &PrecompileOverride{Code: []byte{3}}},
+				addr3: {Precompile: &PrecompileOverride{Code: []byte{4}}},
+			},
+			want:         map[common.Address][]byte{addr1: {3}, addr2: {2}, addr3: {4}},
+			capabilities: concrete.CapabilityMap{},
+		},
+		// Move a precompile and load a new one in its place
+		{
+			overrides: StateOverride{
+				addr1: {MovePrecompileTo: &addr3, Precompile: &PrecompileOverride{Code: []byte{3}}},
+			},
+			want:         map[common.Address][]byte{addr1: {3}, addr2: {2}, addr3: {1}},
+			capabilities: concrete.CapabilityMap{addr3: cc_api.CapabilityBalance},
+		},
+		// Swap two precompiles
+		{
+			overrides: StateOverride{
+				addr1: {MovePrecompileTo: &addr2},
+				addr2: {MovePrecompileTo: &addr1},
+			},
+			want:         map[common.Address][]byte{addr1: {2}, addr2: {1}},
+			capabilities: concrete.CapabilityMap{addr2: cc_api.CapabilityBalance},
+		},
+		// Move a precompile onto another precompile
+		{
+			overrides: StateOverride{addr1: {MovePrecompileTo: &addr2}},
+			expectErr: true,
+		},
+		// Move two precompiles to the same address
+		{
+			overrides: StateOverride{
+				addr1: {MovePrecompileTo: &addr3},
+				addr2: {MovePrecompileTo: &addr3},
+			},
+			expectErr: true,
+		},
+		// Move an account that is not a precompile
+		{
+			overrides: StateOverride{addr3: {MovePrecompileTo: &addr1}},
+			expectErr: true,
+		},
+	}
+	for i, tc := range testSuite {
+		result, resultCapabilities, err := tc.overrides.ApplyPrecompiles(context.Background(), registry, precompiles, capabilities, true)
+		if tc.expectErr {
+			if err == nil {
+				t.Errorf("test %d: want error, have nothing", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("test %d: want no error, have %v", i, err)
+			continue
+		}
+		have := make(map[common.Address][]byte)
+		for addr, pc := range result {
+			have[addr] = pc.(*testPrecompile).output
+		}
+		if !reflect.DeepEqual(have, tc.want) {
+			t.Errorf("test %d: precompiles mismatch, have %v, want %v", i, have, tc.want)
+		}
+		if !reflect.DeepEqual(resultCapabilities, tc.capabilities) {
+			t.Errorf("test %d: capabilities mismatch, have %v, want %v", i, resultCapabilities, tc.capabilities)
+		}
+	}
+	if len(precompiles) != 2 || precompiles[addr1] != pc1 || precompiles[addr2] != pc2 {
+		t.Errorf("registry precompiles were modified")
+	}
+	if len(capabilities) != 1 || capabilities[addr1] != cc_api.CapabilityBalance {
+		t.Errorf("capabilities were modified")
+	}
+	// Code can only be loaded if precompile overrides are enabled in the node
+	overrides := StateOverride{addr1: {Precompile: &PrecompileOverride{Code: []byte{3}}}}
+	if _, _, err := overrides.ApplyPrecompiles(context.Background(), registry, precompiles, capabilities, false); !errors.Is(err, errPrecompileOverridesDisabled) {
+		t.Errorf("want %v, have %v", errPrecompileOverridesDisabled, err)
+	}
+	overrides = StateOverride{addr1: {Precompile: &PrecompileOverride{Remove: true}}}
+	if _, _, err := overrides.ApplyPrecompiles(context.Background(), registry, precompiles, capabilities, false); err != nil {
+		t.Errorf("want no error removing a precompile, have %v", err)
+	}
+}
+
+func TestPrecompileOverrideJSON(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		input string
+		want  PrecompileOverride
+	}{
+		{`"remove"`, PrecompileOverride{Remove: true}},
+		{`"0x0061736d"`, PrecompileOverride{Code: []byte{0x00, 0x61, 0x73, 0x6d}}},
+	} {
+		var o PrecompileOverride
+		if err := json.Unmarshal([]byte(tc.input), &o); err != nil {
+			t.Fatalf("failed to unmarshal %s: %v", tc.input, err)
+		}
+		if !reflect.DeepEqual(o, tc.want) {
+			t.Errorf("unmarshal %s: have %+v, want %+v", tc.input, o, tc.want)
+		}
+		output, err := json.Marshal(o)
+		if err != nil {
+			t.Fatalf("failed to marshal %+v: %v", o, err)
+		}
+		if string(output) != tc.input {
+			t.Errorf("marshal %+v: have %s, want %s", o, output, tc.input)
+		}
+	}
+	for _, input := range []string{`"0x"`, `"keep"`, `1`} {
+		var o PrecompileOverride
+		if err := json.Unmarshal([]byte(input), &o); err == nil {
+			t.Errorf("unmarshal %s: want error, have nothing", input)
+		}
+	}
+}
+
 func TestSignTransaction(t *testing.T) {
 	t.Parallel()
 	// Initialize test accounts
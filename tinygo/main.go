@@ -44,6 +44,11 @@ func newEnvironment() *api.Env {
 	return proxy.NewWasmProxyEnvironment(infra.Memory, infra.Allocator, environment)
 }
 
+//export concrete_AbiVersion
+func abiVersion() uint64 {
+	return uint64(api.LatestAbiVersion)
+}
+
 //export concrete_IsStatic
 func isStatic(pointer uint64) uint64 {
 	input := memory.GetValue(infra.Memory, memory.MemPointer(pointer))